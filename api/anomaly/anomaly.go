@@ -0,0 +1,262 @@
+package anomaly
+
+import (
+	"fmt"
+	"golang-secret-manager/types"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options of the detection, the zero values are replaced by the defaults
+type Options struct {
+	// The recent events that are checked against the baseline
+	Window time.Duration
+
+	// Secrets with fewer events before the window have no baseline
+	MinBaselineEvents int
+
+	// The volume of the window (per day) that is a spike, times the daily
+	// average of the baseline
+	SpikeFactor float64
+
+	// Hours of the day with less than this share of the baseline events are
+	// unusual
+	UnusualHourShare float64
+}
+
+var DefaultOptions = Options{
+	Window:            24 * time.Hour,
+	MinBaselineEvents: 20,
+	SpikeFactor:       3,
+	UnusualHourShare:  0.02,
+}
+
+// The volume of the window must also be at least this many events, a few reads
+// of a quiet secret are not a spike
+const minSpikeEvents = 10
+
+func (o Options) withDefaults() Options {
+	if o.Window <= 0 {
+		o.Window = DefaultOptions.Window
+	}
+	if o.MinBaselineEvents <= 0 {
+		o.MinBaselineEvents = DefaultOptions.MinBaselineEvents
+	}
+	if o.SpikeFactor <= 0 {
+		o.SpikeFactor = DefaultOptions.SpikeFactor
+	}
+	if o.UnusualHourShare <= 0 {
+		o.UnusualHourShare = DefaultOptions.UnusualHourShare
+	}
+	return o
+}
+
+// Parsing the window of the detection, a Go duration or a number of days (7d)
+func ParseWindow(value string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(value, "d"); found {
+		num, err := strconv.Atoi(days)
+		if err == nil && num > 0 {
+			return time.Duration(num) * 24 * time.Hour, nil
+		}
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("invalid window: %s", value)
+	}
+	return duration, nil
+}
+
+// Checking the access logs of the secrets, the secrets map is used for the
+// names of the secrets and can be missing some of them
+func Detect(accessLogs map[string][]types.AccessLog, secrets map[string]types.Secret, options Options, now time.Time) types.AnomalyReport {
+	options = options.withDefaults()
+	report := types.AnomalyReport{
+		GeneratedAt:          now.UTC(),
+		Window:               options.Window.String(),
+		Secrets:              len(accessLogs),
+		Anomalies:            []types.Anomaly{},
+		Baselines:            make(map[string]types.AccessBaseline),
+		InsufficientBaseline: []string{},
+	}
+	windowStart := now.Add(-options.Window)
+
+	for arn, accessLog := range accessLogs {
+		var before, recent []types.AccessLog
+		for _, event := range accessLog {
+			if event.EventTime.Before(windowStart) {
+				before = append(before, event)
+			} else if !event.EventTime.After(now) {
+				recent = append(recent, event)
+			}
+		}
+		if len(before) < options.MinBaselineEvents {
+			report.InsufficientBaseline = append(report.InsufficientBaseline, arn)
+			continue
+		}
+
+		baseline := BuildBaseline(before, windowStart)
+		report.Baselines[arn] = baseline
+		for _, found := range checkWindow(baseline, recent, options) {
+			found.SecretARN = arn
+			found.SecretName = secrets[arn].Name
+			report.Anomalies = append(report.Anomalies, found)
+		}
+	}
+
+	sort.Strings(report.InsufficientBaseline)
+	sort.SliceStable(report.Anomalies, func(i, j int) bool {
+		a, b := report.Anomalies[i], report.Anomalies[j]
+		if severityRank(a.Severity) != severityRank(b.Severity) {
+			return severityRank(a.Severity) < severityRank(b.Severity)
+		}
+		if a.SecretARN != b.SecretARN {
+			return a.SecretARN < b.SecretARN
+		}
+		return a.EventTime.After(b.EventTime)
+	})
+	return report
+}
+
+// Building the baseline from the events before the end of it
+func BuildBaseline(events []types.AccessLog, end time.Time) types.AccessBaseline {
+	baseline := types.AccessBaseline{
+		Events:      len(events),
+		Principals:  make(map[string]int),
+		SourceIPs:   make(map[string]int),
+		ServiceOnly: true,
+	}
+	first := end
+	reads := 0
+	for _, event := range events {
+		if event.EventTime.Before(first) {
+			first = event.EventTime
+		}
+		baseline.Principals[Principal(event)]++
+		if event.SourceIP != "" {
+			baseline.SourceIPs[event.SourceIP]++
+		}
+		baseline.Hours[event.EventTime.UTC().Hour()]++
+		if event.EventName == "GetSecretValue" {
+			reads++
+			if IsHuman(event) {
+				baseline.ServiceOnly = false
+			}
+		}
+	}
+	if reads == 0 {
+		baseline.ServiceOnly = false
+	}
+
+	baseline.Days = end.Sub(first).Hours() / 24
+	if baseline.Days < 1 {
+		baseline.Days = 1
+	}
+	baseline.DailyAverage = float64(baseline.Events) / baseline.Days
+	return baseline
+}
+
+func checkWindow(baseline types.AccessBaseline, recent []types.AccessLog, options Options) []types.Anomaly {
+	var found []types.Anomaly
+	seen := make(map[string]bool)
+	flag := func(kind string, id string, severity string, event types.AccessLog, format string, args ...interface{}) {
+		if seen[kind+"|"+id] {
+			return
+		}
+		seen[kind+"|"+id] = true
+		found = append(found, types.Anomaly{
+			Kind:      kind,
+			Severity:  severity,
+			Message:   fmt.Sprintf(format, args...),
+			Principal: Principal(event),
+			SourceIP:  event.SourceIP,
+			EventID:   event.EventID,
+			EventTime: event.EventTime,
+		})
+	}
+
+	// the events are checked from the oldest so the first one of each kind is
+	// reported
+	sort.SliceStable(recent, func(i, j int) bool {
+		return recent[i].EventTime.Before(recent[j].EventTime)
+	})
+
+	windowDays := options.Window.Hours() / 24
+	if len(recent) >= minSpikeEvents && float64(len(recent))/windowDays > options.SpikeFactor*baseline.DailyAverage {
+		flag(types.AnomalyVolumeSpike, "", types.SeverityMedium, recent[len(recent)-1],
+			"%d events in %s, the daily average is %.1f", len(recent), options.Window, baseline.DailyAverage)
+	}
+
+	for _, event := range recent {
+		principal := Principal(event)
+		read := event.EventName == "GetSecretValue"
+
+		if baseline.ServiceOnly && read && IsHuman(event) {
+			flag(types.AnomalyHumanOnServiceOnly, principal, types.SeverityHigh, event,
+				"%s read a secret that only services read before", principal)
+		}
+
+		if baseline.Principals[principal] == 0 {
+			severity := types.SeverityMedium
+			if read {
+				severity = types.SeverityHigh
+			}
+			flag(types.AnomalyNewPrincipal, principal, severity, event,
+				"%s called %s for the first time", principal, event.EventName)
+		}
+
+		if event.SourceIP != "" && baseline.SourceIPs[event.SourceIP] == 0 && !isServiceHost(event.SourceIP) {
+			flag(types.AnomalyUnusualSourceIP, event.SourceIP, types.SeverityMedium, event,
+				"%s called %s from %s that wasn't seen before", principal, event.EventName, event.SourceIP)
+		}
+
+		hour := event.EventTime.UTC().Hour()
+		if float64(baseline.Hours[hour]) < options.UnusualHourShare*float64(baseline.Events) {
+			flag(types.AnomalyUnusualHour, principal+"|"+strconv.Itoa(hour), types.SeverityLow, event,
+				"%s called %s at %02d:00 UTC, an hour with %d of %d baseline events", principal, event.EventName, hour, baseline.Hours[hour], baseline.Events)
+		}
+	}
+	return found
+}
+
+// The principal of the event, its ARN when it is known
+func Principal(event types.AccessLog) string {
+	if event.SessionIssuer != "" {
+		// the sessions of a role are the same principal
+		return event.SessionIssuer
+	}
+	if event.UserARN != "" {
+		return event.UserARN
+	}
+	return event.User
+}
+
+// Users and the roles of AWS SSO (IAM Identity Center) are people, the other
+// roles and the AWS services are not
+func IsHuman(event types.AccessLog) bool {
+	switch event.UserIdentityType {
+	case "IAMUser", "Root", "IdentityCenterUser":
+		return true
+	case "AssumedRole":
+		return strings.Contains(event.SessionIssuer, "AWSReservedSSO_")
+	}
+	return false
+}
+
+// Calls made by AWS services have the host name of the service as the source
+func isServiceHost(sourceIP string) bool {
+	return strings.HasSuffix(sourceIP, ".amazonaws.com") || sourceIP == "AWS Internal"
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case types.SeverityHigh:
+		return 0
+	case types.SeverityMedium:
+		return 1
+	case types.SeverityLow:
+		return 2
+	}
+	return 3
+}
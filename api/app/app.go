@@ -0,0 +1,66 @@
+package app
+
+import (
+	"golang-secret-manager/api/aws"
+	"golang-secret-manager/api/crawler"
+	"golang-secret-manager/api/inventory"
+	"golang-secret-manager/api/server/config"
+	"golang-secret-manager/api/stream"
+	"golang-secret-manager/types"
+	"golang-secret-manager/utils/ratelimit"
+	"golang-secret-manager/utils/storage"
+	"log"
+)
+
+// App holds everything the server needs, it is built once in main and passed
+// to the handlers and the middlewares instead of reaching for globals
+type App struct {
+	Config config.Config
+	Cache  storage.ICache
+	Codec  storage.Codec
+	AWS    *aws.Service
+	Logger *log.Logger
+
+	// Background and on demand crawls of the secrets, main starts the
+	// schedule of the configured profiles
+	Crawler *crawler.Crawler
+
+	// The saved snapshots of the secrets inventory, set by main
+	Inventory *inventory.Store
+
+	// The accesses and the changes of the secrets for the clients of
+	// /v1/events, the new access log events are published by the service and
+	// main publishes the changes that the crawls found
+	Stream *stream.Hub
+
+	// The requests of each client, shared by the HTTP and the gRPC servers
+	Limiter *ratelimit.Limiter
+}
+
+func New(cfg config.Config, cache storage.ICache, codec storage.Codec, factory aws.ClientFactory, logger *log.Logger) *App {
+	if logger == nil {
+		logger = log.Default()
+	}
+	service := aws.NewService(cache, codec, factory, logger)
+	if cfg.AccessLogRetention > 0 {
+		service.AccessLogRetention = cfg.AccessLogRetention
+	}
+	hub := stream.NewHub(cfg.StreamBuffer)
+	service.OnAccessLogs = func(secretID string, events []types.AccessLog) {
+		secret, err := service.Secrets.Get(aws.GetCacheSecretKey(secretID))
+		if err != nil {
+			secret = types.Secret{ARN: secretID}
+		}
+		hub.PublishAccessLogs(secret, events)
+	}
+	return &App{
+		Config:  cfg,
+		Cache:   cache,
+		Codec:   codec,
+		AWS:     service,
+		Logger:  logger,
+		Crawler: crawler.New(service, cfg.Crawler, logger),
+		Stream:  hub,
+		Limiter: ratelimit.New(float64(cfg.RateLimit), cfg.RateLimitBurst),
+	}
+}
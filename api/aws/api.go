@@ -2,10 +2,9 @@ package aws
 
 import (
 	"context"
-	"fmt"
 	"golang-secret-manager/types"
 	"golang-secret-manager/utils/storage"
-	"log"
+	"time"
 )
 
 // Api for handling the aws secretmanger request
@@ -21,6 +20,14 @@ func GetCacheARNKey(publicKey string) string {
 	return "arnlst" + publicKey
 }
 
+// Time to live of each key namespace inside the cache, secret metadata is
+// rarely changing while the access logs are changing all the time
+var (
+	SecretCacheTTL    = 12 * time.Hour
+	AccessLogCacheTTL = 5 * time.Minute
+	ARNListCacheTTL   = 1 * time.Hour
+)
+
 func createARNList(secrets []types.Secret) []string {
 	var lst []string
 	for _, s := range secrets {
@@ -29,17 +36,26 @@ func createARNList(secrets []types.Secret) []string {
 	return lst
 }
 
-func RetriveAllSecretsWithAccessLog(ctx context.Context, publicKey string, secretKey string, region string) (*types.AllSecretWithAccessLog, error) {
+func (s *Service) RetriveAllSecretsWithAccessLog(ctx context.Context, publicKey string, secretKey string, region string) (*types.AllSecretWithAccessLog, error) {
+	key := getFetchKey(publicKey, secretKey, region, "all-secrets")
+	val, err, shared := s.allSecretsGroup.Do(key, func() (*types.AllSecretWithAccessLog, error) {
+		return s.retriveAllSecretsWithAccessLog(ctx, publicKey, secretKey, region)
+	})
+	if shared {
+		s.Logger.Println("API-AWS: shared an in-flight crawl of all the secrets")
+	}
+	return val, err
+}
+
+func (s *Service) retriveAllSecretsWithAccessLog(ctx context.Context, publicKey string, secretKey string, region string) (*types.AllSecretWithAccessLog, error) {
 	// creating the AWS client
-	client, err := NewAWSClient(ctx, publicKey, secretKey, region)
+	client, err := s.NewClient(ctx, publicKey, secretKey, region)
 	if err != nil {
 		// failed to create AWSClient
-		log.Println("API-AWS: failed to create AWSClient")
+		s.Logger.Println("API-AWS: failed to create AWSClient")
 		return nil, err
 	}
 
-	cacheInstance := storage.GetCacheInstance()
-
 	trys := 5
 	var nextToken *string = nil
 	var allSecrets []types.Secret
@@ -57,10 +73,9 @@ func RetriveAllSecretsWithAccessLog(ctx context.Context, publicKey string, secre
 			// got all secrets
 			for _, secret := range result.Secrets {
 				// caching the secrets
-				key := GetCacheSecretKey(secret.ARN)
-				storage.SetCacheValue[types.Secret](cacheInstance, key, secret)
+				err := s.cacheSecret(secret.ARN, secret)
 				if err != nil {
-					log.Println("API-AWS: failed to cache the secret", secret.ARN)
+					s.Logger.Println("API-AWS: failed to cache the secret", secret.ARN)
 				}
 			}
 			allSecrets = append(allSecrets, result.Secrets...)
@@ -68,11 +83,9 @@ func RetriveAllSecretsWithAccessLog(ctx context.Context, publicKey string, secre
 		}
 
 		for _, secret := range result.Secrets {
-			key := GetCacheSecretKey(secret.ARN)
-			log.Println("found secret: ", secret.ARN) // TEST
-			storage.SetCacheValue[types.Secret](cacheInstance, key, secret)
+			err := s.cacheSecret(secret.ARN, secret)
 			if err != nil {
-				log.Println("API-AWS: failed to cache the secret", secret.ARN)
+				s.Logger.Println("API-AWS: failed to cache the secret", secret.ARN)
 			}
 		}
 		nextToken = result.NextToken
@@ -82,16 +95,16 @@ func RetriveAllSecretsWithAccessLog(ctx context.Context, publicKey string, secre
 	// for each secrets retriving the access log
 	accessLogMap := make(map[string][]types.AccessLog)
 	for _, secret := range allSecrets {
-		accesslog, err := getAccessLogWithTrys(client, secret.ARN)
+		accesslog, err := s.syncAccessLog(client, secret.ARN)
 		if err != nil {
 			// failed to retrived all the access log
 			continue
 		}
 		accessLogMap[secret.ARN] = accesslog
 		key := GetCacheAccessKey(secret.ARN)
-		err = storage.SetCacheValue[[]types.AccessLog](cacheInstance, key, accesslog)
+		err = s.AccessLogs.Set(key, accesslog, storage.WithTTL(AccessLogCacheTTL))
 		if err != nil {
-			log.Println(err.Error())
+			s.Logger.Println(err.Error())
 		}
 	}
 
@@ -100,10 +113,10 @@ func RetriveAllSecretsWithAccessLog(ctx context.Context, publicKey string, secre
 	lst := createARNList(allSecrets)
 
 	// caching the value
-	err = storage.SetCacheValue[[]string](cacheInstance, key, lst)
+	err = s.ARNLists.Set(key, lst, storage.WithTTL(ARNListCacheTTL))
 	if err != nil {
 		// failed to cache the value printing the error
-		log.Println(err.Error())
+		s.Logger.Println(err.Error())
 	}
 
 	var retVal types.AllSecretWithAccessLog
@@ -112,17 +125,17 @@ func RetriveAllSecretsWithAccessLog(ctx context.Context, publicKey string, secre
 	return &retVal, nil
 }
 
-func getAccessLogWithTrys(client IAWSClient, secretID string) ([]types.AccessLog, error) {
+func (s *Service) getAccessLogWithTrys(client IAWSClient, secretID string, startTime *time.Time, endTime *time.Time) ([]types.AccessLog, error) {
 	var accessLogList []types.AccessLog
 	var nextToken *string = nil
 	trys := 5
 	for {
-		accessLogs, err := client.GetAccessLog(secretID, nextToken)
+		accessLogs, err := client.GetAccessLog(secretID, startTime, endTime, nextToken)
 		if err != nil {
 			// failed to retrive all
 			if trys == 0 {
 				// failed to retrive all secrets
-				log.Println("API-AWS: failed to retrive all access log to secret id: ", secretID)
+				s.Logger.Println("API-AWS: failed to retrive all access log to secret id: ", secretID)
 				return nil, err
 			}
 			trys--
@@ -140,64 +153,92 @@ func getAccessLogWithTrys(client IAWSClient, secretID string) ([]types.AccessLog
 	return accessLogList, nil
 }
 
-func GetAccessLog(ctx context.Context, publicKey string, secretKey string, secretID string, region string) ([]types.AccessLog, error) {
-	client, err := NewAWSClient(ctx, publicKey, secretKey, region)
+func (s *Service) GetAccessLog(ctx context.Context, publicKey string, secretKey string, secretID string, region string) ([]types.AccessLog, error) {
+	key := getFetchKey(publicKey, secretKey, region, GetCacheAccessKey(secretID))
+	val, err, shared := s.accessLogGroup.Do(key, func() ([]types.AccessLog, error) {
+		return s.getAccessLog(ctx, publicKey, secretKey, secretID, region)
+	})
+	if shared {
+		s.Logger.Println("API-AWS: shared an in-flight access log lookup of", secretID)
+	}
+	return val, err
+}
+
+func (s *Service) getAccessLog(ctx context.Context, publicKey string, secretKey string, secretID string, region string) ([]types.AccessLog, error) {
+	client, err := s.NewClient(ctx, publicKey, secretKey, region)
 	if err != nil {
 		// failed to create AWSClient
-		log.Println("API-AWS: failed to create AWSClient")
+		s.Logger.Println("API-AWS: failed to create AWSClient")
 		return nil, err
 	}
 
-	cacheInstance := storage.GetCacheInstance()
-
-	if accessLog, err := getAccessLogWithTrys(client, secretID); err != nil {
+	if accessLog, err := s.syncAccessLog(client, secretID); err != nil {
 		// failed to retrive access log
 		return nil, err
 	} else {
 		// caching the accesslog
 		key := GetCacheAccessKey(secretID)
-		if err := storage.SetCacheValue[[]types.AccessLog](cacheInstance, key, accessLog); err != nil {
+		if err := s.AccessLogs.Set(key, accessLog, storage.WithTTL(AccessLogCacheTTL)); err != nil {
 			// failed to cache instance
-			fmt.Println("API-AWS: failed to cache access logs")
+			s.Logger.Println("API-AWS: failed to cache access logs")
 		}
 		return accessLog, nil
 	}
 
 }
-func GetSecretById(ctx context.Context, publicKey string, secretKey string, secretID string, region string) (*types.Secret, error) {
-	client, err := NewAWSClient(ctx, publicKey, secretKey, region)
+
+func (s *Service) GetSecretById(ctx context.Context, publicKey string, secretKey string, secretID string, region string) (*types.Secret, error) {
+	key := getFetchKey(publicKey, secretKey, region, GetCacheSecretKey(secretID))
+	val, err, shared := s.secretGroup.Do(key, func() (*types.Secret, error) {
+		return s.getSecretById(ctx, publicKey, secretKey, secretID, region)
+	})
+	if shared {
+		s.Logger.Println("API-AWS: shared an in-flight lookup of secret", secretID)
+	}
+	return val, err
+}
+
+func (s *Service) getSecretById(ctx context.Context, publicKey string, secretKey string, secretID string, region string) (*types.Secret, error) {
+	client, err := s.NewClient(ctx, publicKey, secretKey, region)
 	if err != nil {
 		// failed to create AWSClient
-		log.Println("API-AWS: failed to create AWSClient")
+		s.Logger.Println("API-AWS: failed to create AWSClient")
 		return nil, err
 	}
 
-	cacheInstance := storage.GetCacheInstance()
-
 	if secret, err := client.GetSecretById(secretID); err != nil {
 		// failed to retrive secred
 		return nil, err
 	} else {
 		// caching the secret
-		key := GetCacheSecretKey(secretID)
-		if err := storage.SetCacheValue[types.Secret](cacheInstance, key, *secret); err != nil {
+		if err := s.cacheSecret(secretID, *secret); err != nil {
 			// failed to cache instance
-			fmt.Println("API-AWS: failed to cache secret")
+			s.Logger.Println("API-AWS: failed to cache secret")
 		}
 		return secret, nil
 	}
 }
 
-func GetSecretByIdWithAccessLog(ctx context.Context, publicKey string, secretKey string, secretID string, region string) (*types.SingleSecretWithAccessLog, error) {
-	fmt.Println("API-AWS: Getting Report For", secretID)
-	client, err := NewAWSClient(ctx, publicKey, secretKey, region)
+func (s *Service) GetSecretByIdWithAccessLog(ctx context.Context, publicKey string, secretKey string, secretID string, region string) (*types.SingleSecretWithAccessLog, error) {
+	key := getFetchKey(publicKey, secretKey, region, "single"+secretID)
+	val, err, shared := s.secretWithAccessLogGroup.Do(key, func() (*types.SingleSecretWithAccessLog, error) {
+		return s.getSecretByIdWithAccessLog(ctx, publicKey, secretKey, secretID, region)
+	})
+	if shared {
+		s.Logger.Println("API-AWS: shared an in-flight report lookup of", secretID)
+	}
+	return val, err
+}
+
+func (s *Service) getSecretByIdWithAccessLog(ctx context.Context, publicKey string, secretKey string, secretID string, region string) (*types.SingleSecretWithAccessLog, error) {
+	s.Logger.Println("API-AWS: Getting Report For", secretID)
+	client, err := s.NewClient(ctx, publicKey, secretKey, region)
 	if err != nil {
 		// failed to create AWSClient
-		log.Println("API-AWS: failed to create AWSClient")
+		s.Logger.Println("API-AWS: failed to create AWSClient")
+		return nil, err
 	}
 
-	cache := storage.GetCacheInstance()
-
 	// defining number of trys
 	trys := 5
 	var secret *types.Secret
@@ -219,7 +260,7 @@ func GetSecretByIdWithAccessLog(ctx context.Context, publicKey string, secretKey
 	}
 
 	// getting the secret accesslog
-	accessLogList, err := getAccessLogWithTrys(client, secretID)
+	accessLogList, err := s.syncAccessLog(client, secretID)
 	if err != nil {
 		// failed to retrive all access log
 		return nil, err
@@ -227,9 +268,9 @@ func GetSecretByIdWithAccessLog(ctx context.Context, publicKey string, secretKey
 
 	// caching the access log
 	key := GetCacheAccessKey(secretID)
-	err = storage.SetCacheValue[[]types.AccessLog](cache, key, accessLogList)
+	err = s.AccessLogs.Set(key, accessLogList, storage.WithTTL(AccessLogCacheTTL))
 	if err != nil {
-		log.Println("API-AWS: failed to cache the Access Log of secret", secretID)
+		s.Logger.Println("API-AWS: failed to cache the Access Log of secret", secretID)
 	}
 
 	// The func GetSecretById won't return lastAccessTime to the secret
@@ -245,10 +286,9 @@ func GetSecretByIdWithAccessLog(ctx context.Context, publicKey string, secretKey
 	}
 
 	// caching the secret
-	key = GetCacheSecretKey(secretID)
-	err = storage.SetCacheValue[types.Secret](cache, key, *secret)
+	err = s.cacheSecret(secretID, *secret)
 	if err != nil {
-		log.Println("API-AWS: failed to cache the secret", secretID)
+		s.Logger.Println("API-AWS: failed to cache the secret", secretID)
 	}
 
 	toReturn := types.SingleSecretWithAccessLog{
@@ -258,23 +298,3 @@ func GetSecretByIdWithAccessLog(ctx context.Context, publicKey string, secretKey
 
 	return &toReturn, nil
 }
-
-func GenerateReportStringBySecret(secret types.Secret, accessLog []types.AccessLog) string {
-	fmt.Println("API-AWS: Generating Report For", secret.ARN)
-	// MetaData
-	report := " # Secret Metadata: \n"
-	report += fmt.Sprintf(" - Secret Name: 		%s\n", secret.Name)
-	report += fmt.Sprintf(" - Secret Created At:	   	%s\n", secret.CreatedAt)
-	report += fmt.Sprintf(" - Secret Last Accessed: 	%s\n", secret.LastAccessed)
-	report += fmt.Sprintf(" - Secret ARN: 			%s\n", secret.ARN)
-
-	// AccessLog
-	report += " - Secret Access Log: \n"
-	for _, accessLog := range accessLog {
-		report += fmt.Sprintf("{\n	User: %s\n", accessLog.User)
-		report += fmt.Sprintf("	Event Time: %s\n", accessLog.EventTime)
-		report += fmt.Sprintf("	Event Name: %s\n", accessLog.EventName)
-		report += fmt.Sprintf("	Event Source: %s\n}\n", accessLog.EventSource)
-	}
-	return report
-}
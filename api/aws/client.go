@@ -2,6 +2,7 @@ package aws
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"golang-secret-manager/types"
@@ -25,9 +26,11 @@ func isErrWithCode(err error, code int) bool {
 	return false
 }
 
+const defaultRegion = "us-east-1"
+
 type IAWSClient interface {
 	GetAllSecrets(nextToken *string) (types.AllSecrets, error)
-	GetAccessLog(secretID string, nextToken *string) (types.AllAccessLog, error)
+	GetAccessLog(secretID string, startTime *time.Time, endTime *time.Time, nextToken *string) (types.AllAccessLog, error)
 	GetSecretById(secretID string) (*types.Secret, error)
 }
 
@@ -42,7 +45,7 @@ type client struct {
 func NewAWSClient(ctx context.Context, publicKey string, secretKey string, region string) (IAWSClient, error) {
 	if region == "" {
 		// setting default value
-		region = "us-east-1"
+		region = defaultRegion
 	}
 	creds := credentials.NewStaticCredentials(publicKey, secretKey, "")
 	sess, err := session.NewSession(&aws.Config{
@@ -68,6 +71,8 @@ func (c *client) GetAllSecrets(nextToken *string) (types.AllSecrets, error) {
 	input := &secretsmanager.ListSecretsInput{
 		MaxResults: aws.Int64(100),
 		NextToken:  nextToken,
+		// the secrets that are scheduled for deletion are still listed
+		IncludePlannedDeletion: aws.Bool(true),
 	}
 
 	listSecretOutput := &secretsmanager.ListSecretsOutput{}
@@ -98,15 +103,10 @@ func (c *client) GetAllSecrets(nextToken *string) (types.AllSecrets, error) {
 
 		// adding all the secrets to the list
 		for _, secret := range listSecretOutput.SecretList {
-			var s types.Secret
-			s.Name = *secret.Name
-			s.ARN = *secret.ARN
-			s.CreatedAt = *secret.CreatedDate
-			s.LastAccessed = *secret.LastAccessedDate
-			secrets = append(secrets, s)
+			secrets = append(secrets, secretFromListEntry(secret))
 		}
 
-		if nextToken == nil {
+		if listSecretOutput.NextToken == nil {
 			// no more secrets to fetch
 			break
 		}
@@ -143,7 +143,9 @@ func (c *client) GetSecretById(secretID string) (*types.Secret, error) {
 	return secret, nil
 }
 
-func (c *client) GetAccessLog(secretID string, nextToken *string) (types.AllAccessLog, error) {
+// Returning the access log of the secret, when startTime or endTime are set
+// only the events between them are returned (CloudTrail includes both)
+func (c *client) GetAccessLog(secretID string, startTime *time.Time, endTime *time.Time, nextToken *string) (types.AllAccessLog, error) {
 
 	// creating the cloudtrail svc
 	svc := cloudtrail.New(c.Session)
@@ -157,6 +159,8 @@ func (c *client) GetAccessLog(secretID string, nextToken *string) (types.AllAcce
 		},
 		MaxResults: aws.Int64(50),
 		NextToken:  nextToken,
+		StartTime:  startTime,
+		EndTime:    endTime,
 	}
 
 	var list []types.AccessLog
@@ -179,13 +183,7 @@ func (c *client) GetAccessLog(secretID string, nextToken *string) (types.AllAcce
 			continue
 		}
 		for _, event := range result.Events {
-			val := types.AccessLog{
-				User:        *event.Username,
-				EventTime:   *event.EventTime,
-				EventName:   *event.EventName,
-				EventSource: *event.EventSource,
-			}
-			list = append(list, val)
+			list = append(list, accessLogFromLookupEvent(event))
 		}
 		if result.NextToken == nil {
 			// finished
@@ -198,3 +196,59 @@ func (c *client) GetAccessLog(secretID string, nextToken *string) (types.AllAcce
 	returnValue.NextToken = nil
 	return returnValue, nil
 }
+
+// The fields of the lookup result can be missing (events without a user
+// name), the full record inside CloudTrailEvent is used when it can be decoded
+func accessLogFromLookupEvent(event *cloudtrail.Event) types.AccessLog {
+	var record types.CloudTrailEvent
+	if raw := aws.StringValue(event.CloudTrailEvent); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &record); err != nil {
+			fmt.Println("Failed to decode the CloudTrail event:", aws.StringValue(event.EventId), err)
+		}
+	}
+	val := accessLogFromEvent(record)
+	if val.EventID == "" {
+		val.EventID = aws.StringValue(event.EventId)
+	}
+	if val.EventName == "" {
+		val.EventName = aws.StringValue(event.EventName)
+	}
+	if val.EventSource == "" {
+		val.EventSource = aws.StringValue(event.EventSource)
+	}
+	if val.EventTime.IsZero() {
+		val.EventTime = aws.TimeValue(event.EventTime)
+	}
+	if username := aws.StringValue(event.Username); username != "" {
+		val.User = username
+	}
+	if event.ReadOnly != nil {
+		val.ReadOnly = aws.StringValue(event.ReadOnly) == "true"
+	}
+	return val
+}
+
+// The dates are missing on secrets that were never accessed or rotated
+func secretFromListEntry(entry *secretsmanager.SecretListEntry) types.Secret {
+	secret := types.Secret{
+		Name:            aws.StringValue(entry.Name),
+		ARN:             aws.StringValue(entry.ARN),
+		CreatedAt:       aws.TimeValue(entry.CreatedDate),
+		LastAccessed:    aws.TimeValue(entry.LastAccessedDate),
+		RotationEnabled: aws.BoolValue(entry.RotationEnabled),
+		LastRotated:     aws.TimeValue(entry.LastRotatedDate),
+		NextRotation:    aws.TimeValue(entry.NextRotationDate),
+		DeletedDate:     aws.TimeValue(entry.DeletedDate),
+		KMSKeyID:        aws.StringValue(entry.KmsKeyId),
+	}
+	if entry.RotationRules != nil {
+		secret.RotationDays = aws.Int64Value(entry.RotationRules.AutomaticallyAfterDays)
+	}
+	if len(entry.Tags) > 0 {
+		secret.Tags = make(map[string]string)
+		for _, tag := range entry.Tags {
+			secret.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+	}
+	return secret
+}
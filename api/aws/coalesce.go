@@ -0,0 +1,17 @@
+package aws
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Building the key that identify a fetch, the secret key is hashed so it
+// won't be kept as is in the in-flight map
+func getFetchKey(publicKey string, secretKey string, region string, resource string) string {
+	sum := sha256.Sum256([]byte(secretKey))
+	if region == "" {
+		region = defaultRegion
+	}
+	return strings.Join([]string{publicKey, hex.EncodeToString(sum[:8]), region, resource}, "|")
+}
@@ -0,0 +1,332 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"golang-secret-manager/types"
+	"golang-secret-manager/utils/storage"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Source of the Secrets Manager events
+const secretsManagerEventSource = "secretsmanager.amazonaws.com"
+
+// Events that change the value or the metadata of the secret, the cached
+// secret is updated or invalidated by them
+var secretChangingEvents = map[string]bool{
+	"PutSecretValue":           true,
+	"UpdateSecret":             true,
+	"UpdateSecretVersionStage": true,
+	"RotateSecret":             true,
+	"RotationSucceeded":        true,
+	"RestoreSecret":            true,
+	"CancelRotateSecret":       true,
+}
+
+// Events that remove the secret
+var secretDeletingEvents = map[string]bool{
+	"DeleteSecret": true,
+}
+
+// Events that add a secret, the cached ARN lists don't have it
+var secretCreatingEvents = map[string]bool{
+	"CreateSecret":    true,
+	"ReplicateSecret": true,
+}
+
+// Decoding the CloudTrail records of a body, the body can be a CloudTrail log
+// file ({"Records": [...]}), an EventBridge event of a CloudTrail API call, a
+// list of records or a single record
+func ParseCloudTrailEvents(data []byte) ([]types.CloudTrailEvent, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty event body")
+	}
+
+	if data[0] == '[' {
+		var events []types.CloudTrailEvent
+		if err := json.Unmarshal(data, &events); err != nil {
+			return nil, fmt.Errorf("failed to decode the events: %v", err)
+		}
+		return events, nil
+	}
+
+	var body struct {
+		Records []types.CloudTrailEvent `json:"Records"`
+		Detail  *types.CloudTrailEvent  `json:"detail"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("failed to decode the events: %v", err)
+	}
+	if body.Records != nil {
+		return body.Records, nil
+	}
+	if body.Detail != nil {
+		return []types.CloudTrailEvent{*body.Detail}, nil
+	}
+
+	var event types.CloudTrailEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode the event: %v", err)
+	}
+	if event.EventName == "" {
+		return nil, fmt.Errorf("body is not a CloudTrail event")
+	}
+	return []types.CloudTrailEvent{event}, nil
+}
+
+// Applying the events on the cache instead of fetching everything again, the
+// access log of the secret is appended with the event and the secret is
+// updated or removed when the event changed it
+func (s *Service) ApplyEvents(events []types.CloudTrailEvent) types.EventIngestResponse {
+	response := types.EventIngestResponse{Received: len(events)}
+	changed := make(map[string]bool)
+	for _, event := range events {
+		keys, err := s.ApplyEvent(event)
+		if err != nil {
+			s.Logger.Println("API-AWS: failed to apply event:", event.EventID, event.EventName, err)
+			response.Failed++
+			continue
+		}
+		if len(keys) == 0 {
+			response.Ignored++
+			continue
+		}
+		response.Applied++
+		for _, key := range keys {
+			changed[key] = true
+		}
+	}
+	for key := range changed {
+		response.Keys = append(response.Keys, key)
+	}
+	sort.Strings(response.Keys)
+	return response
+}
+
+// Applying a single event, returning the cache keys that were changed
+func (s *Service) ApplyEvent(event types.CloudTrailEvent) ([]string, error) {
+	if event.EventSource != secretsManagerEventSource {
+		return nil, nil
+	}
+
+	var changed []string
+	if secretCreatingEvents[event.EventName] && event.ErrorCode == "" {
+		// the new secret is not in any of the lists, they are fetched again
+		for _, key := range storage.AllKeys(s.Cache) {
+			if !strings.HasPrefix(key, GetCacheARNKey("")) {
+				continue
+			}
+			if err := storage.PurgeKey(s.Cache, key); err != nil {
+				return changed, err
+			}
+			changed = append(changed, key)
+		}
+	}
+
+	for _, secretID := range s.eventSecretIDs(event) {
+		keys, err := s.applySecretEvent(secretID, event)
+		changed = append(changed, keys...)
+		if err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+func (s *Service) applySecretEvent(secretID string, event types.CloudTrailEvent) ([]string, error) {
+	var changed []string
+	secretKey := GetCacheSecretKey(secretID)
+	accessKey := GetCacheAccessKey(secretID)
+
+	if secretDeletingEvents[event.EventName] && event.ErrorCode == "" {
+		cached := make(map[string]bool)
+		for _, key := range storage.AllKeys(s.Cache) {
+			cached[key] = true
+		}
+		for _, key := range []string{secretKey, accessKey, GetCacheAccessSyncKey(secretID)} {
+			if !cached[key] {
+				continue
+			}
+			if err := storage.PurgeKey(s.Cache, key); err != nil {
+				return changed, err
+			}
+			changed = append(changed, key)
+		}
+		s.index.remove(secretID)
+		keys, err := s.removeFromARNLists(secretID)
+		return append(changed, keys...), err
+	}
+
+	// the event is published even when the access log is not cached, it is
+	// appended only when it is cached, a partial log must not look like the
+	// whole history
+	entry := accessLogFromEvent(event)
+	s.publishAccessLogs(secretID, []types.AccessLog{entry})
+	accessLog, err := s.AccessLogs.Get(accessKey)
+	if err == nil {
+		// merged like the synced events, the log is kept newest first and an
+		// event that is sent again is not added twice
+		accessLog = mergeAccessLogs(accessLog, []types.AccessLog{entry}, time.Now().Add(-s.retention()))
+		if err := s.AccessLogs.Set(accessKey, accessLog, storage.WithTTL(AccessLogCacheTTL)); err != nil {
+			return changed, err
+		}
+		changed = append(changed, accessKey)
+	} else if !storage.IsNotFound(err) && !storage.IsDecodeError(err) {
+		return changed, err
+	}
+	// the synced log is the one the next sync merges into, without the event
+	// it would be missing from it until CloudTrail returns it. The newest
+	// event time is not moved, the sync still fetches the events that were
+	// delivered late
+	syncKey := GetCacheAccessSyncKey(secretID)
+	state, err := s.AccessLogSyncs.Get(syncKey)
+	if err == nil {
+		state.Events = mergeAccessLogs(state.Events, []types.AccessLog{entry}, time.Now().Add(-s.retention()))
+		if err := s.AccessLogSyncs.Set(syncKey, state, storage.WithTTL(s.retention())); err != nil {
+			return changed, err
+		}
+		changed = append(changed, syncKey)
+	} else if !storage.IsNotFound(err) && !storage.IsDecodeError(err) {
+		return changed, err
+	}
+
+	secret, err := s.Secrets.Get(secretKey)
+	if err != nil {
+		if storage.IsNotFound(err) || storage.IsDecodeError(err) {
+			return changed, nil
+		}
+		return changed, err
+	}
+	if secretChangingEvents[event.EventName] && event.ErrorCode == "" {
+		versionID, _ := event.ResponseElements["versionId"].(string)
+		if versionID == "" {
+			// the new version is unknown, the secret is fetched again
+			if err := storage.PurgeKey(s.Cache, secretKey); err != nil {
+				return changed, err
+			}
+			s.index.remove(secretID)
+			return append(changed, secretKey), nil
+		}
+		secret.Version = versionID
+	} else if event.EventName == "GetSecretValue" && event.ErrorCode == "" {
+		if !event.EventTime.After(secret.LastAccessed) {
+			return changed, nil
+		}
+		secret.LastAccessed = event.EventTime
+	} else {
+		return changed, nil
+	}
+	if err := s.cacheSecret(secretID, secret); err != nil {
+		return changed, err
+	}
+	return append(changed, secretKey), nil
+}
+
+// Removing the ARN from every cached ARN list
+func (s *Service) removeFromARNLists(arn string) ([]string, error) {
+	var changed []string
+	for _, key := range storage.AllKeys(s.Cache) {
+		if !strings.HasPrefix(key, GetCacheARNKey("")) {
+			continue
+		}
+		arns, err := s.ARNLists.Get(key)
+		if err != nil {
+			continue
+		}
+		var filtered []string
+		for _, listed := range arns {
+			if listed != arn {
+				filtered = append(filtered, listed)
+			}
+		}
+		if len(filtered) == len(arns) {
+			continue
+		}
+		if err := s.ARNLists.Set(key, filtered, storage.WithTTL(ARNListCacheTTL)); err != nil {
+			return changed, err
+		}
+		changed = append(changed, key)
+	}
+	return changed, nil
+}
+
+// Returning the ids the secret of the event may be cached with, the event can
+// name the secret by its ARN, partial ARN or name
+func (s *Service) eventSecretIDs(event types.CloudTrailEvent) []string {
+	var ids []string
+	for _, resource := range event.Resources {
+		if resource.ARN != "" {
+			ids = append(ids, resource.ARN)
+		}
+	}
+	for _, field := range []map[string]interface{}{event.ResponseElements, event.RequestParameters, event.AdditionalEventData} {
+		for _, name := range []string{"arn", "aRN", "secretId", "SecretId", "name"} {
+			if id, ok := field[name].(string); ok && id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	// matching the names and partial ARNs to the cached secrets
+	seen := make(map[string]bool)
+	var resolved []string
+	add := func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			resolved = append(resolved, id)
+		}
+	}
+	for _, id := range ids {
+		add(id)
+		for _, cached := range s.lookupSecretIDs(id) {
+			add(cached)
+		}
+	}
+	return resolved
+}
+
+// Building the access log entry of the event
+func accessLogFromEvent(event types.CloudTrailEvent) types.AccessLog {
+	identity := event.UserIdentity
+	user := identity.UserName
+	var sessionIssuer, sessionName string
+	if identity.SessionContext != nil {
+		sessionIssuer = identity.SessionContext.SessionIssuer.ARN
+		if user == "" {
+			user = identity.SessionContext.SessionIssuer.UserName
+		}
+	}
+	if identity.Type == "AssumedRole" {
+		// the principal id of a role session is <role id>:<session name>
+		if i := strings.LastIndex(identity.PrincipalID, ":"); i != -1 {
+			sessionName = identity.PrincipalID[i+1:]
+		}
+	}
+	if user == "" {
+		user = identity.InvokedBy
+	}
+	versionStage, _ := event.RequestParameters["versionStage"].(string)
+	return types.AccessLog{
+		EventID:          event.EventID,
+		User:             user,
+		EventName:        event.EventName,
+		EventSource:      event.EventSource,
+		EventTime:        event.EventTime,
+		SourceIP:         event.SourceIPAddress,
+		UserAgent:        event.UserAgent,
+		UserIdentityType: identity.Type,
+		UserARN:          identity.ARN,
+		SessionIssuer:    sessionIssuer,
+		SessionName:      sessionName,
+		VersionStage:     versionStage,
+		ErrorCode:        event.ErrorCode,
+		ReadOnly:         event.ReadOnly != nil && *event.ReadOnly,
+	}
+}
@@ -0,0 +1,167 @@
+package aws
+
+import (
+	"golang-secret-manager/types"
+	"golang-secret-manager/utils/storage"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testSecretARN = "arn:aws:secretsmanager:eu-west-1:123456789012:secret:db-password-AbCdEf"
+
+// A CloudTrail log file like the ones that are dropped in the events
+// directory, the first record names the secret by its name and the second one
+// by its partial ARN
+const testCloudTrailFile = `{"Records": [
+	{
+		"eventID": "event-1",
+		"eventTime": "2024-03-01T12:00:00Z",
+		"eventName": "GetSecretValue",
+		"eventSource": "secretsmanager.amazonaws.com",
+		"sourceIPAddress": "10.0.0.1",
+		"userIdentity": {"type": "IAMUser", "userName": "alice", "arn": "arn:aws:iam::123456789012:user/alice"},
+		"requestParameters": {"secretId": "db-password"}
+	},
+	{
+		"eventID": "event-2",
+		"eventTime": "2024-03-01T12:05:00Z",
+		"eventName": "PutSecretValue",
+		"eventSource": "secretsmanager.amazonaws.com",
+		"userIdentity": {"type": "IAMUser", "userName": "bob"},
+		"requestParameters": {"secretId": "arn:aws:secretsmanager:eu-west-1:123456789012:secret:db-password"},
+		"responseElements": {"versionId": "version-2"}
+	}
+]}`
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	cache := storage.CreateFastCache(storage.FastCacheConfig{Expiration: time.Hour})
+	return NewService(cache, storage.JSONCodec, nil, log.New(io.Discard, "", 0))
+}
+
+func hasEvent(events []types.AccessLog, eventID string) bool {
+	for _, event := range events {
+		if event.EventID == eventID {
+			return true
+		}
+	}
+	return false
+}
+
+func TestApplyDroppedInEventFile(t *testing.T) {
+	s := newTestService(t)
+	s.AccessLogRetention = 100 * 365 * 24 * time.Hour
+
+	// the secret was cached before the start, it is not in the index yet
+	if err := s.Secrets.Set(GetCacheSecretKey(testSecretARN), types.Secret{Name: "db-password", ARN: testSecretARN, Version: "version-1"}); err != nil {
+		t.Fatal(err)
+	}
+	older := types.AccessLog{EventID: "event-0", EventName: "GetSecretValue", EventTime: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}
+	if err := s.AccessLogs.Set(GetCacheAccessKey(testSecretARN), []types.AccessLog{older}); err != nil {
+		t.Fatal(err)
+	}
+	synced := types.AccessLogSync{NewestEventTime: older.EventTime, SyncedAt: older.EventTime, Events: []types.AccessLog{older}}
+	if err := s.AccessLogSyncs.Set(GetCacheAccessSyncKey(testSecretARN), synced); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "trail.json")
+	if err := os.WriteFile(path, []byte(testCloudTrailFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := ParseCloudTrailEvents(data)
+	if err != nil {
+		t.Fatalf("ParseCloudTrailEvents: %v", err)
+	}
+
+	response := s.ApplyEvents(events)
+	if response.Received != 2 || response.Applied != 2 || response.Failed != 0 {
+		t.Fatalf("ApplyEvents: got %+v", response)
+	}
+	for _, key := range []string{GetCacheSecretKey(testSecretARN), GetCacheAccessKey(testSecretARN), GetCacheAccessSyncKey(testSecretARN)} {
+		found := false
+		for _, changed := range response.Keys {
+			found = found || changed == key
+		}
+		if !found {
+			t.Errorf("%s is not in the changed keys %v", key, response.Keys)
+		}
+	}
+
+	accessLog, err := s.AccessLogs.Get(GetCacheAccessKey(testSecretARN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := s.AccessLogSyncs.Get(GetCacheAccessSyncKey(testSecretARN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, eventID := range []string{"event-0", "event-1", "event-2"} {
+		if !hasEvent(accessLog, eventID) {
+			t.Errorf("%s is not in the access log", eventID)
+		}
+		if !hasEvent(state.Events, eventID) {
+			t.Errorf("%s is not in the synced access log", eventID)
+		}
+	}
+	if !state.NewestEventTime.Equal(older.EventTime) {
+		t.Errorf("the newest synced event time was moved to %v", state.NewestEventTime)
+	}
+
+	secret, err := s.Secrets.Get(GetCacheSecretKey(testSecretARN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Version != "version-2" {
+		t.Errorf("version %q, want version-2", secret.Version)
+	}
+	if want := events[0].EventTime; !secret.LastAccessed.Equal(want) {
+		t.Errorf("last accessed %v, want %v", secret.LastAccessed, want)
+	}
+
+	// applying the file again doesn't add the events twice
+	s.ApplyEvents(events)
+	if accessLog, _ := s.AccessLogs.Get(GetCacheAccessKey(testSecretARN)); len(accessLog) != 3 {
+		t.Errorf("%d events in the access log after applying the file again, want 3", len(accessLog))
+	}
+}
+
+func TestApplyDeleteEvent(t *testing.T) {
+	s := newTestService(t)
+	if err := s.cacheSecret(testSecretARN, types.Secret{Name: "db-password", ARN: testSecretARN}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AccessLogs.Set(GetCacheAccessKey(testSecretARN), []types.AccessLog{}); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := ParseCloudTrailEvents([]byte(`{
+		"eventID": "event-3",
+		"eventTime": "2024-03-02T00:00:00Z",
+		"eventName": "DeleteSecret",
+		"eventSource": "secretsmanager.amazonaws.com",
+		"requestParameters": {"secretId": "db-password"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response := s.ApplyEvents(events); response.Applied != 1 {
+		t.Fatalf("ApplyEvents: got %+v", response)
+	}
+	for _, key := range []string{GetCacheSecretKey(testSecretARN), GetCacheAccessKey(testSecretARN)} {
+		if _, err := s.Cache.Get(key); !storage.IsNotFound(err) {
+			t.Errorf("%s is still cached: %v", key, err)
+		}
+	}
+	if ids := s.lookupSecretIDs("db-password"); len(ids) != 0 {
+		t.Errorf("the deleted secret is still in the index: %v", ids)
+	}
+}
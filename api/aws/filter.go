@@ -0,0 +1,94 @@
+package aws
+
+import (
+	"context"
+	"golang-secret-manager/types"
+	"strings"
+)
+
+// Returning the access log of the secret with only the events of the filter.
+// A synced or cached log is filtered as it is, otherwise the time bounds are
+// asked from CloudTrail and the result is not cached since it is partial
+func (s *Service) GetFilteredAccessLog(ctx context.Context, publicKey string, secretKey string, secretID string, region string, filter *types.AccessLogFilter) ([]types.AccessLog, error) {
+	if accessLog, err := s.AccessLogs.Get(GetCacheAccessKey(secretID)); err == nil {
+		return FilterAccessLogs(accessLog, filter), nil
+	}
+
+	bounded := filter != nil && (filter.Since != nil || filter.Until != nil)
+	if bounded {
+		// a synced log only needs the events since the last sync
+		_, err := s.AccessLogSyncs.Get(GetCacheAccessSyncKey(secretID))
+		bounded = err != nil
+	}
+	if !bounded {
+		accessLog, err := s.GetAccessLog(ctx, publicKey, secretKey, secretID, region)
+		if err != nil {
+			return nil, err
+		}
+		return FilterAccessLogs(accessLog, filter), nil
+	}
+
+	client, err := s.NewClient(ctx, publicKey, secretKey, region)
+	if err != nil {
+		// failed to create AWSClient
+		s.Logger.Println("API-AWS: failed to create AWSClient")
+		return nil, err
+	}
+	accessLog, err := s.getAccessLogWithTrys(client, secretID, filter.Since, filter.Until)
+	if err != nil {
+		return nil, err
+	}
+	return FilterAccessLogs(accessLog, filter), nil
+}
+
+// Returning the events that are matching the filter, a nil filter matches
+// every event
+func FilterAccessLogs(accessLog []types.AccessLog, filter *types.AccessLogFilter) []types.AccessLog {
+	if filter == nil {
+		return accessLog
+	}
+	filtered := []types.AccessLog{}
+	for _, event := range accessLog {
+		if MatchAccessLog(event, filter) {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
+
+func MatchAccessLog(event types.AccessLog, filter *types.AccessLogFilter) bool {
+	if filter.Since != nil && event.EventTime.Before(*filter.Since) {
+		return false
+	}
+	if filter.Until != nil && event.EventTime.After(*filter.Until) {
+		return false
+	}
+	if len(filter.Events) > 0 && !containsFold(filter.Events, event.EventName) {
+		return false
+	}
+	if len(filter.Users) > 0 && !containsFold(filter.Users, event.User) && !containsFold(filter.Users, event.UserARN) {
+		return false
+	}
+	if filter.ExcludeServicePrincipals && isServicePrincipal(event) {
+		return false
+	}
+	return true
+}
+
+// The calls that an AWS service made, like a rotation lambda or CloudFormation
+// reading the secret
+func isServicePrincipal(event types.AccessLog) bool {
+	return event.UserIdentityType == "AWSService" || strings.HasSuffix(event.User, ".amazonaws.com")
+}
+
+func containsFold(list []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"golang-secret-manager/types"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Secrets that weren't accessed for this many days are reported as unused
+const DefaultUnusedDays = 90
+
+// The key that Secrets Manager encrypts with when no key is selected
+const defaultKMSKeyAlias = "alias/aws/secretsmanager"
+
+type HygieneOptions struct {
+	UnusedDays   int
+	RequiredTags []string
+}
+
+var severityRank = map[string]int{
+	types.SeverityHigh:   0,
+	types.SeverityMedium: 1,
+	types.SeverityLow:    2,
+	types.SeverityInfo:   3,
+}
+
+// Crawling all the secrets of the credential with their access logs and
+// checking each one of them
+func (s *Service) HygieneReport(ctx context.Context, publicKey string, secretKey string, region string, options HygieneOptions) (*types.HygieneReport, error) {
+	all, err := s.RetriveAllSecretsWithAccessLog(ctx, publicKey, secretKey, region)
+	if err != nil {
+		return nil, err
+	}
+	report := CheckHygiene(all.Secrets, all.AccessLog, options, time.Now())
+	report.Region = region
+	return &report, nil
+}
+
+// Checking the secrets, the access logs are used for the last access time when
+// they are newer than the LastAccessed date of the secret
+func CheckHygiene(secrets []types.Secret, accessLogs map[string][]types.AccessLog, options HygieneOptions, now time.Time) types.HygieneReport {
+	if options.UnusedDays <= 0 {
+		options.UnusedDays = DefaultUnusedDays
+	}
+	report := types.HygieneReport{
+		GeneratedAt:  now.UTC(),
+		UnusedDays:   options.UnusedDays,
+		RequiredTags: options.RequiredTags,
+		Secrets:      len(secrets),
+		Findings:     []types.HygieneFinding{},
+		Severities:   make(map[string]int),
+	}
+	unusedSince := now.Add(-time.Duration(options.UnusedDays) * 24 * time.Hour)
+
+	for _, secret := range secrets {
+		add := func(check string, severity string, format string, args ...interface{}) {
+			report.Findings = append(report.Findings, types.HygieneFinding{
+				SecretARN:  secret.ARN,
+				SecretName: secret.Name,
+				Check:      check,
+				Severity:   severity,
+				Message:    fmt.Sprintf(format, args...),
+			})
+			report.Severities[severity]++
+		}
+
+		if !secret.DeletedDate.IsZero() {
+			// the other checks don't matter for a secret that is going away
+			add(types.CheckPendingDeletion, types.SeverityMedium, "scheduled for deletion since %s", secret.DeletedDate.Format("2006-01-02"))
+			continue
+		}
+
+		lastAccess := lastAccessOf(secret, accessLogs[secret.ARN])
+		if lastAccess.IsZero() {
+			if secret.CreatedAt.Before(unusedSince) {
+				add(types.CheckNeverAccessed, types.SeverityMedium, "never accessed since it was created on %s", secret.CreatedAt.Format("2006-01-02"))
+			} else {
+				add(types.CheckNeverAccessed, types.SeverityLow, "never accessed, created on %s", secret.CreatedAt.Format("2006-01-02"))
+			}
+		} else if lastAccess.Before(unusedSince) {
+			add(types.CheckUnused, types.SeverityMedium, "not accessed in %d days, last access on %s", int(now.Sub(lastAccess).Hours()/24), lastAccess.Format("2006-01-02"))
+		}
+
+		if !secret.RotationEnabled {
+			add(types.CheckRotationDisabled, types.SeverityMedium, "automatic rotation is disabled")
+		} else if due := rotationDue(secret); !due.IsZero() && due.Before(now) {
+			add(types.CheckRotationOverdue, types.SeverityHigh, "rotation was due on %s", due.Format("2006-01-02"))
+		}
+
+		var missing []string
+		for _, tag := range options.RequiredTags {
+			if _, found := secret.Tags[tag]; !found {
+				missing = append(missing, tag)
+			}
+		}
+		if len(missing) > 0 {
+			add(types.CheckMissingTags, types.SeverityLow, "missing the tags: %s", strings.Join(missing, ", "))
+		}
+
+		if secret.KMSKeyID == "" || secret.KMSKeyID == defaultKMSKeyAlias {
+			add(types.CheckDefaultKMSKey, types.SeverityLow, "encrypted with the default KMS key (%s)", defaultKMSKeyAlias)
+		}
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if severityRank[a.Severity] != severityRank[b.Severity] {
+			return severityRank[a.Severity] < severityRank[b.Severity]
+		}
+		if a.SecretName != b.SecretName {
+			return a.SecretName < b.SecretName
+		}
+		return a.Check < b.Check
+	})
+	return report
+}
+
+// The newest of the LastAccessed date and the reads in the access log
+func lastAccessOf(secret types.Secret, accessLog []types.AccessLog) time.Time {
+	last := secret.LastAccessed
+	for _, event := range accessLog {
+		if event.EventName == "GetSecretValue" && event.ErrorCode == "" && event.EventTime.After(last) {
+			last = event.EventTime
+		}
+	}
+	return last
+}
+
+// The time the next rotation should have happened by, zero when it is unknown
+func rotationDue(secret types.Secret) time.Time {
+	if !secret.NextRotation.IsZero() {
+		return secret.NextRotation
+	}
+	if secret.RotationDays <= 0 {
+		return time.Time{}
+	}
+	last := secret.LastRotated
+	if last.IsZero() {
+		last = secret.CreatedAt
+	}
+	return last.Add(time.Duration(secret.RotationDays) * 24 * time.Hour)
+}
@@ -0,0 +1,52 @@
+package aws
+
+import (
+	"golang-secret-manager/utils/storage"
+	"sort"
+	"strings"
+)
+
+// Removing the cached secret and access log of the ARN from all the cache
+// layers, on dry run only the keys that would be removed are returned
+func (s *Service) InvalidateSecret(arn string, dryRun bool) ([]string, error) {
+	return s.invalidateKeys([]string{GetCacheSecretKey(arn), GetCacheAccessKey(arn), GetCacheAccessSyncKey(arn)}, dryRun)
+}
+
+// Removing the ARN list of the credential and every secret that is in it, the
+// next request of the credential will fetch everything again from AWS
+func (s *Service) InvalidateCredential(publicKey string, dryRun bool) ([]string, error) {
+	keys := []string{GetCacheARNKey(publicKey)}
+	arns, err := s.ARNLists.Get(GetCacheARNKey(publicKey))
+	if err != nil && !storage.IsNotFound(err) {
+		return nil, err
+	}
+	for _, arn := range arns {
+		keys = append(keys, GetCacheSecretKey(arn), GetCacheAccessKey(arn), GetCacheAccessSyncKey(arn))
+	}
+	return s.invalidateKeys(keys, dryRun)
+}
+
+func (s *Service) invalidateKeys(keys []string, dryRun bool) ([]string, error) {
+	cached := make(map[string]bool)
+	for _, key := range storage.AllKeys(s.Cache) {
+		cached[key] = true
+	}
+	var removed []string
+	for _, key := range keys {
+		if !cached[key] {
+			continue
+		}
+		if !dryRun {
+			if err := storage.PurgeKey(s.Cache, key); err != nil {
+				return removed, err
+			}
+			if secretID, ok := strings.CutPrefix(key, GetCacheSecretKey("")); ok {
+				s.index.remove(secretID)
+			}
+			s.Logger.Println("API-AWS: invalidated cache key:", key)
+		}
+		removed = append(removed, key)
+	}
+	sort.Strings(removed)
+	return removed, nil
+}
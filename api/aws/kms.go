@@ -0,0 +1,50 @@
+package aws
+
+import (
+	"fmt"
+	"golang-secret-manager/utils/storage"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// KMS client that is used for the envelope encryption of the persist cache,
+// the server own credentials are used (environment, shared config or role)
+type kmsClient struct {
+	svc *kms.KMS
+}
+
+func NewKMSClient(region string) (storage.KMSClient, error) {
+	if region == "" {
+		region = defaultRegion
+	}
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(region),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &kmsClient{svc: kms.New(sess)}, nil
+}
+
+func (c *kmsClient) GenerateDataKey(masterKeyID string) ([]byte, []byte, error) {
+	output, err := c.svc.GenerateDataKey(&kms.GenerateDataKeyInput{
+		KeyId:   aws.String(masterKeyID),
+		KeySpec: aws.String(kms.DataKeySpecAes256),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("API-AWS: failed to generate data key: %v", err)
+	}
+	return output.Plaintext, output.CiphertextBlob, nil
+}
+
+func (c *kmsClient) Decrypt(ciphertext []byte) ([]byte, error) {
+	output, err := c.svc.Decrypt(&kms.DecryptInput{
+		CiphertextBlob: ciphertext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("API-AWS: failed to decrypt data key: %v", err)
+	}
+	return output.Plaintext, nil
+}
@@ -0,0 +1,170 @@
+package aws
+
+import (
+	"golang-secret-manager/types"
+	"golang-secret-manager/utils/storage"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Key of the synced access log of the secret, it is kept for the whole
+// retention window while the served access log expires after a few minutes
+func GetCacheAccessSyncKey(secretID string) string {
+	return "acsync" + secretID
+}
+
+const (
+	// CloudTrail keeps the events for 90 days
+	DefaultAccessLogRetention = 90 * 24 * time.Hour
+
+	// CloudTrail delivers the events a few minutes after they happened, the
+	// sync asks for a little more than the newest event so late events are
+	// not missed, the events that are returned again are de-duplicated
+	AccessLogSyncOverlap = 15 * time.Minute
+)
+
+// Returning the access log of the secret, on a warm cache only the events
+// since the newest synced event are fetched from CloudTrail and merged into
+// the synced log
+func (s *Service) syncAccessLog(client IAWSClient, secretID string) ([]types.AccessLog, error) {
+	key := GetCacheAccessSyncKey(secretID)
+	state, err := s.AccessLogSyncs.Get(key)
+	if err != nil && !storage.IsNotFound(err) {
+		// the synced log can't be used, fetching the whole history
+		s.Logger.Println("API-AWS: failed to read the synced access log of", secretID, err)
+	}
+
+	now := time.Now()
+	var startTime *time.Time
+	if err == nil {
+		since := state.NewestEventTime
+		if since.IsZero() {
+			since = state.SyncedAt
+		}
+		since = since.Add(-AccessLogSyncOverlap)
+		startTime = &since
+	}
+
+	fetched, err := s.getAccessLogWithTrys(client, secretID, startTime, nil)
+	if err != nil {
+		return nil, err
+	}
+	if startTime != nil {
+		s.Logger.Println("API-AWS: synced", len(fetched), "access log events of", secretID, "since", startTime.Format(time.RFC3339))
+	}
+
+	// the first sync returns the whole history, only the events that were
+	// found by a later sync are new
+	if startTime != nil {
+		s.publishAccessLogs(secretID, newAccessLogs(state.Events, fetched))
+	}
+
+	state = types.AccessLogSync{
+		SyncedAt: now,
+		Events:   mergeAccessLogs(state.Events, fetched, now.Add(-s.retention())),
+	}
+	if len(state.Events) > 0 {
+		state.NewestEventTime = state.Events[0].EventTime
+	}
+	if err := s.AccessLogSyncs.Set(key, state, storage.WithTTL(s.retention())); err != nil {
+		s.Logger.Println("API-AWS: failed to cache the synced access log of", secretID)
+	}
+	return state.Events, nil
+}
+
+func (s *Service) retention() time.Duration {
+	if s.AccessLogRetention <= 0 {
+		return DefaultAccessLogRetention
+	}
+	return s.AccessLogRetention
+}
+
+// Merging the fetched events into the synced ones, the events are
+// de-duplicated by their id, the events before the cutoff are dropped and the
+// newest event is first (like CloudTrail returns them)
+func mergeAccessLogs(synced []types.AccessLog, fetched []types.AccessLog, cutoff time.Time) []types.AccessLog {
+	seen := make(map[string]bool)
+	var merged []types.AccessLog
+	for _, list := range [][]types.AccessLog{fetched, synced} {
+		for _, event := range list {
+			if event.EventTime.Before(cutoff) {
+				continue
+			}
+			id := accessLogID(event)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			merged = append(merged, event)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].EventTime.After(merged[j].EventTime)
+	})
+	return merged
+}
+
+// The fetched events that are not in the synced ones
+func newAccessLogs(synced []types.AccessLog, fetched []types.AccessLog) []types.AccessLog {
+	known := make(map[string]bool, len(synced))
+	for _, event := range synced {
+		known[accessLogID(event)] = true
+	}
+	var events []types.AccessLog
+	for _, event := range fetched {
+		if !known[accessLogID(event)] {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func (s *Service) publishAccessLogs(secretID string, events []types.AccessLog) {
+	if s.OnAccessLogs != nil && len(events) > 0 {
+		s.OnAccessLogs(secretID, events)
+	}
+}
+
+// Entries that were cached before the event id was kept are identified by
+// their time, name and user
+func accessLogID(event types.AccessLog) string {
+	if event.EventID != "" {
+		return event.EventID
+	}
+	return event.EventTime.UTC().Format(time.RFC3339Nano) + "|" + event.EventName + "|" + event.User
+}
+
+// Returning the cached access logs of the secrets (all the cached secrets when
+// arns is empty) without calling AWS, the synced log is used when it is cached
+// since it holds the whole retention window. The cached secrets are returned
+// with them
+func (s *Service) CachedAccessLogs(arns []string) (map[string][]types.AccessLog, map[string]types.Secret) {
+	if len(arns) == 0 {
+		found := make(map[string]bool)
+		for _, key := range storage.AllKeys(s.Cache) {
+			for _, prefix := range []string{GetCacheAccessSyncKey(""), GetCacheAccessKey("")} {
+				if arn, ok := strings.CutPrefix(key, prefix); ok && !found[arn] {
+					found[arn] = true
+					arns = append(arns, arn)
+				}
+			}
+		}
+	}
+
+	accessLogs := make(map[string][]types.AccessLog)
+	secrets := make(map[string]types.Secret)
+	for _, arn := range arns {
+		if state, err := s.AccessLogSyncs.Get(GetCacheAccessSyncKey(arn)); err == nil {
+			accessLogs[arn] = state.Events
+		} else if accessLog, err := s.AccessLogs.Get(GetCacheAccessKey(arn)); err == nil {
+			accessLogs[arn] = accessLog
+		} else {
+			continue
+		}
+		if secret, err := s.Secrets.Get(GetCacheSecretKey(arn)); err == nil {
+			secrets[arn] = secret
+		}
+	}
+	return accessLogs, secrets
+}
@@ -0,0 +1,62 @@
+package aws
+
+import (
+	"golang-secret-manager/types"
+	"golang-secret-manager/utils/storage"
+)
+
+// Names and versions of the types that are saved in the cache. When the
+// layout of a type changes its version is increased and a migration from the
+// previous version is registered in RegisterSchemas
+const (
+	// version 2 added the rotation, deletion, encryption and tags
+	SecretSchema        = "secret"
+	SecretSchemaVersion = 2
+
+	// version 2 added the event id, version 3 the caller and request fields
+	AccessLogSchema        = "access_log_list"
+	AccessLogSchemaVersion = 3
+
+	// holds access logs, its version follows their fields
+	AccessLogSyncSchema        = "access_log_sync"
+	AccessLogSyncSchemaVersion = 2
+
+	ARNListSchema        = "arn_list"
+	ARNListSchemaVersion = 1
+)
+
+// Registering the cached types and their migrations
+func RegisterSchemas(r *storage.SchemaRegistry) error {
+	if err := storage.RegisterSchema[types.Secret](r, SecretSchema, SecretSchemaVersion, "secret"); err != nil {
+		return err
+	}
+	if err := storage.RegisterSchema[[]types.AccessLog](r, AccessLogSchema, AccessLogSchemaVersion, "access"); err != nil {
+		return err
+	}
+	if err := storage.RegisterSchema[types.AccessLogSync](r, AccessLogSyncSchema, AccessLogSyncSchemaVersion, "acsync"); err != nil {
+		return err
+	}
+	if err := storage.RegisterSchema[[]string](r, ARNListSchema, ARNListSchemaVersion, "arnlst"); err != nil {
+		return err
+	}
+
+	// the entries that were saved before the envelope have the same layout
+	// as version 1
+	for _, name := range []string{SecretSchema, AccessLogSchema, ARNListSchema} {
+		if err := r.RegisterMigration(name, 0, storage.KeepData); err != nil {
+			return err
+		}
+	}
+
+	// the fields that were added to the access logs are left empty in the
+	// older entries
+	for _, from := range []int{1, 2} {
+		if err := r.RegisterMigration(AccessLogSchema, from, storage.KeepData); err != nil {
+			return err
+		}
+	}
+	if err := r.RegisterMigration(AccessLogSyncSchema, 1, storage.KeepData); err != nil {
+		return err
+	}
+	return r.RegisterMigration(SecretSchema, 1, storage.KeepData)
+}
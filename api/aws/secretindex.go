@@ -0,0 +1,119 @@
+package aws
+
+import (
+	"golang-secret-manager/types"
+	"golang-secret-manager/utils/storage"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Index of the cached secrets by their ARN, partial ARN and name, since the
+// CloudTrail events are naming the secret by any of them. The secrets that
+// the service caches are added when they are written, the ones that were
+// already in the cache (loaded from the persist layer) on the first lookup
+type secretIndex struct {
+	mu     sync.Mutex
+	loaded bool
+
+	// the ids the secret is cached with by each of its names
+	ids map[string]map[string]bool
+	// the names of each cached id, to remove it
+	names map[string][]string
+}
+
+func newSecretIndex() *secretIndex {
+	return &secretIndex{
+		ids:   make(map[string]map[string]bool),
+		names: make(map[string][]string),
+	}
+}
+
+// Secrets Manager adds a hyphen and six random characters to the name of
+// the secret in its ARN, the ARN without them is the partial ARN
+func partialARN(arn string) string {
+	if i := strings.LastIndex(arn, "-"); i != -1 && len(arn)-i == 7 {
+		return arn[:i]
+	}
+	return ""
+}
+
+func (x *secretIndex) add(secretID string, secret types.Secret) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.addLocked(secretID, secret)
+}
+
+func (x *secretIndex) addLocked(secretID string, secret types.Secret) {
+	x.removeLocked(secretID)
+	var names []string
+	for _, name := range []string{secret.ARN, partialARN(secret.ARN), secret.Name} {
+		if name == "" {
+			continue
+		}
+		if x.ids[name] == nil {
+			x.ids[name] = make(map[string]bool)
+		}
+		x.ids[name][secretID] = true
+		names = append(names, name)
+	}
+	x.names[secretID] = names
+}
+
+func (x *secretIndex) remove(secretID string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.removeLocked(secretID)
+}
+
+func (x *secretIndex) removeLocked(secretID string) {
+	for _, name := range x.names[secretID] {
+		delete(x.ids[name], secretID)
+		if len(x.ids[name]) == 0 {
+			delete(x.ids, name)
+		}
+	}
+	delete(x.names, secretID)
+}
+
+// Caching the secret and adding it to the index
+func (s *Service) cacheSecret(secretID string, secret types.Secret) error {
+	if err := s.Secrets.Set(GetCacheSecretKey(secretID), secret, storage.WithTTL(SecretCacheTTL)); err != nil {
+		return err
+	}
+	s.index.add(secretID, secret)
+	return nil
+}
+
+// Returning the ids of the cached secrets that the ARN, partial ARN or name
+// belongs to
+func (s *Service) lookupSecretIDs(name string) []string {
+	x := s.index
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.loaded {
+		// a single pass over the cache, the later secrets are added by
+		// cacheSecret
+		prefix := GetCacheSecretKey("")
+		for _, key := range storage.AllKeys(s.Cache) {
+			secretID, ok := strings.CutPrefix(key, prefix)
+			if !ok {
+				continue
+			}
+			if _, found := x.names[secretID]; found {
+				continue
+			}
+			if secret, err := s.Secrets.Get(key); err == nil {
+				x.addLocked(secretID, secret)
+			}
+		}
+		x.loaded = true
+	}
+
+	var ids []string
+	for id := range x.ids[name] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,97 @@
+package aws
+
+import (
+	"context"
+	"golang-secret-manager/types"
+	"golang-secret-manager/utils/singleflight"
+	"golang-secret-manager/utils/storage"
+	"log"
+	"sync"
+	"time"
+)
+
+// Creating the AWS client of a user, replaced in tests or when the clients
+// are created differently
+type ClientFactory func(ctx context.Context, publicKey string, secretKey string, region string) (IAWSClient, error)
+
+// Service is fetching the secrets from AWS and caching them, everything it
+// needs is passed to it so a few services with different caches can live in
+// the same process
+type Service struct {
+	Cache     storage.ICache
+	NewClient ClientFactory
+	Logger    *log.Logger
+
+	// Typed views of the cache namespaces
+	Secrets        *storage.TypedCache[types.Secret]
+	AccessLogs     *storage.TypedCache[[]types.AccessLog]
+	AccessLogSyncs *storage.TypedCache[types.AccessLogSync]
+	ARNLists       *storage.TypedCache[[]string]
+
+	// How long the synced access log events are kept
+	AccessLogRetention time.Duration
+
+	// Called with the access log events of the secret that were not known
+	// before, by the incremental sync and the applied CloudTrail events. Set
+	// before serving, it must not block
+	OnAccessLogs func(secretID string, events []types.AccessLog)
+
+	// Concurrent requests for the same credential, region and resource are
+	// sharing one in-flight fetch against AWS
+	allSecretsGroup          singleflight.Group[*types.AllSecretWithAccessLog]
+	secretWithAccessLogGroup singleflight.Group[*types.SingleSecretWithAccessLog]
+	secretGroup              singleflight.Group[*types.Secret]
+	accessLogGroup           singleflight.Group[[]types.AccessLog]
+
+	// The cached secrets by their names, for the CloudTrail events
+	index *secretIndex
+}
+
+func NewService(cache storage.ICache, codec storage.Codec, factory ClientFactory, logger *log.Logger) *Service {
+	if factory == nil {
+		factory = NewAWSClient
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Service{
+		Cache:              cache,
+		NewClient:          factory,
+		Logger:             logger,
+		Secrets:            storage.NewTypedCache[types.Secret](cache, codec),
+		AccessLogs:         storage.NewTypedCache[[]types.AccessLog](cache, codec),
+		AccessLogSyncs:     storage.NewTypedCache[types.AccessLogSync](cache, codec),
+		ARNLists:           storage.NewTypedCache[[]string](cache, codec),
+		AccessLogRetention: DefaultAccessLogRetention,
+		index:              newSecretIndex(),
+	}
+}
+
+// The package functions below are using a service on top of the process wide
+// cache, they are kept for compatibility
+
+var onceService sync.Once
+var service *Service
+
+func defaultService() *Service {
+	onceService.Do(func() {
+		service = NewService(storage.GetCacheInstance(), storage.JSONCodec, NewAWSClient, log.Default())
+	})
+	return service
+}
+
+func RetriveAllSecretsWithAccessLog(ctx context.Context, publicKey string, secretKey string, region string) (*types.AllSecretWithAccessLog, error) {
+	return defaultService().RetriveAllSecretsWithAccessLog(ctx, publicKey, secretKey, region)
+}
+
+func GetAccessLog(ctx context.Context, publicKey string, secretKey string, secretID string, region string) ([]types.AccessLog, error) {
+	return defaultService().GetAccessLog(ctx, publicKey, secretKey, secretID, region)
+}
+
+func GetSecretById(ctx context.Context, publicKey string, secretKey string, secretID string, region string) (*types.Secret, error) {
+	return defaultService().GetSecretById(ctx, publicKey, secretKey, secretID, region)
+}
+
+func GetSecretByIdWithAccessLog(ctx context.Context, publicKey string, secretKey string, secretID string, region string) (*types.SingleSecretWithAccessLog, error) {
+	return defaultService().GetSecretByIdWithAccessLog(ctx, publicKey, secretKey, secretID, region)
+}
@@ -0,0 +1,391 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang-secret-manager/api/aws"
+	"golang-secret-manager/api/server/config"
+	"golang-secret-manager/types"
+	"log"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The profile name of the jobs that crawl the credential of a request
+const RequestProfile = "request"
+
+var ErrUnknownProfile = errors.New("unknown crawler profile")
+
+var ErrStopped = errors.New("the crawler was stopped")
+
+// Crawler crawls every region of every profile each interval in the
+// background, and on demand. Each profile and region has its own schedule,
+// a failed crawl is retried sooner with a growing backoff. The jobs are kept
+// in memory, the history starts over when the server restarts
+type Crawler struct {
+	Service *aws.Service
+	Config  config.CrawlerConfig
+	Logger  *log.Logger
+
+	// Called with the result of every crawl that succeeded, set before Start
+	OnCrawl func(job types.CrawlJob, all *types.AllSecretWithAccessLog)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	slots  chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
+
+	mu      sync.Mutex
+	stopped bool
+	seq     int
+	jobs    []*types.CrawlJob
+	targets []*types.CrawlTarget
+	random  *rand.Rand
+}
+
+func New(service *aws.Service, cfg config.CrawlerConfig, logger *log.Logger) *Crawler {
+	if logger == nil {
+		logger = log.Default()
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Hour
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Minute
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Crawler{
+		Service: service,
+		Config:  cfg,
+		Logger:  logger,
+		ctx:     ctx,
+		cancel:  cancel,
+		slots:   make(chan struct{}, cfg.Concurrency),
+		random:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, profile := range cfg.Profiles {
+		for _, region := range profile.Regions {
+			c.targets = append(c.targets, &types.CrawlTarget{Profile: profile.Name, Region: region})
+		}
+	}
+	return c
+}
+
+// Running the schedule of every profile and region until Stop
+func (c *Crawler) Start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return
+	}
+	for _, profile := range c.Config.Profiles {
+		for _, region := range profile.Regions {
+			c.wg.Add(1)
+			go c.schedule(profile, region)
+		}
+	}
+}
+
+// Stopping the schedules and waiting for the running jobs, the jobs that are
+// triggered after it are refused
+func (c *Crawler) Stop() {
+	// no goroutine is added to the wait group once the flag is set, the
+	// wait group must not grow while it is waited on
+	c.mu.Lock()
+	c.stopped = true
+	c.mu.Unlock()
+	c.once.Do(c.cancel)
+	c.wg.Wait()
+}
+
+func (c *Crawler) schedule(profile config.CrawlerProfile, region string) {
+	defer c.wg.Done()
+	target := c.target(profile.Name, region)
+
+	// the first crawls are spread over the jitter so they don't all start
+	// with the server
+	delay := c.jitter()
+	for {
+		c.mu.Lock()
+		target.NextRun = time.Now().Add(delay)
+		c.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-c.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		job := c.enqueue(profile.Name, region, types.JobTriggerSchedule)
+		c.execute(job, profile.PublicKey, profile.SecretKey)
+
+		c.mu.Lock()
+		succeeded := job.Status == types.JobSucceeded
+		if succeeded {
+			target.Failures = 0
+		} else {
+			target.Failures++
+		}
+		failures := target.Failures
+		c.mu.Unlock()
+
+		if succeeded {
+			delay = c.Config.Interval + c.jitter()
+		} else {
+			delay = c.backoff(failures)
+		}
+	}
+}
+
+// The delay before the next try after the failures, doubled each time and
+// never longer than the interval
+func (c *Crawler) backoff(failures int) time.Duration {
+	delay := c.Config.Backoff
+	for i := 1; i < failures && delay < c.Config.Interval; i++ {
+		delay *= 2
+	}
+	if delay > c.Config.Interval {
+		delay = c.Config.Interval
+	}
+	return delay + c.jitter()/10
+}
+
+func (c *Crawler) jitter() time.Duration {
+	if c.Config.Jitter <= 0 {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Duration(c.random.Int63n(int64(c.Config.Jitter)))
+}
+
+// Crawling now, the jobs are returned queued and run in the background
+func (c *Crawler) Trigger(request types.CrawlRequest) ([]types.CrawlJob, error) {
+	type run struct {
+		profile   string
+		publicKey string
+		secretKey string
+		region    string
+	}
+	var runs []run
+	if request.Profile != "" {
+		found := false
+		for _, profile := range c.Config.Profiles {
+			if profile.Name != request.Profile {
+				continue
+			}
+			found = true
+			for _, region := range profile.Regions {
+				if request.Region == "" || request.Region == region {
+					runs = append(runs, run{profile.Name, profile.PublicKey, profile.SecretKey, region})
+				}
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownProfile, request.Profile)
+		}
+		if len(runs) == 0 {
+			return nil, fmt.Errorf("profile %s doesn't crawl the region %s", request.Profile, request.Region)
+		}
+	} else {
+		if request.PublicKey == "" || request.SecretKey == "" || request.Region == "" {
+			return nil, fmt.Errorf("a profile or a credential with a region is required")
+		}
+		runs = append(runs, run{RequestProfile, request.PublicKey, request.SecretKey, request.Region})
+	}
+
+	c.mu.Lock()
+	if c.stopped {
+		c.mu.Unlock()
+		return nil, ErrStopped
+	}
+	c.wg.Add(len(runs))
+	c.mu.Unlock()
+
+	jobs := make([]types.CrawlJob, 0, len(runs))
+	for _, r := range runs {
+		job := c.enqueue(r.profile, r.region, types.JobTriggerManual)
+		jobs = append(jobs, c.copyJob(job))
+		go func(r run) {
+			defer c.wg.Done()
+			c.execute(job, r.publicKey, r.secretKey)
+		}(r)
+	}
+	return jobs, nil
+}
+
+func (c *Crawler) enqueue(profile string, region string, trigger string) *types.CrawlJob {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	job := &types.CrawlJob{
+		ID:       strconv.Itoa(c.seq),
+		Profile:  profile,
+		Region:   region,
+		Trigger:  trigger,
+		Status:   types.JobQueued,
+		QueuedAt: time.Now().UTC(),
+		Errors:   []string{},
+	}
+	c.jobs = append(c.jobs, job)
+	return job
+}
+
+// Running the job when a slot is free
+func (c *Crawler) execute(job *types.CrawlJob, publicKey string, secretKey string) {
+	select {
+	case c.slots <- struct{}{}:
+		defer func() { <-c.slots }()
+	case <-c.ctx.Done():
+		c.finish(job, nil, ErrStopped)
+		return
+	}
+
+	c.mu.Lock()
+	started := time.Now().UTC()
+	job.StartedAt = &started
+	job.Status = types.JobRunning
+	c.mu.Unlock()
+
+	all, err := c.Service.RetriveAllSecretsWithAccessLog(c.ctx, publicKey, secretKey, job.Region)
+	c.finish(job, all, err)
+	if err == nil && c.OnCrawl != nil {
+		c.OnCrawl(c.copyJob(job), all)
+	}
+}
+
+func (c *Crawler) finish(job *types.CrawlJob, all *types.AllSecretWithAccessLog, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	if job.StartedAt != nil {
+		job.Duration = finished.Sub(*job.StartedAt).Round(time.Millisecond).String()
+	}
+
+	if err != nil {
+		job.Status = types.JobFailed
+		job.Errors = append(job.Errors, err.Error())
+		c.Logger.Printf("CRAWLER: job %s (%s, %s) failed: %v", job.ID, job.Profile, job.Region, err)
+	} else {
+		job.Status = types.JobSucceeded
+		job.Secrets = len(all.Secrets)
+		for _, secret := range all.Secrets {
+			accessLog, found := all.AccessLog[secret.ARN]
+			if !found {
+				// the crawl goes on without the access logs that failed
+				job.Errors = append(job.Errors, "failed to sync the access log of "+secret.ARN)
+				continue
+			}
+			job.AccessLogEvents += len(accessLog)
+		}
+		if target := c.findTarget(job.Profile, job.Region); target != nil {
+			target.LastSuccess = &finished
+		}
+		c.Logger.Printf("CRAWLER: job %s (%s, %s) crawled %d secrets in %s", job.ID, job.Profile, job.Region, job.Secrets, job.Duration)
+	}
+	c.trim()
+}
+
+// Keeping HistorySize finished jobs, the queued and running ones are kept
+func (c *Crawler) trim() {
+	if c.Config.HistorySize <= 0 {
+		return
+	}
+	finished := 0
+	for _, job := range c.jobs {
+		if job.FinishedAt != nil {
+			finished++
+		}
+	}
+	if finished <= c.Config.HistorySize {
+		return
+	}
+	kept := c.jobs[:0]
+	for _, job := range c.jobs {
+		if job.FinishedAt != nil && finished > c.Config.HistorySize {
+			finished--
+			continue
+		}
+		kept = append(kept, job)
+	}
+	c.jobs = kept
+}
+
+// Listing the jobs from the newest, the empty filters match every job
+func (c *Crawler) Jobs(status string, profile string, limit int) types.JobList {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	list := types.JobList{
+		Jobs:    []types.CrawlJob{},
+		Targets: make([]types.CrawlTarget, 0, len(c.targets)),
+	}
+	for i := len(c.jobs) - 1; i >= 0; i-- {
+		job := c.jobs[i]
+		if (status != "" && job.Status != status) || (profile != "" && job.Profile != profile) {
+			continue
+		}
+		list.Jobs = append(list.Jobs, c.copyJobLocked(job))
+		if limit > 0 && len(list.Jobs) == limit {
+			break
+		}
+	}
+	for _, target := range c.targets {
+		list.Targets = append(list.Targets, *target)
+	}
+	sort.Slice(list.Targets, func(i, j int) bool {
+		if list.Targets[i].Profile != list.Targets[j].Profile {
+			return list.Targets[i].Profile < list.Targets[j].Profile
+		}
+		return list.Targets[i].Region < list.Targets[j].Region
+	})
+	return list
+}
+
+func (c *Crawler) Job(id string) (types.CrawlJob, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, job := range c.jobs {
+		if job.ID == id {
+			return c.copyJobLocked(job), true
+		}
+	}
+	return types.CrawlJob{}, false
+}
+
+func (c *Crawler) copyJob(job *types.CrawlJob) types.CrawlJob {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.copyJobLocked(job)
+}
+
+func (c *Crawler) copyJobLocked(job *types.CrawlJob) types.CrawlJob {
+	copied := *job
+	copied.Errors = append([]string{}, job.Errors...)
+	return copied
+}
+
+func (c *Crawler) target(profile string, region string) *types.CrawlTarget {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.findTarget(profile, region)
+}
+
+func (c *Crawler) findTarget(profile string, region string) *types.CrawlTarget {
+	for _, target := range c.targets {
+		if target.Profile == profile && target.Region == region {
+			return target
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"golang-secret-manager/api/aws"
+	"golang-secret-manager/api/server/config"
+	"golang-secret-manager/types"
+	"golang-secret-manager/utils/storage"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errNoAWS = errors.New("no AWS in the tests")
+
+// A crawler of a service that fails every crawl
+func newTestCrawler(cfg config.CrawlerConfig) *Crawler {
+	logger := log.New(io.Discard, "", 0)
+	cache := storage.CreateFastCache(storage.FastCacheConfig{Expiration: time.Minute})
+	service := aws.NewService(cache, storage.JSONCodec, func(ctx context.Context, publicKey string, secretKey string, region string) (aws.IAWSClient, error) {
+		return nil, errNoAWS
+	}, logger)
+	return New(service, cfg, logger)
+}
+
+func TestBackoff(t *testing.T) {
+	c := newTestCrawler(config.CrawlerConfig{Interval: 10 * time.Minute, Backoff: time.Minute})
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 1, want: time.Minute},
+		{failures: 2, want: 2 * time.Minute},
+		{failures: 3, want: 4 * time.Minute},
+		{failures: 4, want: 8 * time.Minute},
+		// never longer than the interval
+		{failures: 5, want: 10 * time.Minute},
+		{failures: 50, want: 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := c.backoff(tt.failures); got != tt.want {
+			t.Errorf("backoff(%d): got %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestJitter(t *testing.T) {
+	if got := newTestCrawler(config.CrawlerConfig{}).jitter(); got != 0 {
+		t.Errorf("jitter without Jitter: got %s, want 0", got)
+	}
+
+	jitter := 10 * time.Second
+	c := newTestCrawler(config.CrawlerConfig{Interval: 10 * time.Minute, Backoff: time.Minute, Jitter: jitter})
+	for i := 0; i < 100; i++ {
+		if got := c.jitter(); got < 0 || got >= jitter {
+			t.Fatalf("jitter: got %s, want it in [0, %s)", got, jitter)
+		}
+		// a tenth of the jitter is added to the backoff
+		if got := c.backoff(2); got < 2*time.Minute || got >= 2*time.Minute+jitter/10 {
+			t.Fatalf("backoff(2): got %s, want it in [2m, 2m+%s)", got, jitter/10)
+		}
+	}
+}
+
+func TestNewDefaults(t *testing.T) {
+	c := newTestCrawler(config.CrawlerConfig{})
+	if c.Config.Interval != time.Hour || c.Config.Backoff != time.Minute || c.Config.Concurrency != 1 {
+		t.Errorf("defaults: got %+v", c.Config)
+	}
+}
+
+func TestScheduleBacksOffAfterAFailure(t *testing.T) {
+	c := newTestCrawler(config.CrawlerConfig{
+		Interval: 2 * time.Hour,
+		Backoff:  time.Hour,
+		Profiles: []config.CrawlerProfile{{Name: "prod", Regions: []string{"eu-west-1"}, PublicKey: "key", SecretKey: "secret"}},
+	})
+	c.Start()
+	defer c.Stop()
+
+	// without jitter the first crawl starts now and fails
+	deadline := time.Now().Add(5 * time.Second)
+	var target types.CrawlTarget
+	for time.Now().Before(deadline) {
+		target = c.Jobs("", "", 0).Targets[0]
+		if target.Failures == 1 && target.NextRun.After(time.Now().Add(30*time.Minute)) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if target.Failures != 1 {
+		t.Fatalf("failures: got %d, want 1", target.Failures)
+	}
+	if next := time.Until(target.NextRun); next <= 59*time.Minute || next > time.Hour {
+		t.Errorf("the retry is in %s, want the backoff of 1h", next)
+	}
+	jobs := c.Jobs(types.JobFailed, "prod", 0).Jobs
+	if len(jobs) != 1 || jobs[0].Trigger != types.JobTriggerSchedule {
+		t.Errorf("failed jobs: got %+v", jobs)
+	}
+}
+
+func TestTriggerAfterStop(t *testing.T) {
+	c := newTestCrawler(config.CrawlerConfig{})
+	request := types.CrawlRequest{PublicKey: "key", SecretKey: "secret", Region: "eu-west-1"}
+
+	// triggers that race with Stop are either waited for or refused
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Trigger(request); err != nil && !errors.Is(err, ErrStopped) {
+				t.Errorf("Trigger: %v", err)
+			}
+		}()
+	}
+	c.Stop()
+	wg.Wait()
+
+	if _, err := c.Trigger(request); !errors.Is(err, ErrStopped) {
+		t.Errorf("Trigger after Stop: got %v, want ErrStopped", err)
+	}
+	for _, job := range c.Jobs("", "", 0).Jobs {
+		if job.FinishedAt == nil {
+			t.Errorf("job %s is still %s after Stop", job.ID, job.Status)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package inventory
+
+import (
+	"fmt"
+	"golang-secret-manager/types"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Building the snapshot of the secrets, the account is taken from the ARN of
+// the secrets
+func FromSecrets(secrets []types.Secret, region string, trigger string, now time.Time) types.InventorySnapshot {
+	snapshot := types.InventorySnapshot{
+		TakenAt: now.UTC(),
+		Region:  region,
+		Trigger: trigger,
+		Secrets: make([]types.InventorySecret, 0, len(secrets)),
+	}
+	for _, secret := range secrets {
+		if snapshot.Account == "" {
+			snapshot.Account = accountOf(secret.ARN)
+		}
+		tags := make(map[string]string, len(secret.Tags))
+		for key, value := range secret.Tags {
+			tags[key] = value
+		}
+		snapshot.Secrets = append(snapshot.Secrets, types.InventorySecret{
+			ARN:             secret.ARN,
+			Name:            secret.Name,
+			Version:         secret.Version,
+			CreatedAt:       secret.CreatedAt.UTC(),
+			RotationEnabled: secret.RotationEnabled,
+			RotationDays:    secret.RotationDays,
+			LastRotated:     secret.LastRotated.UTC(),
+			DeletedDate:     secret.DeletedDate.UTC(),
+			KMSKeyID:        secret.KMSKeyID,
+			Tags:            tags,
+		})
+	}
+	sort.Slice(snapshot.Secrets, func(i, j int) bool {
+		return snapshot.Secrets[i].ARN < snapshot.Secrets[j].ARN
+	})
+	return snapshot
+}
+
+func Info(snapshot types.InventorySnapshot) types.InventorySnapshotInfo {
+	return types.InventorySnapshotInfo{
+		ID:      snapshot.ID,
+		TakenAt: snapshot.TakenAt,
+		Region:  snapshot.Region,
+		Account: snapshot.Account,
+		Trigger: snapshot.Trigger,
+		Secrets: len(snapshot.Secrets),
+	}
+}
+
+// arn:aws:secretsmanager:<region>:<account>:secret:<name>
+func accountOf(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return ""
+	}
+	return parts[4]
+}
+
+// Comparing the secrets of the snapshots by their ARN, a secret that was
+// deleted and created again with the same name has a new ARN so it is removed
+// and added
+func Diff(from types.InventorySnapshot, to types.InventorySnapshot) types.InventoryDiff {
+	diff := types.InventoryDiff{
+		From:    Info(from),
+		To:      Info(to),
+		Added:   []types.InventorySecret{},
+		Removed: []types.InventorySecret{},
+		Changed: []types.InventoryChange{},
+	}
+	switch {
+	case from.Account != "" && to.Account != "" && from.Account != to.Account:
+		diff.Warning = fmt.Sprintf("the snapshots are of different accounts (%s, %s)", from.Account, to.Account)
+	case from.Region != "" && to.Region != "" && from.Region != to.Region:
+		diff.Warning = fmt.Sprintf("the snapshots are of different regions (%s, %s)", from.Region, to.Region)
+	}
+
+	before := make(map[string]types.InventorySecret, len(from.Secrets))
+	for _, secret := range from.Secrets {
+		before[secret.ARN] = secret
+	}
+	after := make(map[string]bool, len(to.Secrets))
+	for _, secret := range to.Secrets {
+		after[secret.ARN] = true
+		old, found := before[secret.ARN]
+		if !found {
+			diff.Added = append(diff.Added, secret)
+			continue
+		}
+		if changes := compare(old, secret); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, types.InventoryChange{ARN: secret.ARN, Name: secret.Name, Changes: changes})
+		} else {
+			diff.Unchanged++
+		}
+	}
+	for _, secret := range from.Secrets {
+		if !after[secret.ARN] {
+			diff.Removed = append(diff.Removed, secret)
+		}
+	}
+
+	byName := func(secrets []types.InventorySecret) {
+		sort.SliceStable(secrets, func(i, j int) bool { return secrets[i].Name < secrets[j].Name })
+	}
+	byName(diff.Added)
+	byName(diff.Removed)
+	sort.SliceStable(diff.Changed, func(i, j int) bool { return diff.Changed[i].Name < diff.Changed[j].Name })
+	return diff
+}
+
+func compare(old types.InventorySecret, new types.InventorySecret) []types.InventoryFieldChange {
+	var changes []types.InventoryFieldChange
+	add := func(field string, from string, to string) {
+		if from != to {
+			changes = append(changes, types.InventoryFieldChange{Field: field, From: from, To: to})
+		}
+	}
+	add("name", old.Name, new.Name)
+	add("version", old.Version, new.Version)
+	add("rotation_enabled", strconv.FormatBool(old.RotationEnabled), strconv.FormatBool(new.RotationEnabled))
+	add("rotation_days", strconv.FormatInt(old.RotationDays, 10), strconv.FormatInt(new.RotationDays, 10))
+	add("last_rotated", formatTime(old.LastRotated), formatTime(new.LastRotated))
+	add("deleted_date", formatTime(old.DeletedDate), formatTime(new.DeletedDate))
+	add("kms_key_id", old.KMSKeyID, new.KMSKeyID)
+
+	keys := make(map[string]bool)
+	for key := range old.Tags {
+		keys[key] = true
+	}
+	for key := range new.Tags {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	for _, key := range sorted {
+		oldValue, inOld := old.Tags[key]
+		newValue, inNew := new.Tags[key]
+		switch {
+		case !inOld:
+			changes = append(changes, types.InventoryFieldChange{Field: "tag:" + key, To: newValue})
+		case !inNew:
+			changes = append(changes, types.InventoryFieldChange{Field: "tag:" + key, From: oldValue})
+		default:
+			add("tag:"+key, oldValue, newValue)
+		}
+	}
+	return changes
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Tracker keeps the last snapshot of each account and region, so each crawl
+// can be compared with the one before it
+type Tracker struct {
+	mu       sync.Mutex
+	previous map[string]types.InventorySnapshot
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{previous: make(map[string]types.InventorySnapshot)}
+}
+
+// Saving the snapshot and returning what changed since the previous one, nil
+// for the first snapshot. The snapshots without secrets have no account, they
+// are kept by the profile
+func (t *Tracker) Update(profile string, snapshot types.InventorySnapshot) *types.InventoryDiff {
+	key := snapshot.Account + "|" + snapshot.Region
+	if snapshot.Account == "" {
+		key = profile + "|" + snapshot.Region
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	previous, found := t.previous[key]
+	t.previous[key] = snapshot
+	if !found {
+		return nil
+	}
+	diff := Diff(previous, snapshot)
+	return &diff
+}
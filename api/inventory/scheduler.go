@@ -0,0 +1,100 @@
+package inventory
+
+import (
+	"context"
+	"golang-secret-manager/api/aws"
+	"golang-secret-manager/types"
+	"log"
+	"sync"
+	"time"
+)
+
+// Crawling the secrets of the credential now, the snapshot is not saved
+func Take(ctx context.Context, service *aws.Service, publicKey string, secretKey string, region string, trigger string) (*types.InventorySnapshot, error) {
+	all, err := service.RetriveAllSecretsWithAccessLog(ctx, publicKey, secretKey, region)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := FromSecrets(all.Secrets, region, trigger, time.Now())
+	return &snapshot, nil
+}
+
+// Scheduler saves a snapshot of the secrets of the server credential each
+// interval. When the server starts it waits for the rest of the interval
+// since the newest snapshot, so restarts don't add snapshots
+type Scheduler struct {
+	Service   *aws.Service
+	Store     *Store
+	Interval  time.Duration
+	PublicKey string
+	SecretKey string
+	Region    string
+	Logger    *log.Logger
+
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+func NewScheduler(service *aws.Service, store *Store, interval time.Duration, publicKey string, secretKey string, region string, logger *log.Logger) *Scheduler {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Scheduler{
+		Service:   service,
+		Store:     store,
+		Interval:  interval,
+		PublicKey: publicKey,
+		SecretKey: secretKey,
+		Region:    region,
+		Logger:    logger,
+		stop:      make(chan struct{}),
+	}
+}
+
+// Taking the snapshots in another goroutine until Stop
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		var wait time.Duration
+		if latest := s.Store.LatestTime(); !latest.IsZero() {
+			wait = s.Interval - time.Since(latest)
+		}
+		for {
+			if wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-s.stop:
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+			}
+			s.Run()
+			wait = s.Interval
+		}
+	}()
+}
+
+func (s *Scheduler) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+	s.wg.Wait()
+}
+
+// Taking and saving a single snapshot
+func (s *Scheduler) Run() (*types.InventorySnapshot, error) {
+	snapshot, err := Take(context.Background(), s.Service, s.PublicKey, s.SecretKey, s.Region, types.InventoryTriggerSchedule)
+	if err != nil {
+		s.Logger.Println("INVENTORY: failed to crawl the secrets:", err)
+		return nil, err
+	}
+	if err := s.Store.Save(snapshot); err != nil {
+		s.Logger.Println("INVENTORY: failed to save the snapshot:", err)
+		return nil, err
+	}
+	s.Logger.Println("INVENTORY: saved snapshot", snapshot.ID, "of", len(snapshot.Secrets), "secrets")
+	return snapshot, nil
+}
@@ -0,0 +1,237 @@
+package inventory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang-secret-manager/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The id of a snapshot is the time it was taken, so sorting the ids sorts the
+// snapshots
+const idLayout = "20060102T150405Z"
+
+const fileExt = ".json"
+
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Store keeps every snapshot as a JSON file in a directory, the newest Keep
+// snapshots are kept (all of them when it is 0)
+type Store struct {
+	Dir  string
+	Keep int
+
+	mu sync.Mutex
+}
+
+func NewStore(dir string, keep int) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("INVENTORY: failed to create the dir: %v", err)
+	}
+	return &Store{Dir: dir, Keep: keep}, nil
+}
+
+// Saving the snapshot with a new id, the file is written under a temp name
+// and renamed so a half written snapshot is never listed
+func (s *Store) Save(snapshot *types.InventorySnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := snapshot.TakenAt.UTC().Format(idLayout)
+	for i := 2; s.exists(id); i++ {
+		id = snapshot.TakenAt.UTC().Format(idLayout) + "-" + strconv.Itoa(i)
+	}
+	snapshot.ID = id
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(s.Dir, ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := os.Rename(temp.Name(), s.path(id)); err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	return s.prune()
+}
+
+func (s *Store) Load(id string) (*types.InventorySnapshot, error) {
+	if !validID(id) {
+		return nil, ErrSnapshotNotFound
+	}
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrSnapshotNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	var snapshot types.InventorySnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot %s: %v", id, err)
+	}
+	return &snapshot, nil
+}
+
+// Listing the snapshots from the oldest, the ones that can't be read are
+// skipped
+func (s *Store) List() ([]types.InventorySnapshotInfo, error) {
+	ids, err := s.ids()
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]types.InventorySnapshotInfo, 0, len(ids))
+	for _, id := range ids {
+		snapshot, err := s.Load(id)
+		if err != nil {
+			continue
+		}
+		infos = append(infos, Info(*snapshot))
+	}
+	return infos, nil
+}
+
+// Returning the snapshot of the reference: its id, "latest" or a time (RFC3339
+// or 2006-01-02) that selects the newest snapshot taken by then
+func (s *Store) Resolve(ref string) (*types.InventorySnapshot, error) {
+	ids, err := s.ids()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, ErrSnapshotNotFound
+	}
+	if ref == "latest" {
+		return s.Load(ids[len(ids)-1])
+	}
+	for _, id := range ids {
+		if id == ref {
+			return s.Load(id)
+		}
+	}
+
+	at, err := time.Parse(time.RFC3339, ref)
+	if err != nil {
+		date, dateErr := time.Parse("2006-01-02", ref)
+		if dateErr != nil {
+			return nil, ErrSnapshotNotFound
+		}
+		// the whole day is included
+		at = date.Add(24*time.Hour - time.Second)
+	}
+	bound := at.UTC().Format(idLayout)
+	for i := len(ids) - 1; i >= 0; i-- {
+		if ids[i][:len(idLayout)] <= bound {
+			return s.Load(ids[i])
+		}
+	}
+	return nil, ErrSnapshotNotFound
+}
+
+// The time of the newest snapshot, zero when there is none
+func (s *Store) LatestTime() time.Time {
+	ids, err := s.ids()
+	if err != nil || len(ids) == 0 {
+		return time.Time{}
+	}
+	t, _ := time.Parse(idLayout, ids[len(ids)-1][:len(idLayout)])
+	return t
+}
+
+func (s *Store) prune() error {
+	if s.Keep <= 0 {
+		return nil
+	}
+	ids, err := s.ids()
+	if err != nil {
+		return err
+	}
+	for len(ids) > s.Keep {
+		if err := os.Remove(s.path(ids[0])); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+// The ids of the saved snapshots from the oldest
+func (s *Store) ids() ([]string, error) {
+	files, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, file := range files {
+		id, found := strings.CutSuffix(file.Name(), fileExt)
+		if file.IsDir() || !found || !validID(id) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return lessID(ids[i], ids[j])
+	})
+	return ids, nil
+}
+
+// The snapshots that were taken in the same second have a "-2", "-3"...
+// suffix, they are sorted by it
+func lessID(a string, b string) bool {
+	if a[:len(idLayout)] != b[:len(idLayout)] {
+		return a < b
+	}
+	return suffixOf(a) < suffixOf(b)
+}
+
+func suffixOf(id string) int {
+	num, err := strconv.Atoi(strings.TrimPrefix(id[len(idLayout):], "-"))
+	if err != nil {
+		return 1
+	}
+	return num
+}
+
+// The id is also the name of the file, anything else is not accepted so a
+// request can't read other files
+func validID(id string) bool {
+	if len(id) < len(idLayout) {
+		return false
+	}
+	if _, err := time.Parse(idLayout, id[:len(idLayout)]); err != nil {
+		return false
+	}
+	suffix := id[len(idLayout):]
+	if suffix == "" {
+		return true
+	}
+	num, err := strconv.Atoi(strings.TrimPrefix(suffix, "-"))
+	return strings.HasPrefix(suffix, "-") && err == nil && num > 1
+}
+
+func (s *Store) exists(id string) bool {
+	_, err := os.Stat(s.path(id))
+	return err == nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.Dir, id+fileExt)
+}
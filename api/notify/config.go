@@ -0,0 +1,247 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"golang-secret-manager/types"
+	"net/url"
+	"os"
+	"path"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config of the notifier, the webhooks and the rules that route the
+// notifications to them. For example:
+//
+//	webhooks:
+//	  - name: security
+//	    url: https://hooks.slack.com/services/...
+//	    format: slack
+//	  - name: audit
+//	    url: https://audit.example.com/hooks/secrets
+//	    secret: ${AUDIT_HOOK_SECRET}
+//	rules:
+//	  - events: [unexpected_access, secret_deleted]
+//	    match:
+//	      tags: {env: prod}
+//	    webhooks: [security, audit]
+//	  - min_severity: high
+//	    webhooks: [audit]
+type Config struct {
+	Webhooks []Webhook `yaml:"webhooks"`
+	Rules    []Rule    `yaml:"rules"`
+
+	// Tries of each delivery, the delay between them starts at Backoff and
+	// is doubled
+	Retries int           `yaml:"retries"`
+	Backoff time.Duration `yaml:"backoff"`
+	Timeout time.Duration `yaml:"timeout"`
+
+	// JSON lines file of the notifications that couldn't be delivered
+	DeadLetter string `yaml:"dead_letter"`
+}
+
+// The environment variables in the url and the secret are expanded, so they
+// don't have to be saved in the file
+type Webhook struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+
+	// The key of the HMAC-SHA256 signature of the payload, sent in the
+	// X-Signature-256 header
+	Secret string `yaml:"secret"`
+
+	// generic (the notification as JSON) or slack
+	Format string `yaml:"format"`
+}
+
+const (
+	FormatGeneric = "generic"
+	FormatSlack   = "slack"
+)
+
+// Selecting notifications and the webhooks they are sent to, the empty fields
+// match every notification. A notification is sent once to each webhook of
+// all the rules it matches
+type Rule struct {
+	Events      []string `yaml:"events"`
+	MinSeverity string   `yaml:"min_severity"`
+	Match       Match    `yaml:"match"`
+	Webhooks    []string `yaml:"webhooks"`
+}
+
+type Match struct {
+	// Glob pattern of the secret name
+	Name string `yaml:"name"`
+
+	// The secret must have every tag, "*" matches any value
+	Tags map[string]string `yaml:"tags"`
+}
+
+var DefaultConfig = Config{
+	Retries:    5,
+	Backoff:    2 * time.Second,
+	Timeout:    10 * time.Second,
+	DeadLetter: "./notify-dead-letter.jsonl",
+}
+
+var knownEvents = map[string]bool{
+	types.NotifySecretCreated:    true,
+	types.NotifySecretDeleted:    true,
+	types.NotifySecretRotated:    true,
+	types.NotifyUnexpectedAccess: true,
+	types.NotifyRotationOverdue:  true,
+}
+
+// Parsing the config, unknown fields are errors like in the policy
+func Parse(data []byte) (*Config, error) {
+	config := DefaultConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse the notify config: %v", err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func Load(filePath string) (*Config, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the notify config: %v", err)
+	}
+	return Parse(data)
+}
+
+func (c *Config) Validate() error {
+	if len(c.Webhooks) == 0 {
+		return fmt.Errorf("notify config has no webhooks")
+	}
+	if c.Retries <= 0 {
+		c.Retries = 1
+	}
+	if c.Backoff <= 0 {
+		c.Backoff = DefaultConfig.Backoff
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultConfig.Timeout
+	}
+
+	names := make(map[string]bool)
+	for i := range c.Webhooks {
+		webhook := &c.Webhooks[i]
+		if webhook.Name == "" {
+			return fmt.Errorf("webhook %d has no name", i+1)
+		}
+		if names[webhook.Name] {
+			return fmt.Errorf("webhook %s is defined twice", webhook.Name)
+		}
+		names[webhook.Name] = true
+		webhook.URL = os.ExpandEnv(webhook.URL)
+		webhook.Secret = os.ExpandEnv(webhook.Secret)
+		parsed, err := url.Parse(webhook.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("webhook %s: invalid url: %s", webhook.Name, webhook.URL)
+		}
+		switch webhook.Format {
+		case "":
+			webhook.Format = FormatGeneric
+		case FormatGeneric, FormatSlack:
+		default:
+			return fmt.Errorf("webhook %s: unknown format: %s", webhook.Name, webhook.Format)
+		}
+	}
+
+	if len(c.Rules) == 0 {
+		return fmt.Errorf("notify config has no rules")
+	}
+	for i, rule := range c.Rules {
+		if len(rule.Webhooks) == 0 {
+			return fmt.Errorf("rule %d has no webhooks", i+1)
+		}
+		for _, name := range rule.Webhooks {
+			if !names[name] {
+				return fmt.Errorf("rule %d: unknown webhook: %s", i+1, name)
+			}
+		}
+		for _, event := range rule.Events {
+			if !knownEvents[event] {
+				return fmt.Errorf("rule %d: unknown event: %s", i+1, event)
+			}
+		}
+		switch rule.MinSeverity {
+		case "", types.SeverityHigh, types.SeverityMedium, types.SeverityLow, types.SeverityInfo:
+		default:
+			return fmt.Errorf("rule %d: unknown severity: %s", i+1, rule.MinSeverity)
+		}
+		if _, err := path.Match(rule.Match.Name, ""); err != nil {
+			return fmt.Errorf("rule %d: invalid name pattern: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// The webhooks of all the rules that match the notification
+func (c *Config) Route(notification types.Notification) []Webhook {
+	selected := make(map[string]bool)
+	for _, rule := range c.Rules {
+		if rule.matches(notification) {
+			for _, name := range rule.Webhooks {
+				selected[name] = true
+			}
+		}
+	}
+	var webhooks []Webhook
+	for _, webhook := range c.Webhooks {
+		if selected[webhook.Name] {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks
+}
+
+func (r Rule) matches(notification types.Notification) bool {
+	if len(r.Events) > 0 {
+		found := false
+		for _, event := range r.Events {
+			if event == notification.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.MinSeverity != "" && severityRank(notification.Severity) > severityRank(r.MinSeverity) {
+		return false
+	}
+	if r.Match.Name != "" {
+		if matched, _ := path.Match(r.Match.Name, notification.SecretName); !matched {
+			return false
+		}
+	}
+	for key, value := range r.Match.Tags {
+		actual, found := notification.Tags[key]
+		if !found || (value != "*" && value != actual) {
+			return false
+		}
+	}
+	return true
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case types.SeverityHigh:
+		return 0
+	case types.SeverityMedium:
+		return 1
+	case types.SeverityLow:
+		return 2
+	}
+	return 3
+}
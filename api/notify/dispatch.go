@@ -0,0 +1,289 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"golang-secret-manager/types"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The headers of a delivery, the signature is the HMAC-SHA256 of the body with
+// the secret of the webhook ("sha256=<hex>")
+const (
+	SignatureHeader = "X-Signature-256"
+	EventHeader     = "X-Notify-Event"
+	DeliveryHeader  = "X-Notify-Delivery"
+)
+
+// Notifications that are waiting for delivery, more are dropped (and logged)
+// so a slow webhook doesn't hold the crawler
+const queueSize = 1000
+
+// Deliveries that are sent at the same time, the retries of a webhook that is
+// down don't hold the others
+const workers = 4
+
+type delivery struct {
+	webhook      Webhook
+	notification types.Notification
+}
+
+// Dispatcher sends the notifications to the webhooks of the rules they match,
+// in the background. A delivery is tried Retries times with a growing delay,
+// then it is saved to the dead letter file
+type Dispatcher struct {
+	Config *Config
+	Client *http.Client
+	Logger *log.Logger
+
+	queue chan delivery
+	stop  chan struct{}
+	once  sync.Once
+	wg    sync.WaitGroup
+
+	deadLetterMu sync.Mutex
+}
+
+func NewDispatcher(config *Config, logger *log.Logger) *Dispatcher {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Dispatcher{
+		Config: config,
+		Client: &http.Client{Timeout: config.Timeout},
+		Logger: logger,
+		queue:  make(chan delivery, queueSize),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Delivering the queued notifications until Stop
+func (d *Dispatcher) Start() {
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for {
+				select {
+				case <-d.stop:
+					return
+				case item := <-d.queue:
+					d.deliver(item)
+				}
+			}
+		}()
+	}
+}
+
+// Stopping the delivery, the notifications that are still queued are saved
+// to the dead letter file so they are not lost
+func (d *Dispatcher) Stop() {
+	d.once.Do(func() {
+		close(d.stop)
+	})
+	d.wg.Wait()
+	for {
+		select {
+		case item := <-d.queue:
+			d.deadLetter(item, 0, fmt.Errorf("the server stopped before delivery"))
+		default:
+			return
+		}
+	}
+}
+
+// Queuing the notification for every webhook that its rules route it to
+func (d *Dispatcher) Notify(notifications ...types.Notification) {
+	for _, notification := range notifications {
+		for _, webhook := range d.Config.Route(notification) {
+			select {
+			case d.queue <- delivery{webhook: webhook, notification: notification}:
+			default:
+				d.Logger.Println("NOTIFY: the queue is full, dropped", notification.Type, "of", notification.SecretARN, "to", webhook.Name)
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(item delivery) {
+	body, err := Payload(item.webhook.Format, item.notification)
+	if err != nil {
+		d.deadLetter(item, 0, err)
+		return
+	}
+
+	backoff := d.Config.Backoff
+	for attempt := 1; ; attempt++ {
+		err = d.send(item, body)
+		if err == nil {
+			return
+		}
+		if attempt >= d.Config.Retries {
+			d.Logger.Println("NOTIFY: failed to deliver", item.notification.ID, "to", item.webhook.Name, "after", attempt, "tries:", err)
+			d.deadLetter(item, attempt, err)
+			return
+		}
+		select {
+		case <-d.stop:
+			d.deadLetter(item, attempt, err)
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (d *Dispatcher) send(item delivery, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, item.webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, item.notification.Type)
+	req.Header.Set(DeliveryHeader, item.notification.ID)
+	if item.webhook.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(item.webhook.Secret, body))
+	}
+
+	res, err := d.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status: %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Appending the notification to the dead letter file, a JSON object per line
+func (d *Dispatcher) deadLetter(item delivery, attempts int, cause error) {
+	if d.Config.DeadLetter == "" {
+		return
+	}
+	line, err := json.Marshal(types.DeadLetter{
+		Webhook:      item.webhook.Name,
+		URL:          item.webhook.URL,
+		Attempts:     attempts,
+		LastError:    cause.Error(),
+		FailedAt:     time.Now().UTC(),
+		Notification: item.notification,
+	})
+	if err != nil {
+		d.Logger.Println("NOTIFY: failed to encode the dead letter:", err)
+		return
+	}
+
+	d.deadLetterMu.Lock()
+	defer d.deadLetterMu.Unlock()
+	file, err := os.OpenFile(d.Config.DeadLetter, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		d.Logger.Println("NOTIFY: failed to open the dead letter file:", err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		d.Logger.Println("NOTIFY: failed to write the dead letter:", err)
+	}
+}
+
+// The signature of the body, the receiver computes it with the same secret
+// and compares it with hmac.Equal
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// The body that is sent to a webhook of the format
+func Payload(format string, notification types.Notification) ([]byte, error) {
+	if format == FormatSlack {
+		return json.Marshal(slackMessage(notification))
+	}
+	return json.Marshal(notification)
+}
+
+var slackColors = map[string]string{
+	types.SeverityHigh:   "#d32f2f",
+	types.SeverityMedium: "#f57c00",
+	types.SeverityLow:    "#1976d2",
+	types.SeverityInfo:   "#9e9e9e",
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type slackAttachment struct {
+	Color    string       `json:"color"`
+	Fallback string       `json:"fallback"`
+	Text     string       `json:"text"`
+	Fields   []slackField `json:"fields"`
+	Ts       int64        `json:"ts"`
+}
+
+type slackPayload struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+// A message for Slack incoming webhooks (and the chats that accept the same
+// payload)
+func slackMessage(notification types.Notification) slackPayload {
+	name := notification.SecretName
+	if name == "" {
+		name = notification.SecretARN
+	}
+	title := fmt.Sprintf("[%s] %s: %s", notification.Severity, notification.Type, name)
+	fields := []slackField{
+		{Title: "Secret", Value: notification.SecretARN},
+	}
+	if notification.Principal != "" {
+		fields = append(fields, slackField{Title: "Principal", Value: notification.Principal})
+	}
+	if notification.Region != "" {
+		fields = append(fields, slackField{Title: "Region", Value: notification.Region, Short: true})
+	}
+	if notification.Profile != "" {
+		fields = append(fields, slackField{Title: "Profile", Value: notification.Profile, Short: true})
+	}
+	return slackPayload{
+		Text: "*" + title + "*",
+		Attachments: []slackAttachment{{
+			Color:    slackColors[notification.Severity],
+			Fallback: title + " - " + notification.Message,
+			Text:     notification.Message,
+			Fields:   fields,
+			Ts:       notification.OccurredAt.Unix(),
+		}},
+	}
+}
+
+var idMu sync.Mutex
+var lastID int64
+
+// A unique id of the notification, the receivers can drop the duplicates of
+// a retried delivery by it
+func newID(now time.Time) string {
+	idMu.Lock()
+	defer idMu.Unlock()
+	id := now.UnixNano()
+	if id <= lastID {
+		id = lastID + 1
+	}
+	lastID = id
+	return "ntf-" + strconv.FormatInt(id, 36)
+}
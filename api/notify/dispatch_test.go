@@ -0,0 +1,338 @@
+package notify
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"golang-secret-manager/types"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A webhook that answers with the statuses in order, then with 200
+type testHook struct {
+	server   *httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []recordedRequest
+	received chan struct{}
+}
+
+type recordedRequest struct {
+	header http.Header
+	body   []byte
+	at     time.Time
+}
+
+func newTestHook(t *testing.T, statuses ...int) *testHook {
+	t.Helper()
+	hook := &testHook{statuses: statuses, received: make(chan struct{}, 100)}
+	hook.server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		hook.mu.Lock()
+		hook.requests = append(hook.requests, recordedRequest{header: r.Header.Clone(), body: body, at: time.Now()})
+		status := http.StatusOK
+		if len(hook.statuses) > 0 {
+			status = hook.statuses[0]
+			hook.statuses = hook.statuses[1:]
+		}
+		hook.mu.Unlock()
+		rw.WriteHeader(status)
+		hook.received <- struct{}{}
+	}))
+	t.Cleanup(hook.server.Close)
+	return hook
+}
+
+func (h *testHook) recorded() []recordedRequest {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]recordedRequest(nil), h.requests...)
+}
+
+func testDispatcher(t *testing.T, config *Config) *Dispatcher {
+	t.Helper()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	return NewDispatcher(config, log.New(io.Discard, "", 0))
+}
+
+func testNotification() types.Notification {
+	return types.Notification{
+		ID:         "ntf-1",
+		Type:       types.NotifyUnexpectedAccess,
+		Severity:   types.SeverityHigh,
+		SecretARN:  "arn:aws:secretsmanager:eu-west-1:123456789012:secret:db-password",
+		SecretName: "db-password",
+		Message:    "GetSecretValue by an unexpected principal",
+		OccurredAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Principal:  "arn:aws:iam::123456789012:user/mallory",
+		Region:     "eu-west-1",
+		Profile:    "prod",
+		Tags:       map[string]string{"env": "prod"},
+	}
+}
+
+func TestDeliverySignature(t *testing.T) {
+	hook := newTestHook(t)
+	d := testDispatcher(t, &Config{
+		Webhooks: []Webhook{{Name: "audit", URL: hook.server.URL, Secret: "s3cret"}},
+		Rules:    []Rule{{Webhooks: []string{"audit"}}},
+		Retries:  1,
+	})
+
+	notification := testNotification()
+	d.deliver(delivery{webhook: d.Config.Webhooks[0], notification: notification})
+
+	requests := hook.recorded()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	req := requests[0]
+	// the receiver side of the signature
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(req.body)
+	if got, want := req.header.Get(SignatureHeader), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("signature: got %q, want %q", got, want)
+	}
+	if got := req.header.Get(EventHeader); got != notification.Type {
+		t.Errorf("event header: got %q, want %q", got, notification.Type)
+	}
+	if got := req.header.Get(DeliveryHeader); got != notification.ID {
+		t.Errorf("delivery header: got %q, want %q", got, notification.ID)
+	}
+
+	var sent types.Notification
+	if err := json.Unmarshal(req.body, &sent); err != nil {
+		t.Fatalf("generic payload is not a notification: %v", err)
+	}
+	if sent.ID != notification.ID || sent.SecretARN != notification.SecretARN {
+		t.Errorf("payload: got %+v", sent)
+	}
+
+	// no secret, no signature
+	d.Config.Webhooks[0].Secret = ""
+	d.deliver(delivery{webhook: d.Config.Webhooks[0], notification: notification})
+	if requests = hook.recorded(); requests[1].header.Get(SignatureHeader) != "" {
+		t.Errorf("a webhook without a secret got a signature")
+	}
+}
+
+func TestDeliveryRetriesWithBackoff(t *testing.T) {
+	hook := newTestHook(t, http.StatusServiceUnavailable, http.StatusInternalServerError)
+	deadLetter := filepath.Join(t.TempDir(), "dead.jsonl")
+	backoff := 20 * time.Millisecond
+	d := testDispatcher(t, &Config{
+		Webhooks:   []Webhook{{Name: "audit", URL: hook.server.URL}},
+		Rules:      []Rule{{Webhooks: []string{"audit"}}},
+		Retries:    3,
+		Backoff:    backoff,
+		DeadLetter: deadLetter,
+	})
+
+	d.deliver(delivery{webhook: d.Config.Webhooks[0], notification: testNotification()})
+
+	requests := hook.recorded()
+	if len(requests) != 3 {
+		t.Fatalf("got %d tries, want 3", len(requests))
+	}
+	if wait := requests[1].at.Sub(requests[0].at); wait < backoff {
+		t.Errorf("first retry after %v, want at least %v", wait, backoff)
+	}
+	if wait := requests[2].at.Sub(requests[1].at); wait < 2*backoff {
+		t.Errorf("second retry after %v, want at least %v", wait, 2*backoff)
+	}
+	for _, req := range requests[1:] {
+		if req.header.Get(DeliveryHeader) != requests[0].header.Get(DeliveryHeader) {
+			t.Error("a retry has another delivery id")
+		}
+	}
+	if _, err := os.Stat(deadLetter); !os.IsNotExist(err) {
+		t.Errorf("a delivered notification was dead lettered: %v", err)
+	}
+}
+
+func TestDeliveryDeadLetter(t *testing.T) {
+	hook := newTestHook(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusBadGateway)
+	deadLetter := filepath.Join(t.TempDir(), "dead.jsonl")
+	d := testDispatcher(t, &Config{
+		Webhooks:   []Webhook{{Name: "audit", URL: hook.server.URL}},
+		Rules:      []Rule{{Webhooks: []string{"audit"}}},
+		Retries:    3,
+		Backoff:    time.Millisecond,
+		DeadLetter: deadLetter,
+	})
+
+	notification := testNotification()
+	d.deliver(delivery{webhook: d.Config.Webhooks[0], notification: notification})
+	if tries := len(hook.recorded()); tries != 3 {
+		t.Fatalf("got %d tries, want 3", tries)
+	}
+
+	file, err := os.Open(deadLetter)
+	if err != nil {
+		t.Fatalf("no dead letter file: %v", err)
+	}
+	defer file.Close()
+	var letters []types.DeadLetter
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var letter types.DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			t.Fatalf("invalid dead letter line %q: %v", scanner.Text(), err)
+		}
+		letters = append(letters, letter)
+	}
+	if len(letters) != 1 {
+		t.Fatalf("got %d dead letters, want 1", len(letters))
+	}
+	letter := letters[0]
+	if letter.Webhook != "audit" || letter.URL != hook.server.URL {
+		t.Errorf("dead letter of webhook %q %q", letter.Webhook, letter.URL)
+	}
+	if letter.Attempts != 3 {
+		t.Errorf("attempts: got %d, want 3", letter.Attempts)
+	}
+	if letter.LastError != "webhook returned status: 502" {
+		t.Errorf("last error: got %q", letter.LastError)
+	}
+	if letter.Notification.ID != notification.ID {
+		t.Errorf("notification: got %q, want %q", letter.Notification.ID, notification.ID)
+	}
+}
+
+func TestSlackPayload(t *testing.T) {
+	hook := newTestHook(t)
+	d := testDispatcher(t, &Config{
+		Webhooks: []Webhook{{Name: "security", URL: hook.server.URL, Format: FormatSlack}},
+		Rules:    []Rule{{Webhooks: []string{"security"}}},
+		Retries:  1,
+	})
+
+	notification := testNotification()
+	d.deliver(delivery{webhook: d.Config.Webhooks[0], notification: notification})
+	requests := hook.recorded()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+
+	var payload slackPayload
+	if err := json.Unmarshal(requests[0].body, &payload); err != nil {
+		t.Fatalf("invalid slack payload: %v", err)
+	}
+	if want := "*[high] unexpected_access: db-password*"; payload.Text != want {
+		t.Errorf("text: got %q, want %q", payload.Text, want)
+	}
+	if len(payload.Attachments) != 1 {
+		t.Fatalf("got %d attachments, want 1", len(payload.Attachments))
+	}
+	attachment := payload.Attachments[0]
+	if attachment.Color != slackColors[types.SeverityHigh] {
+		t.Errorf("color: got %q", attachment.Color)
+	}
+	if attachment.Text != notification.Message {
+		t.Errorf("attachment text: got %q", attachment.Text)
+	}
+	if attachment.Ts != notification.OccurredAt.Unix() {
+		t.Errorf("ts: got %d, want %d", attachment.Ts, notification.OccurredAt.Unix())
+	}
+	fields := make(map[string]string)
+	for _, field := range attachment.Fields {
+		fields[field.Title] = field.Value
+	}
+	want := map[string]string{
+		"Secret":    notification.SecretARN,
+		"Principal": notification.Principal,
+		"Region":    notification.Region,
+		"Profile":   notification.Profile,
+	}
+	for title, value := range want {
+		if fields[title] != value {
+			t.Errorf("field %s: got %q, want %q", title, fields[title], value)
+		}
+	}
+}
+
+func TestRouting(t *testing.T) {
+	security := newTestHook(t)
+	audit := newTestHook(t)
+	d := testDispatcher(t, &Config{
+		Webhooks: []Webhook{
+			{Name: "security", URL: security.server.URL},
+			{Name: "audit", URL: audit.server.URL},
+		},
+		Rules: []Rule{
+			{
+				Events:   []string{types.NotifyUnexpectedAccess},
+				Match:    Match{Tags: map[string]string{"env": "prod"}},
+				Webhooks: []string{"security", "audit"},
+			},
+			{MinSeverity: types.SeverityHigh, Webhooks: []string{"audit"}},
+			{Match: Match{Name: "staging-*"}, Webhooks: []string{"security"}},
+		},
+		Retries: 1,
+	})
+
+	prodAccess := testNotification()
+	prodAccess.ID = "prod-access"
+
+	devAccess := testNotification()
+	devAccess.ID = "dev-access"
+	devAccess.Severity = types.SeverityMedium
+	devAccess.Tags = map[string]string{"env": "dev"}
+
+	highDeleted := testNotification()
+	highDeleted.ID = "high-deleted"
+	highDeleted.Type = types.NotifySecretDeleted
+	highDeleted.Tags = nil
+
+	staging := testNotification()
+	staging.ID = "staging"
+	staging.Type = types.NotifySecretRotated
+	staging.Severity = types.SeverityLow
+	staging.SecretName = "staging-api-key"
+
+	d.Start()
+	d.Notify(prodAccess, devAccess, highDeleted, staging)
+	wait := func(hook *testHook, count int) {
+		t.Helper()
+		for i := 0; i < count; i++ {
+			select {
+			case <-hook.received:
+			case <-time.After(2 * time.Second):
+				t.Fatalf("got %d deliveries, want %d", i, count)
+			}
+		}
+	}
+	wait(security, 2)
+	wait(audit, 2)
+	d.Stop()
+
+	ids := func(hook *testHook) []string {
+		var list []string
+		for _, req := range hook.recorded() {
+			list = append(list, req.header.Get(DeliveryHeader))
+		}
+		sort.Strings(list)
+		return list
+	}
+	if got, want := ids(security), []string{"prod-access", "staging"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("security got %v, want %v", got, want)
+	}
+	// prod-access matches two rules of audit but is sent once
+	if got, want := ids(audit), []string{"high-deleted", "prod-access"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("audit got %v, want %v", got, want)
+	}
+}
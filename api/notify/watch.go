@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"fmt"
+	"golang-secret-manager/api/anomaly"
+	"golang-secret-manager/api/aws"
+	"golang-secret-manager/types"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A finding that was sent is not sent again for this long
+const resendAfter = 30 * 24 * time.Hour
+
+// Watcher turns the crawls into notifications. The changes of the secrets
+// since the previous crawl of the same account and region (see
+// inventory.Tracker) are notified, and the access logs are checked for
+// anomalies and the secrets for overdue rotations. The findings that were
+// sent are kept in memory
+type Watcher struct {
+	Anomalies anomaly.Options
+
+	mu   sync.Mutex
+	sent map[string]time.Time
+}
+
+func NewWatcher() *Watcher {
+	return &Watcher{
+		Anomalies: anomaly.DefaultOptions,
+		sent:      make(map[string]time.Time),
+	}
+}
+
+// The notifications of a crawl of the profile in the region, the diff is nil
+// for the first crawl
+func (w *Watcher) Crawled(profile string, region string, all *types.AllSecretWithAccessLog, snapshot types.InventorySnapshot, diff *types.InventoryDiff, now time.Time) []types.Notification {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var notifications []types.Notification
+	add := func(kind string, severity string, secret types.InventorySecret, principal string, format string, args ...interface{}) {
+		notifications = append(notifications, types.Notification{
+			ID:         newID(now),
+			Type:       kind,
+			Severity:   severity,
+			SecretARN:  secret.ARN,
+			SecretName: secret.Name,
+			Message:    fmt.Sprintf(format, args...),
+			OccurredAt: now.UTC(),
+			Principal:  principal,
+			Profile:    profile,
+			Region:     region,
+			Tags:       secret.Tags,
+		})
+	}
+
+	if diff != nil {
+		for _, secret := range diff.Added {
+			add(types.NotifySecretCreated, types.SeverityLow, secret, "", "secret %s was created", secret.Name)
+		}
+		for _, secret := range diff.Removed {
+			add(types.NotifySecretDeleted, types.SeverityMedium, secret, "", "secret %s was deleted", secret.Name)
+		}
+		current := make(map[string]types.InventorySecret, len(snapshot.Secrets))
+		for _, secret := range snapshot.Secrets {
+			current[secret.ARN] = secret
+		}
+		for _, change := range diff.Changed {
+			secret := current[change.ARN]
+			rotated := false
+			for _, field := range change.Changes {
+				switch field.Field {
+				case "deleted_date":
+					if field.From == "" {
+						add(types.NotifySecretDeleted, types.SeverityMedium, secret, "", "secret %s is scheduled for deletion since %s", secret.Name, field.To)
+					}
+				case "version", "last_rotated":
+					rotated = true
+				}
+			}
+			if rotated {
+				add(types.NotifySecretRotated, types.SeverityInfo, secret, "", "secret %s has a new version %s", secret.Name, secret.Version)
+			}
+		}
+	}
+
+	byARN := make(map[string]types.InventorySecret, len(snapshot.Secrets))
+	secrets := make(map[string]types.Secret, len(all.Secrets))
+	for _, secret := range snapshot.Secrets {
+		byARN[secret.ARN] = secret
+	}
+	for _, secret := range all.Secrets {
+		secrets[secret.ARN] = secret
+	}
+
+	report := anomaly.Detect(all.AccessLog, secrets, w.Anomalies, now)
+	for _, found := range report.Anomalies {
+		if found.Kind != types.AnomalyNewPrincipal && found.Kind != types.AnomalyHumanOnServiceOnly {
+			continue
+		}
+		if !w.firstTime("access|"+found.SecretARN+"|"+found.Principal, now) {
+			continue
+		}
+		add(types.NotifyUnexpectedAccess, found.Severity, byARN[found.SecretARN], found.Principal, "%s", found.Message)
+	}
+
+	hygiene := aws.CheckHygiene(all.Secrets, all.AccessLog, aws.HygieneOptions{}, now)
+	for _, finding := range hygiene.Findings {
+		if finding.Check != types.CheckRotationOverdue {
+			continue
+		}
+		if !w.firstTime("overdue|"+finding.SecretARN+"|"+finding.Message, now) {
+			continue
+		}
+		add(types.NotifyRotationOverdue, types.SeverityHigh, byARN[finding.SecretARN], "", "secret %s: %s", finding.SecretName, finding.Message)
+	}
+
+	w.prune(now)
+	return notifications
+}
+
+// Recording the finding, false when it was already sent
+func (w *Watcher) firstTime(key string, now time.Time) bool {
+	if _, found := w.sent[key]; found {
+		return false
+	}
+	w.sent[key] = now
+	return true
+}
+
+func (w *Watcher) prune(now time.Time) {
+	for key, sentAt := range w.sent {
+		if now.Sub(sentAt) > resendAfter {
+			delete(w.sent, key)
+		}
+	}
+}
+
+// A short summary of the notifications for the log
+func Summary(notifications []types.Notification) string {
+	counts := make(map[string]int)
+	var kinds []string
+	for _, notification := range notifications {
+		if counts[notification.Type] == 0 {
+			kinds = append(kinds, notification.Type)
+		}
+		counts[notification.Type]++
+	}
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		parts = append(parts, fmt.Sprintf("%s: %d", kind, counts[kind]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,223 @@
+package openapi
+
+import (
+	"golang-secret-manager/types"
+	"net/http"
+)
+
+const (
+	tagSecrets   = "secrets"
+	tagAudit     = "audit"
+	tagInventory = "inventory"
+	tagJobs      = "jobs"
+	tagCache     = "cache"
+)
+
+// The media types of the rendered reports
+var reportMedia = []string{"text/plain", "text/markdown", "text/html", "text/csv"}
+
+// A string parameter of the query
+func query(name string, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: "string"}}
+}
+
+// The passphrase of the encrypted cache snapshots
+var passphraseHeader = Parameter{
+	Name:        "X-Snapshot-Passphrase",
+	In:          "header",
+	Description: "Passphrase of an encrypted snapshot",
+	Schema:      &Schema{Type: "string"},
+}
+
+var formatQuery = query("format", "json (default) or text")
+
+// The routes of the HTTP server, TestRoutesMatchDocument of api/server fails
+// when a route is not registered or its admin token differs
+var routes = []Route{
+	{
+		Path: "/secrets", Method: http.MethodPost, Tag: tagSecrets,
+		Summary:  "All the secrets of the credential with their access logs",
+		Request:  types.GetAllSecretsRequest{},
+		Response: types.GetAllSecretsResponse{},
+	},
+	{
+		Path: "/reports", Method: http.MethodPost, Tag: tagSecrets,
+		Summary:       "Report of a single secret and its access log",
+		Description:   "Without a format the report is returned as GetReportResponse, ?format= (text, json, markdown, html, csv) or the Accept header are returning the rendered report. The json format is the SecretReport itself.",
+		Parameters:    []Parameter{query("format", "text, json, markdown, html or csv")},
+		Request:       types.GetReportRequest{},
+		Response:      types.GetReportResponse{},
+		ResponseMedia: reportMedia,
+	},
+	{
+		Path: "/access-logs", Method: http.MethodPost, Tag: tagSecrets,
+		Summary:  "Access log of a single secret, only the events of the filter",
+		Request:  types.GetAccessLogRequest{},
+		Response: types.GetAccessLogResponse{},
+	},
+	{
+		Path: "/hygiene", Method: http.MethodPost, Tag: tagAudit,
+		Summary:       "Hygiene findings of all the secrets of the credential",
+		Parameters:    []Parameter{query("format", "json (default), text or html")},
+		Request:       types.HygieneRequest{},
+		Response:      types.HygieneReport{},
+		ResponseMedia: []string{"text/plain", "text/html"},
+	},
+	{
+		Path: "/v1/policy/check", Method: http.MethodPost, Tag: tagAudit,
+		Summary:     "Checking the secrets of the credential against a policy",
+		Description: "When the request has no policy the policy file of the server is used.",
+		Request:     types.PolicyCheckRequest{},
+		Response:    types.PolicyReport{},
+	},
+	{
+		Path: "/v1/anomalies", Method: http.MethodGet, Tag: tagAudit, Admin: true,
+		Summary: "Anomalies of the cached access logs",
+		Parameters: []Parameter{
+			query("arn", "Secret to check, can be repeated"),
+			query("window", "Recent events that are checked (24h by default)"),
+			formatQuery,
+		},
+		Response:      types.AnomalyReport{},
+		ResponseMedia: []string{"text/plain"},
+	},
+	{
+		Path: "/v1/inventory/snapshots", Method: http.MethodGet, Tag: tagInventory, Admin: true,
+		Summary:  "The saved inventory snapshots",
+		Response: types.InventorySnapshotList{},
+	},
+	{
+		Path: "/v1/inventory/snapshots", Method: http.MethodPost, Tag: tagInventory, Admin: true,
+		Summary:     "Taking an inventory snapshot now",
+		Description: "The credential of the server is used when the request has none.",
+		Request:     types.InventorySnapshotRequest{},
+		Response:    types.InventorySnapshotInfo{},
+	},
+	{
+		Path: "/v1/inventory/diff", Method: http.MethodGet, Tag: tagInventory, Admin: true,
+		Summary: "Comparing two saved inventory snapshots",
+		Parameters: []Parameter{
+			query("from", "Snapshot id, latest or a date"),
+			query("to", "Snapshot id, latest or a date"),
+			formatQuery,
+		},
+		Response:      types.InventoryDiff{},
+		ResponseMedia: []string{"text/plain"},
+	},
+	{
+		Path: "/v1/inventory/diff", Method: http.MethodPost, Tag: tagInventory, Admin: true,
+		Summary:       "Comparing a snapshot with another one or with the live secrets",
+		Parameters:    []Parameter{formatQuery},
+		Request:       types.InventoryDiffRequest{},
+		Response:      types.InventoryDiff{},
+		ResponseMedia: []string{"text/plain"},
+	},
+	{
+		Path: "/v1/events", Method: http.MethodGet, Tag: tagInventory, Admin: true,
+		Summary:     "Server-sent events of the accesses and the changes of the secrets",
+		Description: "Every event is a StreamEvent as JSON, the Last-Event-ID header resumes the stream.",
+		Parameters: []Parameter{
+			query("arn", "Secret to watch, can be repeated"),
+			query("tag", "key or key=value, can be repeated"),
+			query("event", "Event type to watch, can be repeated"),
+			query("last_event_id", "Resuming after this event"),
+		},
+		ResponseMedia: []string{"text/event-stream"},
+	},
+	{
+		Path: "/v1/jobs", Method: http.MethodGet, Tag: tagJobs, Admin: true,
+		Summary:     "The crawl jobs from the newest with the schedule of each profile",
+		Description: "With ?id= the single CrawlJob is returned.",
+		Parameters: []Parameter{
+			query("id", "A single job"),
+			query("status", "queued, running, succeeded or failed"),
+			query("profile", "Jobs of the profile"),
+			query("limit", "Number of jobs"),
+		},
+		Response: types.JobList{},
+	},
+	{
+		Path: "/v1/jobs", Method: http.MethodPost, Tag: tagJobs, Admin: true,
+		Summary:  "Starting a crawl now",
+		Request:  types.CrawlRequest{},
+		Response: types.CrawlResponse{},
+	},
+	{
+		Path: "/metrics", Method: http.MethodGet, Tag: tagCache, Admin: true,
+		Summary:  "Counters of the cache",
+		Response: types.GetMetricsResponse{},
+	},
+	{
+		Path: "/cache/verify", Method: http.MethodGet, Tag: tagCache, Admin: true,
+		Summary:     "Checking that the persist cache can be decrypted",
+		Description: "Answers 409 with the same report when some entries failed.",
+		Response:    types.CacheVerifyReport{},
+	},
+	{
+		Path: "/admin/events", Method: http.MethodPost, Tag: tagCache, Admin: true,
+		Summary:     "Applying Secrets Manager CloudTrail events to the cache",
+		Description: "A CloudTrail log file, an EventBridge event of a CloudTrail API call or plain records.",
+		Request: struct {
+			Records []types.CloudTrailEvent `json:"Records"`
+		}{},
+		Response: types.EventIngestResponse{},
+	},
+	{
+		Path: "/admin/cache/keys", Method: http.MethodGet, Tag: tagCache, Admin: true,
+		Summary:    "The cache keys grouped by their namespace",
+		Parameters: []Parameter{query("namespace", "Keys of the namespace only")},
+		Response:   types.GetCacheKeysResponse{},
+	},
+	{
+		Path: "/admin/cache/entry", Method: http.MethodGet, Tag: tagCache, Admin: true,
+		Summary:    "A single entry of the cache",
+		Parameters: []Parameter{{Name: "key", In: "query", Required: true, Schema: &Schema{Type: "string"}}},
+		Response:   types.CacheEntryInfo{},
+	},
+	{
+		Path: "/admin/cache/invalidate", Method: http.MethodPost, Tag: tagCache, Admin: true,
+		Summary:  "Removing a secret or everything of a credential from the cache",
+		Request:  types.CacheInvalidateRequest{},
+		Response: types.CacheInvalidateResponse{},
+	},
+	{
+		Path: "/admin/cache/flush", Method: http.MethodPost, Tag: tagCache, Admin: true,
+		Summary:  "Saving the changed keys to the persist layer",
+		Response: types.CacheFlushResponse{},
+	},
+	{
+		Path: "/admin/cache/evictions", Method: http.MethodGet, Tag: tagCache, Admin: true,
+		Summary: "Previewing which keys would be evicted",
+		Parameters: []Parameter{
+			query("max_entries", "Previewing a smaller budget of entries"),
+			query("max_bytes", "Previewing a smaller budget of bytes"),
+		},
+		Response: types.CacheEvictionPreviewResponse{},
+	},
+	{
+		Path: "/admin/cache/migrations", Method: http.MethodGet, Tag: tagCache, Admin: true,
+		Summary:  "The entries that couldn't be migrated to the current schema versions",
+		Response: types.CacheMigrationReport{},
+	},
+	{
+		Path: "/admin/cache/export", Method: http.MethodGet, Tag: tagCache, Admin: true,
+		Summary:       "Exporting the whole cache as a snapshot archive",
+		Parameters:    []Parameter{passphraseHeader},
+		ResponseMedia: []string{"application/gzip"},
+	},
+	{
+		Path: "/admin/cache/import", Method: http.MethodPost, Tag: tagCache, Admin: true,
+		Summary: "Importing a snapshot archive",
+		Parameters: []Parameter{
+			query("mode", "merge (default) keeps the keys that are not in the snapshot, replace removes them"),
+			passphraseHeader,
+		},
+		RequestMedia: "application/gzip",
+		Response:     types.CacheImportReport{},
+	},
+	{
+		Path: "/openapi.json", Method: http.MethodGet,
+		Summary:       "This document",
+		ResponseMedia: []string{"application/json"},
+	},
+}
@@ -0,0 +1,222 @@
+// Package openapi builds the OpenAPI 3 document of the HTTP API, the schemas
+// of the request and response bodies are derived from the types package
+package openapi
+
+import (
+	"golang-secret-manager/types"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Version of the OpenAPI specification the document is following
+const openAPIVersion = "3.0.3"
+
+// Version of the HTTP API in the document
+const APIVersion = "1.0.0"
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type PathItem struct {
+	Get  *Operation `json:"get,omitempty"`
+	Post *Operation `json:"post,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// A response or a reference to one of the components
+type Response struct {
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	Responses       map[string]*Response       `json:"responses"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme"`
+	Description string `json:"description,omitempty"`
+}
+
+var (
+	specOnce sync.Once
+	spec     *Document
+)
+
+// Returning the document of the API, it is built once since the routes and
+// the types can't change while the server is running
+func Spec() *Document {
+	specOnce.Do(func() {
+		spec = Build(routes)
+	})
+	return spec
+}
+
+// Building the document of the routes
+func Build(routes []Route) *Document {
+	registry := newSchemaRegistry()
+	doc := &Document{
+		OpenAPI: openAPIVersion,
+		Info: Info{
+			Title:       "Golang Secret Manager",
+			Description: "Reports of the AWS Secrets Manager secrets of a credential with their CloudTrail access logs.",
+			Version:     APIVersion,
+		},
+		Paths: make(map[string]*PathItem),
+	}
+
+	// the handlers and the middlewares are all writing an ApiError
+	doc.Components.Responses = map[string]*Response{
+		"Error": {
+			Description: "The request failed",
+			Content:     map[string]*MediaType{"application/json": {Schema: registry.of(types.ApiError{})}},
+		},
+	}
+	doc.Components.SecuritySchemes = map[string]*SecurityScheme{
+		adminSecurity: {
+			Type:        "http",
+			Scheme:      "bearer",
+			Description: "The ADMIN_TOKEN of the server",
+		},
+	}
+
+	for _, route := range routes {
+		item, ok := doc.Paths[route.Path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[route.Path] = item
+		}
+		operation := route.operation(registry)
+		switch route.Method {
+		case http.MethodGet:
+			item.Get = operation
+		case http.MethodPost:
+			item.Post = operation
+		}
+	}
+	doc.Components.Schemas = registry.schemas
+	return doc
+}
+
+const adminSecurity = "adminToken"
+
+// A single operation of the API
+type Route struct {
+	Path        string
+	Method      string
+	Summary     string
+	Description string
+	Tag         string
+
+	// Behind the admin token
+	Admin      bool
+	Parameters []Parameter
+
+	// A value of the JSON body of the request and of the response, nil when
+	// there is none
+	Request  interface{}
+	Response interface{}
+
+	// The media types of the body when it is not JSON
+	RequestMedia  string
+	ResponseMedia []string
+}
+
+func (r Route) operation(registry *schemaRegistry) *Operation {
+	operation := &Operation{
+		OperationID: operationID(r.Method, r.Path),
+		Summary:     r.Summary,
+		Description: r.Description,
+		Parameters:  r.Parameters,
+		Responses:   make(map[string]*Response),
+	}
+	if r.Tag != "" {
+		operation.Tags = []string{r.Tag}
+	}
+	if r.Admin {
+		operation.Security = []map[string][]string{{adminSecurity: {}}}
+	}
+
+	if r.Request != nil {
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/json": {Schema: registry.of(r.Request)}},
+		}
+	} else if r.RequestMedia != "" {
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{r.RequestMedia: {Schema: &Schema{Type: "string", Format: "binary"}}},
+		}
+	}
+
+	ok := &Response{Description: "OK", Content: make(map[string]*MediaType)}
+	if r.Response != nil {
+		ok.Content["application/json"] = &MediaType{Schema: registry.of(r.Response)}
+	}
+	for _, media := range r.ResponseMedia {
+		schema := &Schema{Type: "string"}
+		if media == "application/json" {
+			schema = &Schema{Type: "object"}
+		} else if !strings.HasPrefix(media, "text/") {
+			schema.Format = "binary"
+		}
+		ok.Content[media] = &MediaType{Schema: schema}
+	}
+	operation.Responses["200"] = ok
+	operation.Responses["default"] = &Response{Ref: "#/components/responses/Error"}
+	return operation
+}
+
+// getSecrets, postV1InventorySnapshots...
+func operationID(method string, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == '-' || r == '.' || r == '_'
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
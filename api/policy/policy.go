@@ -0,0 +1,311 @@
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"golang-secret-manager/api/anomaly"
+	"golang-secret-manager/types"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy is a list of rules, each rule selects secrets with Match and checks
+// them with Require. For example:
+//
+//	rules:
+//	  - id: prod-rotation
+//	    description: prod secrets must rotate every 30 days
+//	    severity: high
+//	    match:
+//	      tags: {env: prod}
+//	    require:
+//	      rotation_enabled: true
+//	      max_rotation_days: 30
+//	  - id: db-readers
+//	    match:
+//	      name: prod/db*
+//	    require:
+//	      allowed_readers: ["arn:aws:iam::*:role/app"]
+type Policy struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+type Rule struct {
+	ID          string      `yaml:"id"`
+	Description string      `yaml:"description"`
+	Severity    string      `yaml:"severity"`
+	Match       Match       `yaml:"match"`
+	Require     Requirement `yaml:"require"`
+}
+
+// Selecting the secrets of a rule, the empty fields match every secret
+type Match struct {
+	// Glob patterns of the secret name
+	Name string   `yaml:"name"`
+	ARNs []string `yaml:"arns"`
+
+	// The secret must have every tag, "*" matches any value
+	Tags map[string]string `yaml:"tags"`
+}
+
+// The checks of a rule, only the fields that are set are checked
+type Requirement struct {
+	RotationEnabled    *bool    `yaml:"rotation_enabled"`
+	MaxRotationDays    int      `yaml:"max_rotation_days"`
+	MaxUnusedDays      int      `yaml:"max_unused_days"`
+	RequiredTags       []string `yaml:"required_tags"`
+	CustomerManagedKey bool     `yaml:"customer_managed_key"`
+
+	// Glob patterns of the principals (ARN or user name) that may read the
+	// secret with GetSecretValue
+	AllowedReaders []string `yaml:"allowed_readers"`
+	NoHumanReaders bool     `yaml:"no_human_readers"`
+}
+
+// Parsing a policy document, unknown fields are errors so a typo doesn't turn
+// into a rule that checks nothing. The rules without a severity are medium
+func Parse(data []byte) (*Policy, error) {
+	var policy Policy
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("failed to parse the policy: %v", err)
+	}
+	for i := range policy.Rules {
+		if policy.Rules[i].Severity == "" {
+			policy.Rules[i].Severity = types.SeverityMedium
+		}
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func Load(filePath string) (*Policy, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the policy: %v", err)
+	}
+	return Parse(data)
+}
+
+// Checking the rules, the policy is not changed
+func (p *Policy) Validate() error {
+	if len(p.Rules) == 0 {
+		return fmt.Errorf("policy has no rules")
+	}
+	ids := make(map[string]bool)
+	for i, rule := range p.Rules {
+		if rule.ID == "" {
+			return fmt.Errorf("rule %d has no id", i+1)
+		}
+		if ids[rule.ID] {
+			return fmt.Errorf("rule %s is defined twice", rule.ID)
+		}
+		ids[rule.ID] = true
+
+		switch rule.Severity {
+		case "", types.SeverityHigh, types.SeverityMedium, types.SeverityLow, types.SeverityInfo:
+		default:
+			return fmt.Errorf("rule %s: unknown severity: %s", rule.ID, rule.Severity)
+		}
+
+		if _, err := path.Match(rule.Match.Name, ""); err != nil {
+			return fmt.Errorf("rule %s: invalid name pattern: %v", rule.ID, err)
+		}
+		for _, reader := range rule.Require.AllowedReaders {
+			if _, err := path.Match(reader, ""); err != nil {
+				return fmt.Errorf("rule %s: invalid reader pattern: %v", rule.ID, err)
+			}
+		}
+		if rule.Require.isEmpty() {
+			return fmt.Errorf("rule %s requires nothing", rule.ID)
+		}
+	}
+	return nil
+}
+
+func (r Requirement) isEmpty() bool {
+	return r.RotationEnabled == nil && r.MaxRotationDays == 0 && r.MaxUnusedDays == 0 &&
+		len(r.RequiredTags) == 0 && !r.CustomerManagedKey && len(r.AllowedReaders) == 0 && !r.NoHumanReaders
+}
+
+// Checking every rule on every secret it matches
+func (p *Policy) Check(secrets []types.Secret, accessLogs map[string][]types.AccessLog, now time.Time) types.PolicyReport {
+	report := types.PolicyReport{
+		GeneratedAt: now.UTC(),
+		Rules:       len(p.Rules),
+		Secrets:     len(secrets),
+		Violations:  []types.PolicyResult{},
+		Results:     []types.PolicyResult{},
+	}
+	for _, rule := range p.Rules {
+		for _, secret := range secrets {
+			if !rule.Match.matches(secret) {
+				continue
+			}
+			evidence := rule.Require.check(secret, accessLogs[secret.ARN], now)
+			result := types.PolicyResult{
+				RuleID:      rule.ID,
+				Description: rule.Description,
+				Severity:    rule.Severity,
+				SecretARN:   secret.ARN,
+				SecretName:  secret.Name,
+				Passed:      len(evidence) == 0,
+				Evidence:    evidence,
+			}
+			if result.Evidence == nil {
+				result.Evidence = []string{}
+			}
+			report.Checked++
+			report.Results = append(report.Results, result)
+			if result.Passed {
+				report.Passed++
+			} else {
+				report.Failed++
+				report.Violations = append(report.Violations, result)
+			}
+		}
+	}
+	sort.SliceStable(report.Violations, func(i, j int) bool {
+		return severityRank(report.Violations[i].Severity) < severityRank(report.Violations[j].Severity)
+	})
+	return report
+}
+
+func (m Match) matches(secret types.Secret) bool {
+	if m.Name != "" {
+		if matched, _ := path.Match(m.Name, secret.Name); !matched {
+			return false
+		}
+	}
+	if len(m.ARNs) > 0 {
+		found := false
+		for _, arn := range m.ARNs {
+			if arn == secret.ARN {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for key, value := range m.Tags {
+		actual, found := secret.Tags[key]
+		if !found || (value != "*" && value != actual) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returning the evidence of every requirement that failed, nothing when the
+// secret passed
+func (r Requirement) check(secret types.Secret, accessLog []types.AccessLog, now time.Time) []string {
+	var evidence []string
+	day := 24 * time.Hour
+
+	if r.RotationEnabled != nil && secret.RotationEnabled != *r.RotationEnabled {
+		evidence = append(evidence, fmt.Sprintf("rotation enabled is %t, expected %t", secret.RotationEnabled, *r.RotationEnabled))
+	}
+
+	if r.MaxRotationDays > 0 {
+		switch {
+		case !secret.RotationEnabled:
+			evidence = append(evidence, fmt.Sprintf("rotation is disabled, expected every %d days", r.MaxRotationDays))
+		case secret.RotationDays > int64(r.MaxRotationDays):
+			evidence = append(evidence, fmt.Sprintf("rotation schedule is every %d days, expected at most %d", secret.RotationDays, r.MaxRotationDays))
+		}
+		maxAge := time.Duration(r.MaxRotationDays) * day
+		switch {
+		case !secret.RotationEnabled:
+		case !secret.LastRotated.IsZero() && now.Sub(secret.LastRotated) > maxAge:
+			evidence = append(evidence, fmt.Sprintf("last rotated %d days ago (%s)", int(now.Sub(secret.LastRotated)/day), secret.LastRotated.Format("2006-01-02")))
+		case secret.LastRotated.IsZero() && !secret.CreatedAt.IsZero() && now.Sub(secret.CreatedAt) > maxAge:
+			evidence = append(evidence, fmt.Sprintf("never rotated since it was created %d days ago", int(now.Sub(secret.CreatedAt)/day)))
+		}
+	}
+
+	if r.MaxUnusedDays > 0 {
+		last := secret.LastAccessed
+		for _, event := range accessLog {
+			if event.EventName == "GetSecretValue" && event.ErrorCode == "" && event.EventTime.After(last) {
+				last = event.EventTime
+			}
+		}
+		if last.IsZero() {
+			evidence = append(evidence, "never accessed")
+		} else if now.Sub(last) > time.Duration(r.MaxUnusedDays)*day {
+			evidence = append(evidence, fmt.Sprintf("last accessed %d days ago (%s)", int(now.Sub(last)/day), last.Format("2006-01-02")))
+		}
+	}
+
+	var missing []string
+	for _, tag := range r.RequiredTags {
+		if _, found := secret.Tags[tag]; !found {
+			missing = append(missing, tag)
+		}
+	}
+	if len(missing) > 0 {
+		evidence = append(evidence, "missing the tags: "+strings.Join(missing, ", "))
+	}
+
+	if r.CustomerManagedKey && (secret.KMSKeyID == "" || secret.KMSKeyID == "alias/aws/secretsmanager") {
+		evidence = append(evidence, "encrypted with the default KMS key")
+	}
+
+	if len(r.AllowedReaders) > 0 || r.NoHumanReaders {
+		reported := make(map[string]bool)
+		for _, event := range accessLog {
+			if event.EventName != "GetSecretValue" || event.ErrorCode != "" {
+				continue
+			}
+			principal := anomaly.Principal(event)
+			if reported[principal] {
+				continue
+			}
+			if len(r.AllowedReaders) > 0 && !allowedReader(r.AllowedReaders, event) {
+				reported[principal] = true
+				evidence = append(evidence, fmt.Sprintf("%s is not an allowed reader, read at %s (event %s)", principal, event.EventTime.UTC().Format(time.RFC3339), event.EventID))
+			} else if r.NoHumanReaders && anomaly.IsHuman(event) {
+				reported[principal] = true
+				evidence = append(evidence, fmt.Sprintf("%s is a person, read at %s (event %s)", principal, event.EventTime.UTC().Format(time.RFC3339), event.EventID))
+			}
+		}
+	}
+	return evidence
+}
+
+// The reader matches by the principal of the event or its user name
+func allowedReader(patterns []string, event types.AccessLog) bool {
+	for _, pattern := range patterns {
+		for _, name := range []string{anomaly.Principal(event), event.UserARN, event.User} {
+			if name == "" {
+				continue
+			}
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case types.SeverityHigh:
+		return 0
+	case types.SeverityMedium:
+		return 1
+	case types.SeverityLow:
+		return 2
+	}
+	return 3
+}
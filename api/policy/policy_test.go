@@ -0,0 +1,322 @@
+package policy
+
+import (
+	"golang-secret-manager/types"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testARN = "arn:aws:secretsmanager:eu-west-1:123456789012:secret:prod/db-password-AbCdEf"
+
+var testNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func daysAgo(days int) time.Time {
+	return testNow.Add(-time.Duration(days) * 24 * time.Hour)
+}
+
+func TestMatch(t *testing.T) {
+	secret := types.Secret{
+		Name: "prod/db-password",
+		ARN:  testARN,
+		Tags: map[string]string{"env": "prod", "team": "payments"},
+	}
+	tests := []struct {
+		name  string
+		match Match
+		want  bool
+	}{
+		{name: "empty match", match: Match{}, want: true},
+		{name: "name glob", match: Match{Name: "prod/db*"}, want: true},
+		{name: "exact name", match: Match{Name: "prod/db-password"}, want: true},
+		{name: "other name", match: Match{Name: "staging/*"}, want: false},
+		{name: "glob doesn't cross the slash", match: Match{Name: "*"}, want: false},
+		{name: "listed ARN", match: Match{ARNs: []string{"arn:other", testARN}}, want: true},
+		{name: "unlisted ARN", match: Match{ARNs: []string{"arn:other"}}, want: false},
+		{name: "tag value", match: Match{Tags: map[string]string{"env": "prod"}}, want: true},
+		{name: "other tag value", match: Match{Tags: map[string]string{"env": "dev"}}, want: false},
+		{name: "any tag value", match: Match{Tags: map[string]string{"team": "*"}}, want: true},
+		{name: "missing tag with any value", match: Match{Tags: map[string]string{"owner": "*"}}, want: false},
+		{name: "every tag is required", match: Match{Tags: map[string]string{"env": "prod", "owner": "*"}}, want: false},
+		{name: "all fields", match: Match{Name: "prod/*", ARNs: []string{testARN}, Tags: map[string]string{"env": "prod"}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.match.matches(secret); got != tt.want {
+				t.Errorf("matches: got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequirementCheck(t *testing.T) {
+	enabled := true
+	read := func(id string, user string, identityType string, when time.Time) types.AccessLog {
+		return types.AccessLog{
+			EventID:          id,
+			EventName:        "GetSecretValue",
+			EventTime:        when,
+			UserARN:          user,
+			UserIdentityType: identityType,
+		}
+	}
+	appRole := "arn:aws:iam::123456789012:role/app"
+	alice := "arn:aws:iam::123456789012:user/alice"
+
+	tests := []struct {
+		name      string
+		require   Requirement
+		secret    types.Secret
+		accessLog []types.AccessLog
+		want      []string
+	}{
+		{
+			name:    "rotation enabled",
+			require: Requirement{RotationEnabled: &enabled},
+			secret:  types.Secret{RotationEnabled: false},
+			want:    []string{"rotation enabled is false, expected true"},
+		},
+		{
+			name:    "rotation disabled with a max age",
+			require: Requirement{MaxRotationDays: 30},
+			secret:  types.Secret{CreatedAt: daysAgo(100)},
+			want:    []string{"rotation is disabled, expected every 30 days"},
+		},
+		{
+			name:    "rotation schedule too long",
+			require: Requirement{MaxRotationDays: 30},
+			secret:  types.Secret{RotationEnabled: true, RotationDays: 90, LastRotated: daysAgo(1)},
+			want:    []string{"rotation schedule is every 90 days, expected at most 30"},
+		},
+		{
+			name:    "last rotated too long ago",
+			require: Requirement{MaxRotationDays: 30},
+			secret:  types.Secret{RotationEnabled: true, RotationDays: 30, LastRotated: daysAgo(45), CreatedAt: daysAgo(400)},
+			want:    []string{"last rotated 45 days ago (2024-04-17)"},
+		},
+		{
+			name:    "rotated recently",
+			require: Requirement{MaxRotationDays: 30},
+			secret:  types.Secret{RotationEnabled: true, RotationDays: 30, LastRotated: daysAgo(10), CreatedAt: daysAgo(400)},
+		},
+		{
+			name:    "never rotated since the creation",
+			require: Requirement{MaxRotationDays: 30},
+			secret:  types.Secret{RotationEnabled: true, RotationDays: 30, CreatedAt: daysAgo(60)},
+			want:    []string{"never rotated since it was created 60 days ago"},
+		},
+		{
+			name:    "never rotated but created recently",
+			require: Requirement{MaxRotationDays: 30},
+			secret:  types.Secret{RotationEnabled: true, RotationDays: 30, CreatedAt: daysAgo(5)},
+		},
+		{
+			name:    "unused without access log",
+			require: Requirement{MaxUnusedDays: 30},
+			secret:  types.Secret{LastAccessed: daysAgo(40)},
+			want:    []string{"last accessed 40 days ago (2024-04-22)"},
+		},
+		{
+			name:    "used recently without access log",
+			require: Requirement{MaxUnusedDays: 30},
+			secret:  types.Secret{LastAccessed: daysAgo(3)},
+		},
+		{
+			name:    "never accessed",
+			require: Requirement{MaxUnusedDays: 30},
+			want:    []string{"never accessed"},
+		},
+		{
+			name:      "used recently by the access log",
+			require:   Requirement{MaxUnusedDays: 30},
+			secret:    types.Secret{LastAccessed: daysAgo(40)},
+			accessLog: []types.AccessLog{read("e1", appRole, "AssumedRole", daysAgo(2))},
+		},
+		{
+			name:    "failed and other events are not uses",
+			require: Requirement{MaxUnusedDays: 30},
+			secret:  types.Secret{LastAccessed: daysAgo(40)},
+			accessLog: []types.AccessLog{
+				{EventName: "GetSecretValue", ErrorCode: "AccessDenied", EventTime: daysAgo(1)},
+				{EventName: "DescribeSecret", EventTime: daysAgo(1)},
+			},
+			want: []string{"last accessed 40 days ago (2024-04-22)"},
+		},
+		{
+			name:    "missing tags",
+			require: Requirement{RequiredTags: []string{"owner", "env", "team"}},
+			secret:  types.Secret{Tags: map[string]string{"env": "prod"}},
+			want:    []string{"missing the tags: owner, team"},
+		},
+		{
+			name:    "default KMS key",
+			require: Requirement{CustomerManagedKey: true},
+			secret:  types.Secret{KMSKeyID: "alias/aws/secretsmanager"},
+			want:    []string{"encrypted with the default KMS key"},
+		},
+		{
+			name:    "customer managed KMS key",
+			require: Requirement{CustomerManagedKey: true},
+			secret:  types.Secret{KMSKeyID: "arn:aws:kms:eu-west-1:123456789012:key/1234"},
+		},
+		{
+			name:    "reader not allowed is reported once",
+			require: Requirement{AllowedReaders: []string{"arn:aws:iam::*:role/app"}},
+			accessLog: []types.AccessLog{
+				read("e1", appRole, "AssumedRole", daysAgo(1)),
+				read("e2", alice, "IAMUser", daysAgo(2)),
+				read("e3", alice, "IAMUser", daysAgo(3)),
+			},
+			want: []string{alice + " is not an allowed reader, read at 2024-05-30T12:00:00Z (event e2)"},
+		},
+		{
+			name:    "allowed by the user name",
+			require: Requirement{AllowedReaders: []string{"alice"}},
+			accessLog: []types.AccessLog{
+				{EventID: "e1", EventName: "GetSecretValue", EventTime: daysAgo(1), User: "alice", UserARN: alice},
+			},
+		},
+		{
+			name:    "human readers are reported once",
+			require: Requirement{NoHumanReaders: true},
+			accessLog: []types.AccessLog{
+				read("e1", appRole, "AssumedRole", daysAgo(1)),
+				read("e2", alice, "IAMUser", daysAgo(2)),
+				read("e3", alice, "IAMUser", daysAgo(3)),
+			},
+			want: []string{alice + " is a person, read at 2024-05-30T12:00:00Z (event e2)"},
+		},
+		{
+			name:    "a reader that is not allowed is not reported as a person too",
+			require: Requirement{AllowedReaders: []string{"arn:aws:iam::*:role/app"}, NoHumanReaders: true},
+			accessLog: []types.AccessLog{
+				read("e1", alice, "IAMUser", daysAgo(1)),
+				read("e2", alice, "IAMUser", daysAgo(2)),
+			},
+			want: []string{alice + " is not an allowed reader, read at 2024-05-31T12:00:00Z (event e1)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.require.check(tt.secret, tt.accessLog, testNow)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("check:\ngot  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		err      string
+	}{
+		{
+			name: "valid",
+			document: `
+rules:
+  - id: prod-rotation
+    match: {tags: {env: prod}}
+    require: {max_rotation_days: 30}
+`,
+		},
+		{
+			name: "unknown field",
+			document: `
+rules:
+  - id: prod-rotation
+    require: {max_rotaton_days: 30}
+`,
+			err: "field max_rotaton_days not found",
+		},
+		{
+			name:     "no rules",
+			document: `rules: []`,
+			err:      "policy has no rules",
+		},
+		{
+			name: "rule without requirements",
+			document: `
+rules:
+  - id: empty
+    match: {name: "prod/*"}
+`,
+			err: "rule empty requires nothing",
+		},
+		{
+			name: "unknown severity",
+			document: `
+rules:
+  - id: prod-rotation
+    severity: urgent
+    require: {max_rotation_days: 30}
+`,
+			err: "unknown severity: urgent",
+		},
+		{
+			name: "duplicate id",
+			document: `
+rules:
+  - id: same
+    require: {max_unused_days: 30}
+  - id: same
+    require: {max_unused_days: 60}
+`,
+			err: "rule same is defined twice",
+		},
+		{
+			name: "invalid pattern",
+			document: `
+rules:
+  - id: bad
+    match: {name: "prod/["}
+    require: {max_unused_days: 30}
+`,
+			err: "invalid name pattern",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.document))
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("Parse: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Parse: got %v, want an error with %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseDefaultsTheSeverity(t *testing.T) {
+	policy, err := Parse([]byte(`
+rules:
+  - id: default
+    require: {max_unused_days: 30}
+  - id: high
+    severity: high
+    require: {max_unused_days: 30}
+`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got := policy.Rules[0].Severity; got != types.SeverityMedium {
+		t.Errorf("default severity: got %q, want %q", got, types.SeverityMedium)
+	}
+	if got := policy.Rules[1].Severity; got != types.SeverityHigh {
+		t.Errorf("severity: got %q, want %q", got, types.SeverityHigh)
+	}
+
+	// validating doesn't change the policy
+	built := &Policy{Rules: []Rule{{ID: "built", Require: Requirement{MaxUnusedDays: 30}}}}
+	if err := built.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if built.Rules[0].Severity != "" {
+		t.Errorf("Validate set the severity to %q", built.Rules[0].Severity)
+	}
+}
@@ -0,0 +1,31 @@
+package report
+
+import (
+	"fmt"
+	"golang-secret-manager/types"
+	"strings"
+)
+
+// The anomaly report that the CLI is printing
+func AnomalyText(report types.AnomalyReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, " # Access Anomalies (last %s)\n", report.Window)
+	fmt.Fprintf(&b, " - Generated At: %s\n", formatTime(report.GeneratedAt))
+	fmt.Fprintf(&b, " - Secrets: %d, with a baseline: %d, anomalies: %d\n", report.Secrets, len(report.Baselines), len(report.Anomalies))
+	if len(report.InsufficientBaseline) > 0 {
+		fmt.Fprintf(&b, " - Not enough events for a baseline: %s\n", strings.Join(report.InsufficientBaseline, ", "))
+	}
+	if len(report.Anomalies) == 0 {
+		fmt.Fprintf(&b, "\nNo anomalies.\n")
+		return b.String()
+	}
+	b.WriteString("\n")
+	for _, anomaly := range report.Anomalies {
+		secret := anomaly.SecretName
+		if secret == "" {
+			secret = anomaly.SecretARN
+		}
+		fmt.Fprintf(&b, "[%-6s] %-24s %s  %s\n         %s\n", strings.ToUpper(anomaly.Severity), anomaly.Kind, formatTime(anomaly.EventTime), secret, anomaly.Message)
+	}
+	return b.String()
+}
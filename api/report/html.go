@@ -0,0 +1,92 @@
+package report
+
+import (
+	"golang-secret-manager/types"
+	"html/template"
+	"io"
+)
+
+type htmlRenderer struct{}
+
+func (htmlRenderer) Name() string {
+	return "html"
+}
+
+func (htmlRenderer) ContentType() string {
+	return "text/html; charset=utf-8"
+}
+
+func (htmlRenderer) Render(w io.Writer, report types.SecretReport) error {
+	return htmlTemplate.Execute(w, report)
+}
+
+// The page has no external assets so it can be saved and opened offline, the
+// access table is sorted by clicking on its headers
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"time": formatTime,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Secret Report: {{.Secret.Name}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.5em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; font-size: 0.9em; }
+th { background: #f3f3f3; }
+#access th { cursor: pointer; user-select: none; }
+#access th[data-dir="asc"]::after { content: " \25B2"; }
+#access th[data-dir="desc"]::after { content: " \25BC"; }
+tr.error td { background: #fdecea; }
+code { font-size: 0.95em; }
+</style>
+</head>
+<body>
+<h1>Secret Report: {{.Secret.Name}}</h1>
+<table>
+<tr><th>ARN</th><td><code>{{.Secret.ARN}}</code></td></tr>
+<tr><th>Version</th><td><code>{{.Secret.Version}}</code></td></tr>
+<tr><th>Created At</th><td>{{time .Secret.CreatedAt}}</td></tr>
+<tr><th>Last Accessed</th><td>{{time .Secret.LastAccessed}}</td></tr>
+<tr><th>Generated At</th><td>{{time .GeneratedAt}}</td></tr>
+</table>
+<h2>Summary</h2>
+<table>
+<tr><th>Events</th><td>{{.Summary.Events}}</td></tr>
+<tr><th>Reads</th><td>{{.Summary.Reads}}</td></tr>
+<tr><th>Errors</th><td>{{.Summary.Errors}}</td></tr>
+<tr><th>Users</th><td>{{range $i, $user := .Summary.Users}}{{if $i}}, {{end}}{{$user}}{{end}}</td></tr>
+</table>
+<h2>Access Log</h2>
+{{if .AccessLog}}
+<table id="access">
+<thead>
+<tr><th>Time</th><th>Event</th><th>User</th><th>Identity</th><th>Session</th><th>Source IP</th><th>User Agent</th><th>Version Stage</th><th>Error</th><th>Event ID</th></tr>
+</thead>
+<tbody>
+{{range .AccessLog}}<tr{{if .ErrorCode}} class="error"{{end}}><td>{{time .EventTime}}</td><td>{{.EventName}}</td><td>{{.User}}</td><td title="{{.UserARN}}">{{.UserIdentityType}}</td><td title="{{.SessionIssuer}}">{{.SessionName}}</td><td>{{.SourceIP}}</td><td>{{.UserAgent}}</td><td>{{.VersionStage}}</td><td>{{.ErrorCode}}</td><td><code>{{.EventID}}</code></td></tr>
+{{end}}</tbody>
+</table>
+<script>
+document.querySelectorAll("#access th").forEach(function (th, column) {
+  th.addEventListener("click", function () {
+    var dir = th.dataset.dir === "asc" ? "desc" : "asc";
+    document.querySelectorAll("#access th").forEach(function (other) { delete other.dataset.dir; });
+    th.dataset.dir = dir;
+    var body = document.querySelector("#access tbody");
+    var rows = Array.prototype.slice.call(body.rows);
+    rows.sort(function (a, b) {
+      var x = a.cells[column].textContent, y = b.cells[column].textContent;
+      return dir === "asc" ? x.localeCompare(y) : y.localeCompare(x);
+    });
+    rows.forEach(function (row) { body.appendChild(row); });
+  });
+});
+</script>
+{{else}}
+<p>No events.</p>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,129 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"golang-secret-manager/types"
+	GenericEncoding "golang-secret-manager/utils/genericEncoding"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// The formats of the hygiene report
+var hygieneFormats = map[string]string{
+	"text": "text/plain; charset=utf-8",
+	"json": "application/json",
+	"html": "text/html; charset=utf-8",
+}
+
+// Returning the format of the hygiene report that the request asked for, the
+// default is json
+func NegotiateHygiene(r *http.Request) (string, error) {
+	if format := strings.ToLower(r.URL.Query().Get("format")); format != "" {
+		if _, found := hygieneFormats[format]; !found {
+			return "", fmt.Errorf("unknown hygiene report format: %s", format)
+		}
+		return format, nil
+	}
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "text/html"):
+		return "html", nil
+	case strings.Contains(accept, "text/plain"):
+		return "text", nil
+	}
+	return "json", nil
+}
+
+func RenderHygiene(w io.Writer, format string, report types.HygieneReport) error {
+	switch format {
+	case "text":
+		_, err := io.WriteString(w, HygieneText(report))
+		return err
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case "html":
+		return hygieneTemplate.Execute(w, report)
+	}
+	return fmt.Errorf("unknown hygiene report format: %s", format)
+}
+
+// Sending the hygiene report in the format the request asked for
+func RespondHygiene(rw http.ResponseWriter, r *http.Request, report types.HygieneReport) error {
+	format, err := NegotiateHygiene(r)
+	if err != nil {
+		return GenericEncoding.WriteJson(rw, http.StatusBadRequest, types.ApiError{Err: err.Error(), Status: http.StatusBadRequest})
+	}
+	rw.Header().Set("Content-Type", hygieneFormats[format])
+	rw.WriteHeader(http.StatusOK)
+	return RenderHygiene(rw, format, report)
+}
+
+// The hygiene report that the CLI is printing
+func HygieneText(report types.HygieneReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, " # Secret Hygiene Report (%s)\n", report.Region)
+	fmt.Fprintf(&b, " - Generated At: %s\n", formatTime(report.GeneratedAt))
+	fmt.Fprintf(&b, " - Secrets: %d, findings: %d (high: %d, medium: %d, low: %d)\n",
+		report.Secrets, len(report.Findings),
+		report.Severities[types.SeverityHigh], report.Severities[types.SeverityMedium], report.Severities[types.SeverityLow])
+	fmt.Fprintf(&b, " - Unused after: %d days\n", report.UnusedDays)
+	if len(report.RequiredTags) > 0 {
+		fmt.Fprintf(&b, " - Required tags: %s\n", strings.Join(report.RequiredTags, ", "))
+	}
+	if len(report.Findings) == 0 {
+		fmt.Fprintf(&b, "\nNo findings.\n")
+		return b.String()
+	}
+	b.WriteString("\n")
+	for _, finding := range report.Findings {
+		fmt.Fprintf(&b, "[%-6s] %-20s %s\n         %s\n", strings.ToUpper(finding.Severity), finding.Check, finding.SecretName, finding.Message)
+	}
+	return b.String()
+}
+
+var hygieneTemplate = template.Must(template.New("hygiene").Funcs(template.FuncMap{
+	"time": formatTime,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Secret Hygiene Report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.5em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; font-size: 0.9em; }
+th { background: #f3f3f3; }
+.high { background: #fdecea; }
+.medium { background: #fff4e5; }
+.low { background: #eef6fc; }
+</style>
+</head>
+<body>
+<h1>Secret Hygiene Report</h1>
+<table>
+<tr><th>Region</th><td>{{.Region}}</td></tr>
+<tr><th>Generated At</th><td>{{time .GeneratedAt}}</td></tr>
+<tr><th>Secrets</th><td>{{.Secrets}}</td></tr>
+<tr><th>Unused After</th><td>{{.UnusedDays}} days</td></tr>
+<tr><th>Required Tags</th><td>{{range $i, $tag := .RequiredTags}}{{if $i}}, {{end}}{{$tag}}{{end}}</td></tr>
+<tr><th>Findings</th><td>{{len .Findings}} (high: {{index .Severities "high"}}, medium: {{index .Severities "medium"}}, low: {{index .Severities "low"}})</td></tr>
+</table>
+{{if .Findings}}
+<table>
+<thead><tr><th>Severity</th><th>Check</th><th>Secret</th><th>Finding</th></tr></thead>
+<tbody>
+{{range .Findings}}<tr class="{{.Severity}}"><td>{{.Severity}}</td><td>{{.Check}}</td><td title="{{.SecretARN}}">{{.SecretName}}</td><td>{{.Message}}</td></tr>
+{{end}}</tbody>
+</table>
+{{else}}
+<p>No findings.</p>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,45 @@
+package report
+
+import (
+	"fmt"
+	"golang-secret-manager/types"
+	"strings"
+)
+
+// The inventory diff that the CLI is printing
+func InventoryDiffText(diff types.InventoryDiff) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, " # Inventory Diff\n")
+	fmt.Fprintf(&b, " - From: %s (%s, %d secrets)\n", diff.From.ID, formatTime(diff.From.TakenAt), diff.From.Secrets)
+	fmt.Fprintf(&b, " - To:   %s (%s, %d secrets)\n", diff.To.ID, formatTime(diff.To.TakenAt), diff.To.Secrets)
+	fmt.Fprintf(&b, " - Added: %d, removed: %d, changed: %d, unchanged: %d\n", len(diff.Added), len(diff.Removed), len(diff.Changed), diff.Unchanged)
+	if diff.Warning != "" {
+		fmt.Fprintf(&b, " - Warning: %s\n", diff.Warning)
+	}
+	if len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0 {
+		fmt.Fprintf(&b, "\nNo changes.\n")
+		return b.String()
+	}
+
+	b.WriteString("\n")
+	for _, secret := range diff.Added {
+		fmt.Fprintf(&b, "+ %s\n    %s\n", secret.Name, secret.ARN)
+	}
+	for _, secret := range diff.Removed {
+		fmt.Fprintf(&b, "- %s\n    %s\n", secret.Name, secret.ARN)
+	}
+	for _, change := range diff.Changed {
+		fmt.Fprintf(&b, "~ %s\n", change.Name)
+		for _, field := range change.Changes {
+			fmt.Fprintf(&b, "    %-20s %s -> %s\n", field.Field, valueOrNone(field.From), valueOrNone(field.To))
+		}
+	}
+	return b.String()
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
@@ -0,0 +1,183 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"golang-secret-manager/types"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type textRenderer struct{}
+
+func (textRenderer) Name() string {
+	return "text"
+}
+
+func (textRenderer) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+func (textRenderer) Render(w io.Writer, report types.SecretReport) error {
+	_, err := io.WriteString(w, Text(report.Secret, report.AccessLog))
+	return err
+}
+
+// The text report that the CLI is printing
+func Text(secret types.Secret, accessLog []types.AccessLog) string {
+	// MetaData
+	report := " # Secret Metadata: \n"
+	report += fmt.Sprintf(" - Secret Name: 		%s\n", secret.Name)
+	report += fmt.Sprintf(" - Secret Created At:	   	%s\n", secret.CreatedAt)
+	report += fmt.Sprintf(" - Secret Last Accessed: 	%s\n", secret.LastAccessed)
+	report += fmt.Sprintf(" - Secret ARN: 			%s\n", secret.ARN)
+
+	// AccessLog
+	report += " - Secret Access Log: \n"
+	for _, accessLog := range accessLog {
+		report += fmt.Sprintf("{\n	User: %s\n", accessLog.User)
+		report += fmt.Sprintf("	Event ID: %s\n", accessLog.EventID)
+		report += fmt.Sprintf("	Event Time: %s\n", accessLog.EventTime)
+		report += fmt.Sprintf("	Event Name: %s\n", accessLog.EventName)
+		report += fmt.Sprintf("	Event Source: %s\n", accessLog.EventSource)
+		report += fmt.Sprintf("	Identity: %s %s\n", accessLog.UserIdentityType, accessLog.UserARN)
+		if accessLog.SessionIssuer != "" {
+			report += fmt.Sprintf("	Assumed Role: %s (session %s)\n", accessLog.SessionIssuer, accessLog.SessionName)
+		}
+		report += fmt.Sprintf("	Source IP: %s\n", accessLog.SourceIP)
+		report += fmt.Sprintf("	User Agent: %s\n", accessLog.UserAgent)
+		if accessLog.VersionStage != "" {
+			report += fmt.Sprintf("	Version Stage: %s\n", accessLog.VersionStage)
+		}
+		if accessLog.ErrorCode != "" {
+			report += fmt.Sprintf("	Error Code: %s\n", accessLog.ErrorCode)
+		}
+		report += fmt.Sprintf("	Read Only: %t\n}\n", accessLog.ReadOnly)
+	}
+	return report
+}
+
+type jsonRenderer struct{}
+
+func (jsonRenderer) Name() string {
+	return "json"
+}
+
+func (jsonRenderer) ContentType() string {
+	return "application/json"
+}
+
+func (jsonRenderer) Render(w io.Writer, report types.SecretReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+type markdownRenderer struct{}
+
+func (markdownRenderer) Name() string {
+	return "markdown"
+}
+
+func (markdownRenderer) ContentType() string {
+	return "text/markdown; charset=utf-8"
+}
+
+func (markdownRenderer) Render(w io.Writer, report types.SecretReport) error {
+	var b strings.Builder
+	secret := report.Secret
+	fmt.Fprintf(&b, "# Secret Report: %s\n\n", markdownEscape(secret.Name))
+	fmt.Fprintf(&b, "| | |\n|---|---|\n")
+	fmt.Fprintf(&b, "| ARN | `%s` |\n", secret.ARN)
+	fmt.Fprintf(&b, "| Version | `%s` |\n", secret.Version)
+	fmt.Fprintf(&b, "| Created At | %s |\n", formatTime(secret.CreatedAt))
+	fmt.Fprintf(&b, "| Last Accessed | %s |\n", formatTime(secret.LastAccessed))
+	fmt.Fprintf(&b, "| Generated At | %s |\n\n", formatTime(report.GeneratedAt))
+
+	summary := report.Summary
+	fmt.Fprintf(&b, "## Summary\n\n")
+	fmt.Fprintf(&b, "- Events: %d\n- Reads: %d\n- Errors: %d\n", summary.Events, summary.Reads, summary.Errors)
+	fmt.Fprintf(&b, "- Users: %s\n\n", markdownEscape(strings.Join(summary.Users, ", ")))
+
+	fmt.Fprintf(&b, "## Access Log\n\n")
+	if len(report.AccessLog) == 0 {
+		fmt.Fprintf(&b, "No events.\n")
+	} else {
+		fmt.Fprintf(&b, "| Time | Event | User | Identity | Source IP | Version Stage | Error |\n")
+		fmt.Fprintf(&b, "|---|---|---|---|---|---|---|\n")
+		for _, event := range report.AccessLog {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s |\n",
+				formatTime(event.EventTime),
+				markdownEscape(event.EventName),
+				markdownEscape(event.User),
+				markdownEscape(event.UserIdentityType),
+				markdownEscape(event.SourceIP),
+				markdownEscape(event.VersionStage),
+				markdownEscape(event.ErrorCode))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownEscape(value string) string {
+	value = strings.ReplaceAll(value, "\\", "\\\\")
+	value = strings.ReplaceAll(value, "|", "\\|")
+	return strings.ReplaceAll(value, "\n", " ")
+}
+
+type csvRenderer struct{}
+
+func (csvRenderer) Name() string {
+	return "csv"
+}
+
+func (csvRenderer) ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (csvRenderer) Render(w io.Writer, report types.SecretReport) error {
+	writer := csv.NewWriter(w)
+	secret := report.Secret
+	writer.Write([]string{"Name", "ARN", "Version", "Created At", "Last Accessed"})
+	writer.Write([]string{secret.Name, secret.ARN, secret.Version, formatTime(secret.CreatedAt), formatTime(secret.LastAccessed)})
+	writer.Write(nil)
+	writer.Write(AccessLogCSVHeader)
+	for _, event := range report.AccessLog {
+		writer.Write(AccessLogCSVRecord(event))
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// The columns of the access log in the CSV files
+var AccessLogCSVHeader = []string{"User", "Event Time", "Event Name", "Event ID", "Source IP", "User Agent",
+	"Identity Type", "Identity ARN", "Session Issuer", "Session Name", "Version Stage", "Error Code", "Read Only"}
+
+func AccessLogCSVRecord(event types.AccessLog) []string {
+	return []string{
+		event.User,
+		formatTime(event.EventTime),
+		event.EventName,
+		event.EventID,
+		event.SourceIP,
+		event.UserAgent,
+		event.UserIdentityType,
+		event.UserARN,
+		event.SessionIssuer,
+		event.SessionName,
+		event.VersionStage,
+		event.ErrorCode,
+		strconv.FormatBool(event.ReadOnly),
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
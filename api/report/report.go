@@ -0,0 +1,171 @@
+package report
+
+import (
+	"fmt"
+	"golang-secret-manager/types"
+	GenericEncoding "golang-secret-manager/utils/genericEncoding"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Renderer writes a report in a single format
+type Renderer interface {
+	Name() string
+	ContentType() string
+	Render(w io.Writer, report types.SecretReport) error
+}
+
+var (
+	TextRenderer     Renderer = textRenderer{}
+	JSONRenderer     Renderer = jsonRenderer{}
+	MarkdownRenderer Renderer = markdownRenderer{}
+	HTMLRenderer     Renderer = htmlRenderer{}
+	CSVRenderer      Renderer = csvRenderer{}
+)
+
+var renderers = []Renderer{TextRenderer, JSONRenderer, MarkdownRenderer, HTMLRenderer, CSVRenderer}
+
+// Returning the renderer by its name (text, json, markdown, html or csv)
+func RendererByName(name string) (Renderer, error) {
+	switch strings.ToLower(name) {
+	case "md":
+		return MarkdownRenderer, nil
+	case "htm":
+		return HTMLRenderer, nil
+	}
+	for _, renderer := range renderers {
+		if strings.EqualFold(renderer.Name(), name) {
+			return renderer, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown report format: %s", name)
+}
+
+// Returning the renderer that the request asked for, the format query
+// parameter is used before the Accept header. nil is returned when the client
+// didn't ask for a format and should get the JSON response of the older
+// clients
+func Negotiate(r *http.Request) (Renderer, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return RendererByName(format)
+	}
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		for _, renderer := range renderers {
+			if renderer == JSONRenderer {
+				// application/json is the response of the older clients
+				continue
+			}
+			if mediaType == baseMediaType(renderer.ContentType()) {
+				return renderer, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// Building the report of the secret and its access log
+func Build(secret types.Secret, accessLog []types.AccessLog, filter *types.AccessLogFilter) types.SecretReport {
+	if accessLog == nil {
+		accessLog = []types.AccessLog{}
+	}
+	report := types.SecretReport{
+		Secret:      secret,
+		AccessLog:   accessLog,
+		Filter:      filter,
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	users := make(map[string]bool)
+	summary := &report.Summary
+	summary.Users = []string{}
+	for i := range accessLog {
+		event := accessLog[i]
+		summary.Events++
+		if event.EventName == "GetSecretValue" {
+			summary.Reads++
+		}
+		if event.ErrorCode != "" {
+			summary.Errors++
+		}
+		if event.User != "" && !users[event.User] {
+			users[event.User] = true
+			summary.Users = append(summary.Users, event.User)
+		}
+		if summary.FirstEvent == nil || event.EventTime.Before(*summary.FirstEvent) {
+			summary.FirstEvent = &accessLog[i].EventTime
+		}
+		if summary.LastEvent == nil || event.EventTime.After(*summary.LastEvent) {
+			summary.LastEvent = &accessLog[i].EventTime
+		}
+	}
+	sort.Strings(summary.Users)
+	return report
+}
+
+// Writing the report with the renderer to the client
+func Write(rw http.ResponseWriter, renderer Renderer, report types.SecretReport) error {
+	rw.Header().Set("Content-Type", renderer.ContentType())
+	if renderer == HTMLRenderer || renderer == CSVRenderer {
+		rw.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", FileName(report, renderer)))
+	}
+	rw.WriteHeader(http.StatusOK)
+	return renderer.Render(rw, report)
+}
+
+// Name of the file the report is saved to
+func FileName(report types.SecretReport, renderer Renderer) string {
+	name := report.Secret.Name
+	if name == "" {
+		name = "secret"
+	}
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+	return "report-" + name + "." + Extension(renderer)
+}
+
+func Extension(renderer Renderer) string {
+	switch renderer {
+	case MarkdownRenderer:
+		return "md"
+	case TextRenderer:
+		return "txt"
+	}
+	return renderer.Name()
+}
+
+func baseMediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return mediaType
+}
+
+// Sending the report in the format the request asked for, the clients that
+// didn't ask for a format are getting the text report and the structured one
+// inside the JSON response
+func Respond(rw http.ResponseWriter, r *http.Request, report types.SecretReport) error {
+	renderer, err := Negotiate(r)
+	if err != nil {
+		return GenericEncoding.WriteJson(rw, http.StatusBadRequest, types.ApiError{Err: err.Error(), Status: http.StatusBadRequest})
+	}
+	if renderer == nil {
+		return GenericEncoding.WriteJson(rw, http.StatusOK, types.GetReportResponse{
+			Report: Text(report.Secret, report.AccessLog),
+			Data:   &report,
+		})
+	}
+	return Write(rw, renderer, report)
+}
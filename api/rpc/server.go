@@ -0,0 +1,254 @@
+package rpc
+
+import (
+	"context"
+	"golang-secret-manager/api/app"
+	"golang-secret-manager/api/report"
+	"golang-secret-manager/api/server/handler"
+	"golang-secret-manager/api/server/middleware"
+	"golang-secret-manager/api/stream"
+	pb "golang-secret-manager/proto/secretmanagerpb"
+	"golang-secret-manager/types"
+	"net/http"
+	"net/url"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// The methods that are admin endpoints in the HTTP API
+var adminMethods = map[string]bool{
+	pb.SecretManager_StreamInventory_FullMethodName: true,
+	pb.SecretManager_StreamEvents_FullMethodName:    true,
+}
+
+// Server is the gRPC API of the secret manager. It is using the middlewares
+// and the handlers of the HTTP API for the cache lookups, the admin token and
+// the rate limit, so both APIs answer the same
+type Server struct {
+	pb.UnimplementedSecretManagerServer
+
+	// The fetches from AWS are shared between the requests (like the HTTP
+	// server does), they are not canceled with the request
+	ctx context.Context
+	app *app.App
+	m   *middleware.Middleware
+	h   *handler.Handler
+}
+
+func New(ctx context.Context, a *app.App) *Server {
+	return &Server{
+		ctx: ctx,
+		app: a,
+		m:   middleware.New(a),
+		h:   handler.New(a),
+	}
+}
+
+// The grpc.Server of the API with the interceptors
+func NewGRPCServer(ctx context.Context, a *app.App) *grpc.Server {
+	s := New(ctx, a)
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	)
+	pb.RegisterSecretManagerServer(server, s)
+	return server
+}
+
+func (s *Server) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+	if err := s.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return next(ctx, req)
+}
+
+func (s *Server) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, next grpc.StreamHandler) error {
+	if err := s.authorize(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return next(srv, ss)
+}
+
+// Checking the rate limit of the client and the admin token of the admin
+// methods ("authorization: Bearer <token>" metadata)
+func (s *Server) authorize(ctx context.Context, method string) error {
+	client := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		client = middleware.ClientAddr(p.Addr.String())
+	}
+	if !s.app.Limiter.Allow(client) {
+		s.app.Logger.Println("RPC: rate limited", client, "on", method)
+		return status.Error(codes.ResourceExhausted, "too many requests")
+	}
+
+	if adminMethods[method] {
+		if !s.m.AdminEnabled() {
+			s.app.Logger.Println("RPC: admin request to", method, "refused, no admin token is configured")
+			return status.Error(codes.PermissionDenied, "admin methods are disabled, ADMIN_TOKEN is not set")
+		}
+		authorization := ""
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get("authorization"); len(values) > 0 {
+				authorization = values[0]
+			}
+		}
+		if !s.m.IsAdmin(authorization) {
+			s.app.Logger.Println("RPC: unauthorized admin request to", method)
+			return status.Error(codes.Unauthenticated, "invalid admin token")
+		}
+	}
+	return nil
+}
+
+// The status of the error of a handler
+func statusError(apiErr *types.ApiError) error {
+	code := codes.Internal
+	switch apiErr.Status {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		code = codes.Unimplemented
+	}
+	return status.Error(code, apiErr.Err)
+}
+
+func (s *Server) GetAllSecrets(ctx context.Context, req *pb.GetAllSecretsRequest) (*pb.GetAllSecretsResponse, error) {
+	credential := req.GetCredential()
+	toHandler, response := s.m.LookupAllSecrets(types.GetAllSecretsRequest{
+		PublicKey: credential.GetPublicKey(),
+		SecretKey: credential.GetSecretKey(),
+		Region:    credential.GetRegion(),
+	})
+	if response == nil {
+		var apiErr *types.ApiError
+		response, apiErr = s.h.ResolveAllSecrets(s.ctx, toHandler)
+		if apiErr != nil {
+			return nil, statusError(apiErr)
+		}
+	}
+	return pb.NewGetAllSecretsResponse(*response), nil
+}
+
+func (s *Server) GetSecret(ctx context.Context, req *pb.GetSecretRequest) (*pb.Secret, error) {
+	if req.GetSecretId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "secret_id is required")
+	}
+	secret := s.m.LookupSecret(req.GetSecretId())
+	if secret == nil {
+		credential := req.GetCredential()
+		var err error
+		secret, err = s.app.AWS.GetSecretById(s.ctx, credential.GetPublicKey(), credential.GetSecretKey(), req.GetSecretId(), credential.GetRegion())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "failed to retrive Secret from API")
+		}
+	}
+	return pb.NewSecret(*secret), nil
+}
+
+func (s *Server) GetReport(ctx context.Context, req *pb.GetReportRequest) (*pb.SecretReport, error) {
+	if req.GetSecretId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "secret_id is required")
+	}
+	credential := req.GetCredential()
+	toHandler, secretReport := s.m.LookupReport(types.GetReportRequest{
+		PublicKey: credential.GetPublicKey(),
+		SecretKey: credential.GetSecretKey(),
+		Region:    credential.GetRegion(),
+		SecretID:  req.GetSecretId(),
+		Filter:    req.GetFilter().ToType(),
+	})
+	if secretReport == nil {
+		var apiErr *types.ApiError
+		secretReport, apiErr = s.h.ResolveReport(s.ctx, toHandler)
+		if apiErr != nil {
+			return nil, statusError(apiErr)
+		}
+	}
+	return pb.NewSecretReport(*secretReport, report.Text(secretReport.Secret, secretReport.AccessLog)), nil
+}
+
+func (s *Server) GetAccessLog(ctx context.Context, req *pb.GetAccessLogRequest) (*pb.GetAccessLogResponse, error) {
+	credential := req.GetCredential()
+	response, apiErr := s.h.AccessLog(s.ctx, types.GetAccessLogRequest{
+		PublicKey: credential.GetPublicKey(),
+		SecretKey: credential.GetSecretKey(),
+		Region:    credential.GetRegion(),
+		SecretID:  req.GetSecretId(),
+		Filter:    req.GetFilter().ToType(),
+	})
+	if apiErr != nil {
+		return nil, statusError(apiErr)
+	}
+	return &pb.GetAccessLogResponse{
+		SecretId:  response.SecretID,
+		AccessLog: pb.NewAccessLogs(response.AccessLog),
+	}, nil
+}
+
+func (s *Server) StreamInventory(req *pb.StreamInventoryRequest, ss pb.SecretManager_StreamInventoryServer) error {
+	var snapshot *types.InventorySnapshot
+	var apiErr *types.ApiError
+	switch ref := req.GetSnapshot(); ref {
+	case "", types.InventoryTriggerLive:
+		credential := req.GetCredential()
+		snapshot, apiErr = s.h.TakeInventory(s.ctx, credential.GetPublicKey(), credential.GetSecretKey(), credential.GetRegion(), types.InventoryTriggerLive)
+	default:
+		snapshot, apiErr = s.h.ResolveInventory(ref)
+	}
+	if apiErr != nil {
+		return statusError(apiErr)
+	}
+
+	for _, secret := range snapshot.Secrets {
+		if err := ss.Send(pb.NewInventorySecret(secret)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) StreamEvents(req *pb.StreamEventsRequest, ss pb.SecretManager_StreamEventsServer) error {
+	filter, err := stream.ParseFilter(url.Values{
+		"arn":   req.GetArns(),
+		"tag":   req.GetTags(),
+		"event": req.GetEvents(),
+	})
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	sub, backlog := s.app.Stream.Subscribe(filter, req.GetLastEventId())
+	defer s.app.Stream.Unsubscribe(sub)
+	for _, event := range backlog {
+		if err := ss.Send(pb.NewStreamEvent(event)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ss.Context().Done():
+			return nil
+		case event, open := <-sub.Events:
+			if !open {
+				if sub.Lagged {
+					return status.Error(codes.ResourceExhausted, "the client fell behind, resume with last_event_id")
+				}
+				return status.Error(codes.Unavailable, "the server is shutting down")
+			}
+			if err := ss.Send(pb.NewStreamEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
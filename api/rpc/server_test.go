@@ -0,0 +1,139 @@
+package rpc
+
+import (
+	"context"
+	"golang-secret-manager/api/app"
+	"golang-secret-manager/api/server/config"
+	pb "golang-secret-manager/proto/secretmanagerpb"
+	"golang-secret-manager/types"
+	"golang-secret-manager/utils/storage"
+	"io"
+	"log"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testAdminToken = "test-token"
+
+// Serving the API of the app on an in memory listener
+func newTestClient(t *testing.T, cfg config.Config) (pb.SecretManagerClient, *app.App) {
+	t.Helper()
+	cache := storage.CreateFastCache(storage.FastCacheConfig{Expiration: time.Minute})
+	a := app.New(cfg, cache, storage.JSONCodec, nil, log.New(io.Discard, "", 0))
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewGRPCServer(context.Background(), a)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewSecretManagerClient(conn), a
+}
+
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+// Opening the stream of the method and reading its first message
+func recvStream(ctx context.Context, client pb.SecretManagerClient, method string) error {
+	switch method {
+	case "StreamInventory":
+		stream, err := client.StreamInventory(ctx, &pb.StreamInventoryRequest{Snapshot: "latest"})
+		if err != nil {
+			return err
+		}
+		_, err = stream.Recv()
+		return err
+	default:
+		stream, err := client.StreamEvents(ctx, &pb.StreamEventsRequest{LastEventId: "0"})
+		if err != nil {
+			return err
+		}
+		_, err = stream.Recv()
+		return err
+	}
+}
+
+func TestAdminStreamsNeedTheToken(t *testing.T) {
+	client, _ := newTestClient(t, config.Config{AdminToken: testAdminToken})
+	disabled, _ := newTestClient(t, config.Config{})
+
+	for _, method := range []string{"StreamInventory", "StreamEvents"} {
+		t.Run(method, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			tests := []struct {
+				name   string
+				client pb.SecretManagerClient
+				ctx    context.Context
+				want   codes.Code
+			}{
+				{name: "without the token", client: client, ctx: ctx, want: codes.Unauthenticated},
+				{name: "with a wrong token", client: client, ctx: withToken(ctx, "wrong"), want: codes.Unauthenticated},
+				{name: "without ADMIN_TOKEN", client: disabled, ctx: withToken(ctx, testAdminToken), want: codes.PermissionDenied},
+			}
+			for _, tt := range tests {
+				if got := status.Code(recvStream(tt.ctx, tt.client, method)); got != tt.want {
+					t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamEventsWithTheToken(t *testing.T) {
+	client, a := newTestClient(t, config.Config{AdminToken: testAdminToken})
+	a.Stream.Publish(types.StreamEvent{Type: types.StreamAccessLog, SecretARN: "arn:secret"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.StreamEvents(withToken(ctx, testAdminToken), &pb.StreamEventsRequest{LastEventId: "0"})
+	if err != nil {
+		t.Fatalf("StreamEvents: %v", err)
+	}
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if event.GetId() != 1 || event.GetSecretArn() != "arn:secret" {
+		t.Errorf("got %+v", event)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	client, _ := newTestClient(t, config.Config{AdminToken: testAdminToken, RateLimit: 1, RateLimitBurst: 2})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the request is refused by the handler, after the rate limit
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetSecret(ctx, &pb.GetSecretRequest{}); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("request %d: got %v, want InvalidArgument", i+1, err)
+		}
+	}
+	if _, err := client.GetSecret(ctx, &pb.GetSecretRequest{}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("over the burst: got %v, want ResourceExhausted", err)
+	}
+	// the streams are sharing the limit
+	if err := recvStream(withToken(ctx, testAdminToken), client, "StreamEvents"); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("stream over the burst: got %v, want ResourceExhausted", err)
+	}
+}
@@ -0,0 +1,272 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Configuration of the server, each value can be set by an environment
+// variable (or inside the .env file in the working dir)
+type Config struct {
+	Addr string
+
+	// Address of the gRPC server (:9090), it is off when it is empty
+	GrpcAddr string
+
+	// Requests a second of each client address with bursts of
+	// RateLimitBurst, 0 disables the limit
+	RateLimit      int
+	RateLimitBurst int
+
+	// Token of the admin endpoints, when it is empty they are refusing every
+	// request
+	AdminToken string
+
+	// Cache system
+	CacheBackend      string
+	PersistCacheDir   string
+	BoltPath          string
+	BoltCompact       bool
+	RedisAddr         string
+	RedisPrefix       string
+	RedisStandIn      bool
+	CacheExpiration   time.Duration
+	CacheMaxEntries   int
+	CacheMaxBytes     int64
+	CacheSaveInterval time.Duration
+	CacheCodec        string
+
+	Encryption EncryptionConfig
+
+	// How long the synced access log events of a secret are kept
+	AccessLogRetention time.Duration
+
+	// Policy that is checked when a request doesn't send its own
+	PolicyFile string
+
+	// Snapshots of the secrets inventory, they are taken each interval with
+	// the inventory credential (the scheduler is off without one)
+	InventoryDir       string
+	InventoryInterval  time.Duration
+	InventoryKeep      int
+	InventoryPublicKey string
+	InventorySecretKey string
+	InventoryRegion    string
+
+	Crawler CrawlerConfig
+
+	// Webhooks and routing rules of the notifications, empty disables them
+	NotifyFile string
+
+	// Events of /v1/events that are kept for the clients that reconnect
+	StreamBuffer int
+
+	// Directory that CloudTrail events are dropped into, empty disables
+	// the poller
+	EventsDir          string
+	EventsPollInterval time.Duration
+}
+
+// Encryption at rest of the persist cache
+type EncryptionConfig struct {
+	// none, file, passphrase, kms or local-kms
+	Mode string
+
+	KeyFile         string
+	Passphrase      string
+	SaltFile        string
+	KMSKeyID        string
+	KMSRegion       string
+	KMSBlobFile     string
+	LocalKMSKeyFile string
+
+	// The key that the cache was encrypted with before, the entries are
+	// re-encrypted with the current key when the server starts
+	PreviousKeyFile     string
+	PreviousPassphrase  string
+	PreviousKMSBlobFile string
+}
+
+// The background crawler, it crawls every region of every profile each
+// interval. It is off when there are no profiles
+type CrawlerConfig struct {
+	Interval time.Duration
+
+	// A random delay up to Jitter is added to each run so the crawls of the
+	// profiles are spread out
+	Jitter time.Duration
+
+	// The delay before the first retry of a failed crawl, doubled on each
+	// failure up to the interval
+	Backoff time.Duration
+
+	// Crawls that can run at the same time
+	Concurrency int
+
+	// Finished jobs that are kept in the history
+	HistorySize int
+
+	Profiles []CrawlerProfile
+}
+
+// A credential the crawler is using, set by CRAWLER_PROFILES=prod,staging and
+// CRAWLER_<NAME>_PUBLIC_KEY, CRAWLER_<NAME>_SECRET_KEY, CRAWLER_<NAME>_REGIONS
+type CrawlerProfile struct {
+	Name      string
+	PublicKey string
+	SecretKey string
+	Regions   []string
+}
+
+const (
+	BackendFiles = "files"
+	BackendBolt  = "bolt"
+	BackendRedis = "redis"
+)
+
+const (
+	EncryptionNone       = "none"
+	EncryptionFile       = "file"
+	EncryptionPassphrase = "passphrase"
+	EncryptionKMS        = "kms"
+	EncryptionLocalKMS   = "local-kms"
+)
+
+func Load() Config {
+	// the .env file is optional
+	godotenv.Load(".env")
+
+	return Config{
+		Addr:               getEnv("SERVER_ADDR", ":8080"),
+		GrpcAddr:           os.Getenv("GRPC_ADDR"),
+		RateLimit:          getEnvInt("RATE_LIMIT", 0),
+		RateLimitBurst:     getEnvInt("RATE_LIMIT_BURST", 20),
+		AdminToken:         os.Getenv("ADMIN_TOKEN"),
+		CacheBackend:       getEnv("CACHE_BACKEND", BackendFiles),
+		PersistCacheDir:    getEnv("CACHE_DIR", "./persist-cache/"),
+		BoltPath:           getEnv("CACHE_BOLT_PATH", "./cache.db"),
+		BoltCompact:        getEnvBool("CACHE_BOLT_COMPACT", false),
+		RedisAddr:          getEnv("CACHE_REDIS_ADDR", "localhost:6379"),
+		RedisPrefix:        getEnv("CACHE_REDIS_PREFIX", "secret-manager:"),
+		RedisStandIn:       getEnvBool("CACHE_REDIS_STANDIN", false),
+		CacheExpiration:    getEnvDuration("CACHE_EXPIRATION", 5*time.Minute),
+		CacheMaxEntries:    getEnvInt("CACHE_MAX_ENTRIES", 10000),
+		CacheMaxBytes:      int64(getEnvInt("CACHE_MAX_BYTES", 64<<20)),
+		CacheSaveInterval:  getEnvDuration("CACHE_SAVE_INTERVAL", 20*time.Second),
+		CacheCodec:         getEnv("CACHE_CODEC", "json"),
+		AccessLogRetention: getEnvDuration("ACCESS_LOG_RETENTION", 90*24*time.Hour),
+		PolicyFile:         os.Getenv("POLICY_FILE"),
+		InventoryDir:       getEnv("INVENTORY_DIR", "./inventory/"),
+		InventoryInterval:  getEnvDuration("INVENTORY_INTERVAL", 24*time.Hour),
+		InventoryKeep:      getEnvInt("INVENTORY_KEEP", 365),
+		InventoryPublicKey: os.Getenv("INVENTORY_PUBLIC_KEY"),
+		InventorySecretKey: os.Getenv("INVENTORY_SECRET_KEY"),
+		InventoryRegion:    getEnv("INVENTORY_REGION", "eu-north-1"),
+		Crawler: CrawlerConfig{
+			Interval:    getEnvDuration("CRAWLER_INTERVAL", time.Hour),
+			Jitter:      getEnvDuration("CRAWLER_JITTER", 5*time.Minute),
+			Backoff:     getEnvDuration("CRAWLER_BACKOFF", time.Minute),
+			Concurrency: getEnvInt("CRAWLER_CONCURRENCY", 2),
+			HistorySize: getEnvInt("CRAWLER_HISTORY", 200),
+			Profiles:    loadCrawlerProfiles(),
+		},
+		NotifyFile:         os.Getenv("NOTIFY_FILE"),
+		StreamBuffer:       getEnvInt("STREAM_BUFFER", 1000),
+		EventsDir:          os.Getenv("EVENTS_DIR"),
+		EventsPollInterval: getEnvDuration("EVENTS_POLL_INTERVAL", 10*time.Second),
+		Encryption: EncryptionConfig{
+			Mode:                getEnv("CACHE_ENCRYPTION", EncryptionNone),
+			KeyFile:             getEnv("CACHE_KEY_FILE", "./cache-keys/cache.key"),
+			Passphrase:          os.Getenv("CACHE_PASSPHRASE"),
+			SaltFile:            getEnv("CACHE_SALT_FILE", "./cache-keys/cache.salt"),
+			KMSKeyID:            os.Getenv("CACHE_KMS_KEY_ID"),
+			KMSRegion:           os.Getenv("CACHE_KMS_REGION"),
+			KMSBlobFile:         getEnv("CACHE_KMS_BLOB_FILE", "./cache-keys/cache.blob"),
+			LocalKMSKeyFile:     getEnv("CACHE_LOCAL_KMS_FILE", "./cache-keys/local-kms.key"),
+			PreviousKeyFile:     os.Getenv("CACHE_PREVIOUS_KEY_FILE"),
+			PreviousPassphrase:  os.Getenv("CACHE_PREVIOUS_PASSPHRASE"),
+			PreviousKMSBlobFile: os.Getenv("CACHE_PREVIOUS_KMS_BLOB_FILE"),
+		},
+	}
+}
+
+func loadCrawlerProfiles() []CrawlerProfile {
+	var profiles []CrawlerProfile
+	for _, name := range splitList(os.Getenv("CRAWLER_PROFILES")) {
+		prefix := "CRAWLER_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		profile := CrawlerProfile{
+			Name:      name,
+			PublicKey: os.Getenv(prefix + "PUBLIC_KEY"),
+			SecretKey: os.Getenv(prefix + "SECRET_KEY"),
+			Regions:   splitList(getEnv(prefix+"REGIONS", getEnv("CRAWLER_REGIONS", "eu-north-1"))),
+		}
+		if profile.PublicKey == "" || profile.SecretKey == "" {
+			log.Println("CONFIG: crawler profile", name, "has no keys, skipping it")
+			continue
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles
+}
+
+// Splitting a comma separated list, the empty items are dropped
+func splitList(val string) []string {
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnv(name string, defaultValue string) string {
+	if val := os.Getenv(name); val != "" {
+		return val
+	}
+	return defaultValue
+}
+
+func getEnvInt(name string, defaultValue int) int {
+	val := os.Getenv(name)
+	if val == "" {
+		return defaultValue
+	}
+	num, err := strconv.Atoi(val)
+	if err != nil {
+		log.Println("CONFIG: invalid number in", name, "using default:", defaultValue)
+		return defaultValue
+	}
+	return num
+}
+
+func getEnvBool(name string, defaultValue bool) bool {
+	val := os.Getenv(name)
+	if val == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		log.Println("CONFIG: invalid boolean in", name, "using default:", defaultValue)
+		return defaultValue
+	}
+	return b
+}
+
+func getEnvDuration(name string, defaultValue time.Duration) time.Duration {
+	val := os.Getenv(name)
+	if val == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(val)
+	if err != nil {
+		log.Println("CONFIG: invalid duration in", name, "using default:", defaultValue)
+		return defaultValue
+	}
+	return duration
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"golang-secret-manager/api/aws"
+	"golang-secret-manager/utils/storage"
+)
+
+// Building the key provider of the persist cache from the config, nil is
+// returned when the encryption is disabled
+func BuildKeyProvider(cfg EncryptionConfig) (storage.KeyProvider, error) {
+	switch cfg.Mode {
+	case "", EncryptionNone:
+		return nil, nil
+	case EncryptionFile:
+		return storage.NewFileKeyProvider(cfg.KeyFile), nil
+	case EncryptionPassphrase:
+		if cfg.Passphrase == "" {
+			return nil, fmt.Errorf("CACHE_PASSPHRASE must be set for passphrase encryption")
+		}
+		return storage.NewPassphraseKeyProvider(cfg.Passphrase, cfg.SaltFile), nil
+	case EncryptionKMS:
+		if cfg.KMSKeyID == "" {
+			return nil, fmt.Errorf("CACHE_KMS_KEY_ID must be set for kms encryption")
+		}
+		client, err := aws.NewKMSClient(cfg.KMSRegion)
+		if err != nil {
+			return nil, err
+		}
+		return storage.NewKMSKeyProvider(client, cfg.KMSKeyID, cfg.KMSBlobFile), nil
+	case EncryptionLocalKMS:
+		client := storage.NewLocalKMS(cfg.LocalKMSKeyFile)
+		return storage.NewKMSKeyProvider(client, "local", cfg.KMSBlobFile), nil
+	}
+	return nil, fmt.Errorf("unknown cache encryption mode: %s", cfg.Mode)
+}
+
+// The keys that the cache may still be encrypted with
+func BuildPreviousKeyProviders(cfg EncryptionConfig) ([]storage.KeyProvider, error) {
+	var providers []storage.KeyProvider
+	if cfg.PreviousKeyFile != "" {
+		providers = append(providers, storage.NewFileKeyProvider(cfg.PreviousKeyFile))
+	}
+	if cfg.PreviousPassphrase != "" {
+		providers = append(providers, storage.NewPassphraseKeyProvider(cfg.PreviousPassphrase, cfg.SaltFile))
+	}
+	if cfg.PreviousKMSBlobFile != "" {
+		var client storage.KMSClient = storage.NewLocalKMS(cfg.LocalKMSKeyFile)
+		if cfg.Mode == EncryptionKMS {
+			var err error
+			if client, err = aws.NewKMSClient(cfg.KMSRegion); err != nil {
+				return nil, err
+			}
+		}
+		providers = append(providers, storage.NewKMSKeyProvider(client, cfg.KMSKeyID, cfg.PreviousKMSBlobFile))
+	}
+	return providers, nil
+}
+
+// Encrypting the persist layer when the encryption is enabled, the entries
+// that were written with a previous key are re-encrypted
+func ApplyEncryption(layer storage.ICache, cfg EncryptionConfig) error {
+	provider, err := BuildKeyProvider(cfg)
+	if err != nil || provider == nil {
+		return err
+	}
+	previous, err := BuildPreviousKeyProviders(cfg)
+	if err != nil {
+		return err
+	}
+	encrypted, ok := layer.(interface {
+		SetEncryption(provider storage.KeyProvider, previous ...storage.KeyProvider) error
+	})
+	if !ok {
+		return fmt.Errorf("cache layer %T doesn't support encryption", layer)
+	}
+	return encrypted.SetEncryption(provider, previous...)
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"golang-secret-manager/utils/storage"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestApplyEncryptionFailsClosed(t *testing.T) {
+	dir := t.TempDir()
+	persist, err := storage.CreatePersistCache(filepath.Join(dir, "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer persist.HomeDir.Close()
+
+	tests := []struct {
+		name  string
+		layer storage.ICache
+		cfg   EncryptionConfig
+	}{
+		{
+			name:  "missing passphrase",
+			layer: persist,
+			cfg:   EncryptionConfig{Mode: EncryptionPassphrase, SaltFile: filepath.Join(dir, "salt")},
+		},
+		{
+			name:  "unknown mode",
+			layer: persist,
+			cfg:   EncryptionConfig{Mode: "rot13"},
+		},
+		{
+			name:  "layer without encryption",
+			layer: storage.CreateFastCache(storage.FastCacheConfig{Expiration: time.Minute}),
+			cfg:   EncryptionConfig{Mode: EncryptionFile, KeyFile: filepath.Join(dir, "cache.key")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ApplyEncryption(tt.layer, tt.cfg)
+			if err == nil {
+				t.Fatal("ApplyEncryption returned no error")
+			}
+			if storage.IsReencryptError(err) {
+				t.Errorf("got a ReencryptError, the server would start in plain text: %v", err)
+			}
+		})
+	}
+
+	if err := ApplyEncryption(persist, EncryptionConfig{Mode: EncryptionNone}); err != nil {
+		t.Errorf("ApplyEncryption without encryption: %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"golang-secret-manager/types"
+	GenericEncoding "golang-secret-manager/utils/genericEncoding"
+	"golang-secret-manager/utils/storage"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// The calls of the fast cache that the admin endpoints are using
+type inspectableCache interface {
+	Keys() []string
+	Inspect(key string) (types.CacheEntryInfo, error)
+	Flush() (int, error)
+	PreviewEvictions(maxEntries int, maxBytes int64) []types.CacheEviction
+}
+
+func (h *Handler) inspectableCache() (inspectableCache, error) {
+	cache, ok := h.app.Cache.(inspectableCache)
+	if !ok {
+		return nil, &types.ApiError{Err: "cache doesn't support inspection", Status: http.StatusNotImplemented}
+	}
+	return cache, nil
+}
+
+// Listing the cache keys grouped by namespace, ?namespace= returns only one
+func (h *Handler) GetCacheKeysHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+	cache, err := h.inspectableCache()
+	if err != nil {
+		return err
+	}
+
+	namespace := r.URL.Query().Get("namespace")
+	toSend := types.GetCacheKeysResponse{Keys: make(map[string][]string)}
+	for _, key := range cache.Keys() {
+		keyNamespace := storage.KeyNamespace(key, storage.DefaultNamespaces)
+		if namespace != "" && namespace != keyNamespace {
+			continue
+		}
+		toSend.Keys[keyNamespace] = append(toSend.Keys[keyNamespace], key)
+	}
+
+	if err := GenericEncoding.WriteJson(rw, http.StatusOK, toSend); err != nil {
+		log.Printf("failed to write json to client %v", err)
+	}
+	return nil
+}
+
+// Showing a single entry with its age and dirty flag, ?key=
+func (h *Handler) GetCacheEntryHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+	cache, err := h.inspectableCache()
+	if err != nil {
+		return err
+	}
+
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		return &types.ApiError{Err: "missing key", Status: http.StatusBadRequest}
+	}
+	info, err := cache.Inspect(key)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			return &types.ApiError{Err: "key was not found: " + key, Status: http.StatusNotFound}
+		}
+		h.app.Logger.Println("HANDLER: failed to inspect cache key:", key, err)
+		return &types.ApiError{Err: "failed to read the key from the cache", Status: http.StatusInternalServerError}
+	}
+
+	if err := GenericEncoding.WriteJson(rw, http.StatusOK, info); err != nil {
+		log.Printf("failed to write json to client %v", err)
+	}
+	return nil
+}
+
+// Removing a single ARN or everything of a credential from all the cache
+// layers, with dry_run only the keys that would be removed are returned
+func (h *Handler) InvalidateCacheHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+	if r.Method != http.MethodPost {
+		return &types.ApiError{Err: "method not allowed", Status: http.StatusMethodNotAllowed}
+	}
+	reqBody, err := GenericEncoding.JsonBodyDecoder[types.CacheInvalidateRequest](r.Body)
+	if err != nil {
+		return &types.ApiError{Err: "request body didn't matched!", Status: http.StatusBadRequest}
+	}
+
+	var keys []string
+	switch {
+	case reqBody.ARN != "" && reqBody.PublicKey != "":
+		return &types.ApiError{Err: "only one of arn or public_key can be set", Status: http.StatusBadRequest}
+	case reqBody.ARN != "":
+		keys, err = h.app.AWS.InvalidateSecret(reqBody.ARN, reqBody.DryRun)
+	case reqBody.PublicKey != "":
+		keys, err = h.app.AWS.InvalidateCredential(reqBody.PublicKey, reqBody.DryRun)
+	default:
+		return &types.ApiError{Err: "arn or public_key must be set", Status: http.StatusBadRequest}
+	}
+	if err != nil {
+		h.app.Logger.Println("HANDLER: failed to invalidate the cache:", err)
+		return &types.ApiError{Err: "failed to invalidate the cache", Status: http.StatusInternalServerError}
+	}
+
+	toSend := types.CacheInvalidateResponse{DryRun: reqBody.DryRun, Keys: keys}
+	if err := GenericEncoding.WriteJson(rw, http.StatusOK, toSend); err != nil {
+		log.Printf("failed to write json to client %v", err)
+	}
+	return nil
+}
+
+// Saving all the changed keys to the persist layer now
+func (h *Handler) FlushCacheHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+	if r.Method != http.MethodPost {
+		return &types.ApiError{Err: "method not allowed", Status: http.StatusMethodNotAllowed}
+	}
+	cache, err := h.inspectableCache()
+	if err != nil {
+		return err
+	}
+
+	flushed, err := cache.Flush()
+	if err != nil {
+		h.app.Logger.Println("HANDLER: failed to flush the cache:", err)
+		return &types.ApiError{Err: "failed to flush some of the keys to the persist layer", Status: http.StatusInternalServerError}
+	}
+
+	if err := GenericEncoding.WriteJson(rw, http.StatusOK, types.CacheFlushResponse{Flushed: flushed}); err != nil {
+		log.Printf("failed to write json to client %v", err)
+	}
+	return nil
+}
+
+// Previewing which keys would be evicted, ?max_entries= and ?max_bytes= are
+// previewing a smaller budget than the configured one
+func (h *Handler) PreviewEvictionsHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+	cache, err := h.inspectableCache()
+	if err != nil {
+		return err
+	}
+
+	query := r.URL.Query()
+	var maxEntries int
+	var maxBytes int64
+	if val := query.Get("max_entries"); val != "" {
+		if maxEntries, err = strconv.Atoi(val); err != nil {
+			return &types.ApiError{Err: "invalid max_entries", Status: http.StatusBadRequest}
+		}
+	}
+	if val := query.Get("max_bytes"); val != "" {
+		if maxBytes, err = strconv.ParseInt(val, 10, 64); err != nil {
+			return &types.ApiError{Err: "invalid max_bytes", Status: http.StatusBadRequest}
+		}
+	}
+
+	toSend := types.CacheEvictionPreviewResponse{Evictions: cache.PreviewEvictions(maxEntries, maxBytes)}
+	if err := GenericEncoding.WriteJson(rw, http.StatusOK, toSend); err != nil {
+		log.Printf("failed to write json to client %v", err)
+	}
+	return nil
+}
+
+// Returning the entries that couldn't be migrated to the current schema
+// versions when the cache was loaded
+func (h *Handler) GetCacheMigrationsHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+	cache, ok := h.app.Cache.(interface {
+		MigrationReport() types.CacheMigrationReport
+	})
+	if !ok {
+		return &types.ApiError{Err: "cache doesn't support schema migrations", Status: http.StatusNotImplemented}
+	}
+	if err := GenericEncoding.WriteJson(rw, http.StatusOK, cache.MigrationReport()); err != nil {
+		log.Printf("failed to write json to client %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"golang-secret-manager/api/anomaly"
+	"golang-secret-manager/api/report"
+	"golang-secret-manager/types"
+	GenericEncoding "golang-secret-manager/utils/genericEncoding"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Checking the cached access logs against the baseline of each secret,
+// ?arn= (can be repeated) selects the secrets, ?window= the recent events
+// that are checked (24h by default) and ?format=text returns the CLI report
+func (h *Handler) GetAnomaliesHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+	if r.Method != http.MethodGet {
+		return &types.ApiError{Err: "method not allowed", Status: http.StatusMethodNotAllowed}
+	}
+
+	options := anomaly.DefaultOptions
+	query := r.URL.Query()
+	if value := query.Get("window"); value != "" {
+		window, err := anomaly.ParseWindow(value)
+		if err != nil {
+			return &types.ApiError{Err: err.Error(), Status: http.StatusBadRequest}
+		}
+		options.Window = window
+	}
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "text" {
+		return &types.ApiError{Err: "unknown format: " + format, Status: http.StatusBadRequest}
+	}
+
+	accessLogs, secrets := h.app.AWS.CachedAccessLogs(query["arn"])
+	result := anomaly.Detect(accessLogs, secrets, options, time.Now())
+	if len(result.Anomalies) > 0 {
+		h.app.Logger.Println("HANDLER: found", len(result.Anomalies), "access anomalies")
+	}
+
+	if format == "text" {
+		rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		rw.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(rw, report.AnomalyText(result)); err != nil {
+			log.Printf("failed to write report to client %v", err)
+		}
+		return nil
+	}
+	if err := GenericEncoding.WriteJson(rw, http.StatusOK, result); err != nil {
+		log.Printf("failed to write json to client %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"golang-secret-manager/api/aws"
+	"golang-secret-manager/types"
+	GenericEncoding "golang-secret-manager/utils/genericEncoding"
+	"io"
+	"log"
+	"net/http"
+)
+
+// Largest body of events that is accepted
+const maxEventsBodySize = 16 << 20
+
+// Receiving Secrets Manager CloudTrail events (a CloudTrail log file, an
+// EventBridge event or plain records) and applying them to the cache
+func (h *Handler) IngestEventsHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+	if r.Method != http.MethodPost {
+		return &types.ApiError{Err: "method not allowed", Status: http.StatusMethodNotAllowed}
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, maxEventsBodySize))
+	if err != nil {
+		return &types.ApiError{Err: "failed to read the request body", Status: http.StatusBadRequest}
+	}
+	events, err := aws.ParseCloudTrailEvents(data)
+	if err != nil {
+		return &types.ApiError{Err: err.Error(), Status: http.StatusBadRequest}
+	}
+
+	toSend := h.app.AWS.ApplyEvents(events)
+	h.app.Logger.Printf("HANDLER: applied %d of %d events to the cache", toSend.Applied, toSend.Received)
+	if err := GenericEncoding.WriteJson(rw, http.StatusOK, toSend); err != nil {
+		log.Printf("failed to write json to client %v", err)
+	}
+	return nil
+}
@@ -1,10 +1,13 @@
 package handler
 
 import (
-	"fmt"
+	"context"
+	"golang-secret-manager/api/app"
 	"golang-secret-manager/api/aws"
+	"golang-secret-manager/api/report"
 	"golang-secret-manager/types"
 	GenericEncoding "golang-secret-manager/utils/genericEncoding"
+	"golang-secret-manager/utils/storage"
 	"log"
 	"net/http"
 )
@@ -22,44 +25,61 @@ func MakeHTTPHandleFuncDecoder(handler types.ApiHandlerFunc) http.HandlerFunc {
 				}
 				return
 			}
-			// apiErr is type apiError
-			if err := GenericEncoding.WriteJson(rw, apiErr.Status, apiErr.Err); err != nil {
+			// apiErr is type apiError, written whole like the middlewares
+			// are writing their errors
+			if err := GenericEncoding.WriteJson(rw, apiErr.Status, apiErr); err != nil {
 				log.Printf("failed to write json to client %v", err)
 			}
 		}
 	}
 }
 
-func GetAllSecretsHandlers(rw http.ResponseWriter, r *http.Request) error {
+// The handlers are using the services of the app
+type Handler struct {
+	app *app.App
+}
+
+func New(a *app.App) *Handler {
+	return &Handler{app: a}
+}
+
+func (h *Handler) GetAllSecretsHandlers(rw http.ResponseWriter, r *http.Request) error {
 	defer r.Body.Close()
 	ctx := r.Context()
 	contextKey := types.GetContextInforamtionKey()
 	fromContext, ok := ctx.Value(contextKey).(*types.FromGetAllSecretsMiddlewareToHandler)
 	if !ok {
-		fmt.Println("HANDLER: failed to convert the context value to the handler")
+		h.app.Logger.Println("HANDLER: failed to convert the context value to the handler")
 		return &types.ApiError{Err: "Error Converting the context to the handler", Status: http.StatusInternalServerError}
 	}
 
+	toSend, apiErr := h.ResolveAllSecrets(ctx, fromContext)
+	if apiErr != nil {
+		return apiErr
+	}
+	if err := GenericEncoding.WriteJson(rw, http.StatusOK, toSend); err != nil {
+		// failed sending back to client
+		h.app.Logger.Println("HANDLER: failed to send back to client information")
+	}
+	return nil
+}
+
+// Retriving the secrets that the middleware didn't find in the cache (the
+// gRPC server is using it too)
+func (h *Handler) ResolveAllSecrets(ctx context.Context, fromContext *types.FromGetAllSecretsMiddlewareToHandler) (*types.GetAllSecretsResponse, *types.ApiError) {
 	if !fromContext.FoundedArnList {
 		// there was not ArnList for that user in the cache
-		val, err := aws.RetriveAllSecretsWithAccessLog(ctx, fromContext.PublicKey, fromContext.SecretKey, fromContext.Region)
+		val, err := h.app.AWS.RetriveAllSecretsWithAccessLog(ctx, fromContext.PublicKey, fromContext.SecretKey, fromContext.Region)
 		if err != nil {
 			// failed to retrive all of them, return bad request
-			fmt.Println("HANDLER: failed to retrive all the secrets and access log")
-			return &types.ApiError{Err: "Error while trying to retrive all Secrets + Access Logs", Status: http.StatusInternalServerError}
+			h.app.Logger.Println("HANDLER: failed to retrive all the secrets and access log")
+			return nil, &types.ApiError{Err: "Error while trying to retrive all Secrets + Access Logs", Status: http.StatusInternalServerError}
 		}
 
-		// sending back to the client the anwser
-		toSend := types.GetAllSecretsResponse{
+		return &types.GetAllSecretsResponse{
 			Secrets:   val.Secrets,
 			AccessLog: val.AccessLog,
-		}
-
-		if err = GenericEncoding.WriteJson(rw, http.StatusOK, toSend); err != nil {
-			// failed sending back to client
-			fmt.Println("HANDLER: failed to send back to client information")
-		}
-		return nil
+		}, nil
 	}
 
 	// maybe some of the data was found, for what not using the api to retrive the
@@ -67,7 +87,7 @@ func GetAllSecretsHandlers(rw http.ResponseWriter, r *http.Request) error {
 	for _, arn := range fromContext.ArnList {
 		if _, ok := fromContext.FoundedSecrets[arn]; !ok {
 			// key wasn't found in the map, using the AWS api to retrive it
-			fromApi, err := aws.GetSecretByIdWithAccessLog(
+			fromApi, err := h.app.AWS.GetSecretByIdWithAccessLog(
 				ctx,
 				fromContext.PublicKey,
 				fromContext.SecretKey,
@@ -75,7 +95,7 @@ func GetAllSecretsHandlers(rw http.ResponseWriter, r *http.Request) error {
 				fromContext.Region)
 			if err != nil {
 				// failed to retrive the information
-				fmt.Println("HANDLER: failed to retrive infromation from API about arn:", arn)
+				h.app.Logger.Println("HANDLER: failed to retrive infromation from API about arn:", arn)
 				continue
 			}
 			fromContext.FoundedSecrets[arn] = fromApi.Secret
@@ -88,54 +108,160 @@ func GetAllSecretsHandlers(rw http.ResponseWriter, r *http.Request) error {
 		secretList = append(secretList, val)
 	}
 
-	toSend := types.GetAllSecretsResponse{
+	return &types.GetAllSecretsResponse{
 		Secrets:   secretList,
 		AccessLog: fromContext.FoundedAccessLog,
-	}
-	if err := GenericEncoding.WriteJson(rw, http.StatusOK, toSend); err != nil {
-		// failed sending back to client
-		fmt.Println("HANDLER: failed to send back to client information")
-	}
-	return nil
+	}, nil
 }
 
-func GetReportsHandler(rw http.ResponseWriter, r *http.Request) error {
+func (h *Handler) GetReportsHandler(rw http.ResponseWriter, r *http.Request) error {
 	defer r.Body.Close()
 	ctx := r.Context()
 	contextKey := types.GetContextInforamtionKey()
 	fromContext, ok := ctx.Value(contextKey).(*types.FromGetReportMiddlewareToHandler)
 	if !ok {
-		fmt.Println("HANDLER: failed to convert the context value to the handler")
+		h.app.Logger.Println("HANDLER: failed to convert the context value to the handler")
 		return &types.ApiError{Err: "Error Converting the context to the handler", Status: http.StatusInternalServerError}
 	}
 
+	secretReport, apiErr := h.ResolveReport(ctx, fromContext)
+	if apiErr != nil {
+		return apiErr
+	}
+	// writing to the client back in the format it asked for
+	if err := report.Respond(rw, r, *secretReport); err != nil {
+		log.Printf("failed to write report to client %v", err)
+	}
+	return nil
+}
+
+// Retriving what the middleware didn't find in the cache and building the
+// report
+func (h *Handler) ResolveReport(ctx context.Context, fromContext *types.FromGetReportMiddlewareToHandler) (*types.SecretReport, *types.ApiError) {
 	if fromContext.FoundedSecret == nil {
 		// retriving the secret from AWS api
-		secret, err := aws.GetSecretById(ctx, fromContext.PublicKey, fromContext.SecretKey, fromContext.SecretID, fromContext.Region)
+		secret, err := h.app.AWS.GetSecretById(ctx, fromContext.PublicKey, fromContext.SecretKey, fromContext.SecretID, fromContext.Region)
 		if err != nil {
 			// failed to retrive secret from AWS api
-			return &types.ApiError{Err: "failed to retrive Secret from API", Status: http.StatusBadRequest}
+			return nil, &types.ApiError{Err: "failed to retrive Secret from API", Status: http.StatusBadRequest}
 		}
 		fromContext.FoundedSecret = secret
 	}
 
 	if fromContext.FoundedAccessLog == nil {
 		// retriving the access log from AWS api
-		access, err := aws.GetAccessLog(ctx, fromContext.PublicKey, fromContext.SecretKey, fromContext.SecretID, fromContext.Region)
+		access, err := h.app.AWS.GetFilteredAccessLog(ctx, fromContext.PublicKey, fromContext.SecretKey, fromContext.SecretID, fromContext.Region, fromContext.Filter)
 		if err != nil {
-			return &types.ApiError{Err: "failed to retrive Access Log from API", Status: http.StatusBadRequest}
+			return nil, &types.ApiError{Err: "failed to retrive Access Log from API", Status: http.StatusBadRequest}
 		}
 		fromContext.FoundedAccessLog = access
 	}
 
-	// writing to the client back
-	report := aws.GenerateReportStringBySecret(*fromContext.FoundedSecret, fromContext.FoundedAccessLog)
-	toSend := types.GetReportResponse{
-		Report: report,
+	h.app.Logger.Println("HANDLER: Generating Report For", fromContext.FoundedSecret.ARN)
+	secretReport := report.Build(*fromContext.FoundedSecret, fromContext.FoundedAccessLog, fromContext.Filter)
+	return &secretReport, nil
+}
+
+// Returning the access log of a single secret, only the events of the filter
+// are returned
+func (h *Handler) GetAccessLogHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+	reqBody, err := GenericEncoding.JsonBodyDecoder[types.GetAccessLogRequest](r.Body)
+	if err != nil {
+		return &types.ApiError{Err: "request body didn't matched!", Status: http.StatusBadRequest}
 	}
 
+	toSend, apiErr := h.AccessLog(r.Context(), *reqBody)
+	if apiErr != nil {
+		return apiErr
+	}
 	if err := GenericEncoding.WriteJson(rw, http.StatusOK, toSend); err != nil {
 		log.Printf("failed to write json to client %v", err)
 	}
 	return nil
 }
+
+func (h *Handler) AccessLog(ctx context.Context, reqBody types.GetAccessLogRequest) (*types.GetAccessLogResponse, *types.ApiError) {
+	if reqBody.SecretID == "" {
+		return nil, &types.ApiError{Err: "secret_id is required", Status: http.StatusBadRequest}
+	}
+	if reqBody.Filter != nil && reqBody.Filter.Since != nil && reqBody.Filter.Until != nil && reqBody.Filter.Until.Before(*reqBody.Filter.Since) {
+		return nil, &types.ApiError{Err: "until is before since", Status: http.StatusBadRequest}
+	}
+
+	access, err := h.app.AWS.GetFilteredAccessLog(ctx, reqBody.PublicKey, reqBody.SecretKey, reqBody.SecretID, reqBody.Region, reqBody.Filter)
+	if err != nil {
+		h.app.Logger.Println("HANDLER: failed to retrive the access log of", reqBody.SecretID, err)
+		return nil, &types.ApiError{Err: "failed to retrive Access Log from API", Status: http.StatusBadRequest}
+	}
+
+	return &types.GetAccessLogResponse{
+		SecretID:  reqBody.SecretID,
+		AccessLog: access,
+	}, nil
+}
+
+// Crawling all the secrets of the credential and checking their hygiene
+func (h *Handler) GetHygieneReportHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+	reqBody, err := GenericEncoding.JsonBodyDecoder[types.HygieneRequest](r.Body)
+	if err != nil {
+		return &types.ApiError{Err: "request body didn't matched!", Status: http.StatusBadRequest}
+	}
+	if _, err := report.NegotiateHygiene(r); err != nil {
+		return &types.ApiError{Err: err.Error(), Status: http.StatusBadRequest}
+	}
+
+	hygiene, err := h.app.AWS.HygieneReport(r.Context(), reqBody.PublicKey, reqBody.SecretKey, reqBody.Region, aws.HygieneOptions{
+		UnusedDays:   reqBody.UnusedDays,
+		RequiredTags: reqBody.RequiredTags,
+	})
+	if err != nil {
+		h.app.Logger.Println("HANDLER: failed to crawl the secrets for the hygiene report:", err)
+		return &types.ApiError{Err: "Error while trying to retrive all Secrets + Access Logs", Status: http.StatusInternalServerError}
+	}
+	if err := report.RespondHygiene(rw, r, *hygiene); err != nil {
+		log.Printf("failed to write report to client %v", err)
+	}
+	return nil
+}
+
+// Returning the counters of the cache (hits, misses, evictions...)
+func (h *Handler) GetMetricsHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+	cache, ok := h.app.Cache.(interface{ Stats() types.CacheStats })
+	if !ok {
+		return &types.ApiError{Err: "cache doesn't support metrics", Status: http.StatusNotImplemented}
+	}
+	toSend := types.GetMetricsResponse{
+		Cache: cache.Stats(),
+	}
+	if err := GenericEncoding.WriteJson(rw, http.StatusOK, toSend); err != nil {
+		log.Printf("failed to write json to client %v", err)
+	}
+	return nil
+}
+
+// Checking that all the entries of the persist cache can be decrypted
+func (h *Handler) VerifyCacheHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+	cache, ok := h.app.Cache.(interface{ Layer() storage.ICache })
+	if !ok {
+		return &types.ApiError{Err: "cache has no persist layer", Status: http.StatusNotImplemented}
+	}
+	layer, ok := cache.Layer().(interface {
+		Verify() types.CacheVerifyReport
+	})
+	if !ok {
+		return &types.ApiError{Err: "persist layer doesn't support verify", Status: http.StatusNotImplemented}
+	}
+	report := layer.Verify()
+	status := http.StatusOK
+	if !report.OK {
+		status = http.StatusConflict
+	}
+	if err := GenericEncoding.WriteJson(rw, status, report); err != nil {
+		log.Printf("failed to write json to client %v", err)
+	}
+	return nil
+}
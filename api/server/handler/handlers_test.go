@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"golang-secret-manager/types"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMakeHTTPHandleFuncDecoderWritesApiError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want types.ApiError
+	}{
+		{
+			name: "api error",
+			err:  &types.ApiError{Err: "secret not found", Status: http.StatusNotFound},
+			want: types.ApiError{Err: "secret not found", Status: http.StatusNotFound},
+		},
+		{
+			name: "other error",
+			err:  errors.New("connection reset"),
+			want: types.ApiError{Err: "internal error", Status: http.StatusInternalServerError},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := MakeHTTPHandleFuncDecoder(func(rw http.ResponseWriter, r *http.Request) error {
+				return tt.err
+			})
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != tt.want.Status {
+				t.Errorf("status: got %d, want %d", rec.Code, tt.want.Status)
+			}
+			var got types.ApiError
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("body %q is not an ApiError: %v", rec.Body.String(), err)
+			}
+			if got != tt.want {
+				t.Errorf("body: got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"golang-secret-manager/api/inventory"
+	"golang-secret-manager/api/report"
+	"golang-secret-manager/types"
+	GenericEncoding "golang-secret-manager/utils/genericEncoding"
+	"io"
+	"log"
+	"net/http"
+)
+
+// Listing the saved snapshots (GET) or taking a snapshot now (POST), the
+// credential of the request is used or the inventory credential of the server
+func (h *Handler) InventorySnapshotsHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+	if h.app.Inventory == nil {
+		return &types.ApiError{Err: "the inventory is not enabled", Status: http.StatusServiceUnavailable}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		snapshots, err := h.app.Inventory.List()
+		if err != nil {
+			h.app.Logger.Println("HANDLER: failed to list the inventory snapshots:", err)
+			return &types.ApiError{Err: "failed to list the snapshots", Status: http.StatusInternalServerError}
+		}
+		if err := GenericEncoding.WriteJson(rw, http.StatusOK, types.InventorySnapshotList{Snapshots: snapshots}); err != nil {
+			log.Printf("failed to write json to client %v", err)
+		}
+		return nil
+
+	case http.MethodPost:
+		reqBody, err := GenericEncoding.JsonBodyDecoder[types.InventorySnapshotRequest](r.Body)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return &types.ApiError{Err: "request body didn't matched!", Status: http.StatusBadRequest}
+		}
+		if reqBody == nil {
+			reqBody = &types.InventorySnapshotRequest{}
+		}
+		snapshot, apiErr := h.TakeInventory(r.Context(), reqBody.PublicKey, reqBody.SecretKey, reqBody.Region, types.InventoryTriggerManual)
+		if apiErr != nil {
+			return apiErr
+		}
+		if err := h.app.Inventory.Save(snapshot); err != nil {
+			h.app.Logger.Println("HANDLER: failed to save the inventory snapshot:", err)
+			return &types.ApiError{Err: "failed to save the snapshot", Status: http.StatusInternalServerError}
+		}
+		h.app.Logger.Println("HANDLER: saved inventory snapshot", snapshot.ID, "of", len(snapshot.Secrets), "secrets")
+		if err := GenericEncoding.WriteJson(rw, http.StatusOK, inventory.Info(*snapshot)); err != nil {
+			log.Printf("failed to write json to client %v", err)
+		}
+		return nil
+	}
+	return &types.ApiError{Err: "method not allowed", Status: http.StatusMethodNotAllowed}
+}
+
+// Comparing two snapshots, or a snapshot and the live secrets. The request is
+// sent as a JSON body (POST) or as the from and to query parameters (GET), and
+// ?format=text returns the CLI report
+func (h *Handler) InventoryDiffHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+	if h.app.Inventory == nil {
+		return &types.ApiError{Err: "the inventory is not enabled", Status: http.StatusServiceUnavailable}
+	}
+
+	query := r.URL.Query()
+	var request types.InventoryDiffRequest
+	switch r.Method {
+	case http.MethodGet:
+		request.From = query.Get("from")
+		request.To = query.Get("to")
+	case http.MethodPost:
+		reqBody, err := GenericEncoding.JsonBodyDecoder[types.InventoryDiffRequest](r.Body)
+		if err != nil {
+			return &types.ApiError{Err: "request body didn't matched!", Status: http.StatusBadRequest}
+		}
+		request = *reqBody
+	default:
+		return &types.ApiError{Err: "method not allowed", Status: http.StatusMethodNotAllowed}
+	}
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "text" {
+		return &types.ApiError{Err: "unknown format: " + format, Status: http.StatusBadRequest}
+	}
+	if request.From == "" {
+		return &types.ApiError{Err: "the snapshot to compare from is missing", Status: http.StatusBadRequest}
+	}
+
+	from, apiErr := h.ResolveInventory(request.From)
+	if apiErr != nil {
+		return apiErr
+	}
+	var to *types.InventorySnapshot
+	if request.To == "" || request.To == types.InventoryTriggerLive {
+		to, apiErr = h.TakeInventory(r.Context(), request.PublicKey, request.SecretKey, request.Region, types.InventoryTriggerLive)
+		if apiErr == nil {
+			to.ID = types.InventoryTriggerLive
+		}
+	} else {
+		to, apiErr = h.ResolveInventory(request.To)
+	}
+	if apiErr != nil {
+		return apiErr
+	}
+
+	diff := inventory.Diff(*from, *to)
+	if format == "text" {
+		rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		rw.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(rw, report.InventoryDiffText(diff)); err != nil {
+			log.Printf("failed to write report to client %v", err)
+		}
+		return nil
+	}
+	if err := GenericEncoding.WriteJson(rw, http.StatusOK, diff); err != nil {
+		log.Printf("failed to write json to client %v", err)
+	}
+	return nil
+}
+
+func (h *Handler) ResolveInventory(ref string) (*types.InventorySnapshot, *types.ApiError) {
+	snapshot, err := h.app.Inventory.Resolve(ref)
+	if errors.Is(err, inventory.ErrSnapshotNotFound) {
+		return nil, &types.ApiError{Err: "no snapshot matches: " + ref, Status: http.StatusNotFound}
+	} else if err != nil {
+		h.app.Logger.Println("HANDLER: failed to load the inventory snapshot:", ref, err)
+		return nil, &types.ApiError{Err: "failed to load the snapshot", Status: http.StatusInternalServerError}
+	}
+	return snapshot, nil
+}
+
+// Crawling the secrets with the credential, the inventory credential of the
+// server is used when the request has none
+func (h *Handler) TakeInventory(ctx context.Context, publicKey string, secretKey string, region string, trigger string) (*types.InventorySnapshot, *types.ApiError) {
+	if publicKey == "" && secretKey == "" {
+		cfg := h.app.Config
+		publicKey, secretKey = cfg.InventoryPublicKey, cfg.InventorySecretKey
+		if region == "" {
+			region = cfg.InventoryRegion
+		}
+	}
+	if publicKey == "" || secretKey == "" {
+		return nil, &types.ApiError{Err: "no credential was sent and the server has no inventory credential", Status: http.StatusBadRequest}
+	}
+	snapshot, err := inventory.Take(ctx, h.app.AWS, publicKey, secretKey, region, trigger)
+	if err != nil {
+		h.app.Logger.Println("HANDLER: failed to crawl the secrets for the inventory:", err)
+		return nil, &types.ApiError{Err: "Error while trying to retrive all Secrets + Access Logs", Status: http.StatusInternalServerError}
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"errors"
+	"golang-secret-manager/api/crawler"
+	"golang-secret-manager/types"
+	GenericEncoding "golang-secret-manager/utils/genericEncoding"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// Listing the crawl jobs from the newest with the schedule of each profile
+// (GET, ?status=, ?profile= and ?limit= filter them, ?id= returns one job), or
+// starting a crawl now (POST)
+func (h *Handler) JobsHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		if id := query.Get("id"); id != "" {
+			job, found := h.app.Crawler.Job(id)
+			if !found {
+				return &types.ApiError{Err: "job not found: " + id, Status: http.StatusNotFound}
+			}
+			if err := GenericEncoding.WriteJson(rw, http.StatusOK, job); err != nil {
+				log.Printf("failed to write json to client %v", err)
+			}
+			return nil
+		}
+
+		limit := 0
+		if value := query.Get("limit"); value != "" {
+			num, err := strconv.Atoi(value)
+			if err != nil || num < 0 {
+				return &types.ApiError{Err: "invalid limit: " + value, Status: http.StatusBadRequest}
+			}
+			limit = num
+		}
+		list := h.app.Crawler.Jobs(query.Get("status"), query.Get("profile"), limit)
+		if err := GenericEncoding.WriteJson(rw, http.StatusOK, list); err != nil {
+			log.Printf("failed to write json to client %v", err)
+		}
+		return nil
+
+	case http.MethodPost:
+		reqBody, err := GenericEncoding.JsonBodyDecoder[types.CrawlRequest](r.Body)
+		if err != nil {
+			return &types.ApiError{Err: "request body didn't matched!", Status: http.StatusBadRequest}
+		}
+		jobs, err := h.app.Crawler.Trigger(*reqBody)
+		if errors.Is(err, crawler.ErrUnknownProfile) {
+			return &types.ApiError{Err: err.Error(), Status: http.StatusNotFound}
+		} else if errors.Is(err, crawler.ErrStopped) {
+			return &types.ApiError{Err: err.Error(), Status: http.StatusServiceUnavailable}
+		} else if err != nil {
+			return &types.ApiError{Err: err.Error(), Status: http.StatusBadRequest}
+		}
+		h.app.Logger.Println("HANDLER: started", len(jobs), "crawl jobs")
+		if err := GenericEncoding.WriteJson(rw, http.StatusOK, types.CrawlResponse{Jobs: jobs}); err != nil {
+			log.Printf("failed to write json to client %v", err)
+		}
+		return nil
+	}
+	return &types.ApiError{Err: "method not allowed", Status: http.StatusMethodNotAllowed}
+}
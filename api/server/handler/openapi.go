@@ -0,0 +1,21 @@
+package handler
+
+import (
+	"golang-secret-manager/api/openapi"
+	"golang-secret-manager/types"
+	GenericEncoding "golang-secret-manager/utils/genericEncoding"
+	"log"
+	"net/http"
+)
+
+// Returning the OpenAPI document of the HTTP API
+func (h *Handler) GetOpenAPIHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+	if r.Method != http.MethodGet {
+		return &types.ApiError{Err: "method not allowed", Status: http.StatusMethodNotAllowed}
+	}
+	if err := GenericEncoding.WriteJson(rw, http.StatusOK, openapi.Spec()); err != nil {
+		log.Printf("failed to write json to client %v", err)
+	}
+	return nil
+}
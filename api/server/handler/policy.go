@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"golang-secret-manager/api/policy"
+	"golang-secret-manager/types"
+	GenericEncoding "golang-secret-manager/utils/genericEncoding"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Checking all the secrets of the credential against the policy of the
+// request, or the policy file of the server. The report is returned with
+// 200 also when there are violations, the caller decides what to do with them
+func (h *Handler) CheckPolicyHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+	reqBody, err := GenericEncoding.JsonBodyDecoder[types.PolicyCheckRequest](r.Body)
+	if err != nil {
+		return &types.ApiError{Err: "request body didn't matched!", Status: http.StatusBadRequest}
+	}
+
+	var rules *policy.Policy
+	switch {
+	case reqBody.Policy != "":
+		rules, err = policy.Parse([]byte(reqBody.Policy))
+	case h.app.Config.PolicyFile != "":
+		rules, err = policy.Load(h.app.Config.PolicyFile)
+	default:
+		return &types.ApiError{Err: "no policy was sent and the server has no policy file", Status: http.StatusBadRequest}
+	}
+	if err != nil {
+		return &types.ApiError{Err: err.Error(), Status: http.StatusBadRequest}
+	}
+
+	all, err := h.app.AWS.RetriveAllSecretsWithAccessLog(r.Context(), reqBody.PublicKey, reqBody.SecretKey, reqBody.Region)
+	if err != nil {
+		h.app.Logger.Println("HANDLER: failed to crawl the secrets for the policy check:", err)
+		return &types.ApiError{Err: "Error while trying to retrive all Secrets + Access Logs", Status: http.StatusInternalServerError}
+	}
+
+	report := rules.Check(all.Secrets, all.AccessLog, time.Now())
+	h.app.Logger.Printf("HANDLER: policy check of %d rules on %d secrets, violations: %d", report.Rules, report.Secrets, report.Failed)
+	if err := GenericEncoding.WriteJson(rw, http.StatusOK, report); err != nil {
+		log.Printf("failed to write json to client %v", err)
+	}
+	return nil
+}
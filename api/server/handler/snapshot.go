@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"golang-secret-manager/types"
+	GenericEncoding "golang-secret-manager/utils/genericEncoding"
+	"golang-secret-manager/utils/storage"
+	"log"
+	"net/http"
+)
+
+// The passphrase of an encrypted snapshot is passed in a header so it won't
+// be written to the access logs with the url
+const snapshotPassphraseHeader = "X-Snapshot-Passphrase"
+
+// Largest snapshot that can be imported
+const maxSnapshotSize = 512 << 20
+
+// Exporting the whole cache as a snapshot archive
+func (h *Handler) ExportCacheHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+
+	// the changed keys are saved first so the persist layer holds everything,
+	// reading it won't fill the memory with the keys that were evicted
+	var source storage.ICache = h.app.Cache
+	if cache, ok := h.app.Cache.(inspectableCache); ok {
+		if _, err := cache.Flush(); err != nil {
+			h.app.Logger.Println("HANDLER: failed to flush the cache before export:", err)
+			return &types.ApiError{Err: "failed to flush the cache before export", Status: http.StatusInternalServerError}
+		}
+		if layer, ok := h.app.Cache.(interface{ Layer() storage.ICache }); ok && layer.Layer() != nil {
+			source = layer.Layer()
+		}
+	}
+
+	// the archive is built in memory so an error can still be returned
+	var buf bytes.Buffer
+	manifest, err := storage.ExportSnapshot(source, &buf, storage.SnapshotOptions{
+		Codec:      h.app.Codec,
+		Passphrase: r.Header.Get(snapshotPassphraseHeader),
+	})
+	if err != nil {
+		h.app.Logger.Println("HANDLER: failed to export the cache:", err)
+		return &types.ApiError{Err: "failed to export the cache", Status: http.StatusInternalServerError}
+	}
+	h.app.Logger.Println("HANDLER: exported", len(manifest.Entries), "cache entries")
+
+	fileName := fmt.Sprintf("cache-snapshot-%s.tar.gz", manifest.CreatedAt.Format("20060102-150405"))
+	rw.Header().Set("Content-Type", "application/gzip")
+	rw.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	rw.WriteHeader(http.StatusOK)
+	if _, err := buf.WriteTo(rw); err != nil {
+		log.Printf("failed to write snapshot to client %v", err)
+	}
+	return nil
+}
+
+// Importing a snapshot archive, ?mode=merge (default) keeps the keys that are
+// not in the snapshot and ?mode=replace removes them
+func (h *Handler) ImportCacheHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+	if r.Method != http.MethodPost {
+		return &types.ApiError{Err: "method not allowed", Status: http.StatusMethodNotAllowed}
+	}
+
+	report, err := storage.ImportSnapshot(h.app.Cache, http.MaxBytesReader(rw, r.Body, maxSnapshotSize), storage.SnapshotImportOptions{
+		Mode:       storage.SnapshotImportMode(r.URL.Query().Get("mode")),
+		Codec:      h.app.Codec,
+		Passphrase: r.Header.Get(snapshotPassphraseHeader),
+	})
+	if err != nil {
+		h.app.Logger.Println("HANDLER: failed to import the cache:", err)
+		if report.Imported == 0 && report.Removed == 0 {
+			// nothing was changed, the snapshot itself is not valid
+			return &types.ApiError{Err: "failed to import snapshot: " + err.Error(), Status: http.StatusBadRequest}
+		}
+		return &types.ApiError{Err: "snapshot was partly imported: " + err.Error(), Status: http.StatusInternalServerError}
+	}
+
+	// saving the imported keys to the persist layer now
+	if cache, ok := h.app.Cache.(inspectableCache); ok {
+		if _, err := cache.Flush(); err != nil {
+			h.app.Logger.Println("HANDLER: failed to flush the imported keys:", err)
+		}
+	}
+	h.app.Logger.Println("HANDLER: imported", report.Imported, "cache entries, removed", report.Removed, "skipped", report.Expired, "expired")
+
+	if err := GenericEncoding.WriteJson(rw, http.StatusOK, report); err != nil {
+		log.Printf("failed to write json to client %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"golang-secret-manager/api/stream"
+	"golang-secret-manager/types"
+	"net/http"
+	"time"
+)
+
+// The comment that keeps the idle streams open through the proxies
+const streamHeartbeat = 15 * time.Second
+
+// Streaming the accesses and the changes of the secrets as server-sent events
+// (?arn=, ?tag=key=value and ?event= filter them). A client that reconnects
+// with the Last-Event-ID header (or ?last_event_id=) gets the events it missed
+// while they are kept
+func (h *Handler) StreamEventsHandler(rw http.ResponseWriter, r *http.Request) error {
+	defer r.Body.Close()
+	if r.Method != http.MethodGet {
+		return &types.ApiError{Err: "method not allowed", Status: http.StatusMethodNotAllowed}
+	}
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		return &types.ApiError{Err: "streaming is not supported", Status: http.StatusInternalServerError}
+	}
+	filter, err := stream.ParseFilter(r.URL.Query())
+	if err != nil {
+		return &types.ApiError{Err: err.Error(), Status: http.StatusBadRequest}
+	}
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+
+	sub, backlog := h.app.Stream.Subscribe(filter, lastID)
+	defer h.app.Stream.Unsubscribe(sub)
+
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.Header().Set("Connection", "keep-alive")
+	rw.Header().Set("X-Accel-Buffering", "no")
+	rw.WriteHeader(http.StatusOK)
+	// the client reconnects after 3s when the stream is closed
+	fmt.Fprint(rw, "retry: 3000\n\n")
+	for _, event := range backlog {
+		if err := writeStreamEvent(rw, event); err != nil {
+			return nil
+		}
+	}
+	flusher.Flush()
+	h.app.Logger.Println("HANDLER: stream client connected from", r.RemoteAddr)
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			h.app.Logger.Println("HANDLER: stream client disconnected from", r.RemoteAddr)
+			return nil
+		case event, open := <-sub.Events:
+			if !open {
+				if sub.Lagged {
+					h.app.Logger.Println("HANDLER: stream client", r.RemoteAddr, "fell behind, disconnected")
+				}
+				return nil
+			}
+			if err := writeStreamEvent(rw, event); err != nil {
+				return nil
+			}
+			flusher.Flush()
+		case now := <-heartbeat.C:
+			if _, err := fmt.Fprintf(rw, ": heartbeat %s\n\n", now.UTC().Format(time.RFC3339)); err != nil {
+				return nil
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeStreamEvent(rw http.ResponseWriter, event types.StreamEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(rw, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"golang-secret-manager/types"
+	GenericEncoding "golang-secret-manager/utils/genericEncoding"
+	"net/http"
+	"strings"
+)
+
+// Checking the admin token of the request, when no token is configured the
+// admin endpoints are refusing every request
+func (m *Middleware) AdminMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if !m.AdminEnabled() {
+			m.app.Logger.Println("MIDDILEWARE: admin request to", r.URL.Path, "refused, no admin token is configured")
+			GenericEncoding.WriteJson(rw, http.StatusForbidden, types.ApiError{Err: "admin endpoints are disabled, ADMIN_TOKEN is not set", Status: http.StatusForbidden})
+			return
+		}
+		if !m.IsAdmin(r.Header.Get("Authorization")) {
+			m.app.Logger.Println("MIDDILEWARE: unauthorized admin request to", r.URL.Path)
+			GenericEncoding.WriteJson(rw, http.StatusUnauthorized, types.ApiError{Err: "invalid admin token", Status: http.StatusUnauthorized})
+			return
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// The admin endpoints are only served when a token is configured
+func (m *Middleware) AdminEnabled() bool {
+	return m.app.Config.AdminToken != ""
+}
+
+// Checking the "Bearer <token>" authorization of a request (the header or
+// the gRPC metadata), always false when no token is configured
+func (m *Middleware) IsAdmin(authorization string) bool {
+	token := m.app.Config.AdminToken
+	if token == "" {
+		return false
+	}
+	given, found := strings.CutPrefix(authorization, "Bearer ")
+	return found && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
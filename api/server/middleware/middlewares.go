@@ -2,16 +2,26 @@ package middleware
 
 import (
 	"context"
-	"fmt"
+	"golang-secret-manager/api/app"
 	"golang-secret-manager/api/aws"
+	"golang-secret-manager/api/report"
 	"golang-secret-manager/types"
 	GenericEncoding "golang-secret-manager/utils/genericEncoding"
 	"golang-secret-manager/utils/storage"
 	"net/http"
 )
 
+// The middlewares are using the cache and the logger of the app
+type Middleware struct {
+	app *app.App
+}
+
+func New(a *app.App) *Middleware {
+	return &Middleware{app: a}
+}
+
 // Before handling the request checking if the value of the secrets in the cache
-func GetAllSecretsMiddleware(next http.HandlerFunc) http.HandlerFunc {
+func (m *Middleware) GetAllSecretsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 		// Decoding the request body
 		defer r.Body.Close()
@@ -21,98 +31,98 @@ func GetAllSecretsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		ctx := r.Context()
-
-		// retriving the cache instance
-		cacheInstance := storage.GetCacheInstance()
+		toContext, cached := m.LookupAllSecrets(*reqBody)
+		if cached != nil {
+			// returning the value from the cache if all the value was in cache
+			if err := GenericEncoding.WriteJson(rw, http.StatusOK, cached); err != nil {
+				m.app.Logger.Println("MIDDILEWARE: failed to send back to client information")
+			}
+			return
+		}
 
-		// extraction publicKey from body
-		publicKey := reqBody.PublicKey
+		// Calling handler
+		ctx := context.WithValue(r.Context(), types.GetContextInforamtionKey(), toContext)
+		next.ServeHTTP(rw, r.WithContext(ctx))
+	})
+}
 
-		// Structure that will be pass to the handler
-		toContext := types.FromGetAllSecretsMiddlewareToHandler{
-			FoundedAccessLog: nil,
-			FoundedSecrets:   nil,
-			ArnList:          nil,
-			FoundedArnList:   false,
-			PublicKey:        publicKey,
-			SecretKey:        reqBody.SecretKey,
-			Region:           reqBody.Region,
+// Looking up the secrets of the credential in the cache, the response is
+// returned when all of them were cached. Otherwise what was found is returned
+// for the handler to retrive the missing ones (the gRPC server is using it
+// too)
+func (m *Middleware) LookupAllSecrets(reqBody types.GetAllSecretsRequest) (*types.FromGetAllSecretsMiddlewareToHandler, *types.GetAllSecretsResponse) {
+	// extraction publicKey from body
+	publicKey := reqBody.PublicKey
+
+	// Structure that will be pass to the handler
+	toContext := types.FromGetAllSecretsMiddlewareToHandler{
+		FoundedAccessLog: nil,
+		FoundedSecrets:   nil,
+		ArnList:          nil,
+		FoundedArnList:   false,
+		PublicKey:        publicKey,
+		SecretKey:        reqBody.SecretKey,
+		Region:           reqBody.Region,
+	}
+
+	// checking if user ARN list in cache
+	KeyArn := aws.GetCacheARNKey(publicKey)
+	arnList, err := m.app.AWS.ARNLists.Get(KeyArn)
+	if err != nil {
+		// was not found in cache, the handler retrives everything
+		m.logCacheMiss(err, "User", publicKey, "ARN list")
+		return &toContext, nil
+	}
+
+	allFound := true
+	foundedSecrets := make(map[string]types.Secret)
+	for _, arn := range arnList {
+		key := aws.GetCacheSecretKey(arn)
+		val, err := m.app.AWS.Secrets.Get(key)
+		if err != nil {
+			// not in cache
+			m.logCacheMiss(err, "Secret id:", arn, "secret")
+			allFound = false
+			continue
 		}
+		foundedSecrets[arn] = val
+	}
 
-		// checking if user ARN list in cache
-		KeyArn := aws.GetCacheARNKey(publicKey)
-		contextKey := types.GetContextInforamtionKey()
-		arnList, err := storage.GetCacheValue[[]string](cacheInstance, KeyArn)
+	foundedAccessLog := make(map[string][]types.AccessLog)
+	for _, arn := range arnList {
+		key := aws.GetCacheAccessKey(arn)
+		val, err := m.app.AWS.AccessLogs.Get(key)
 		if err != nil {
-			// was not found in cache, calling to next function
-			fmt.Println("MIDDILEWARE: User", publicKey, "ARN list was not found in the cache")
-			// Setting the pass by value to the context
-			ctx := context.WithValue(ctx, contextKey, &toContext)
-			next.ServeHTTP(rw, r.WithContext(ctx))
-			return
+			// not in cache
+			m.logCacheMiss(err, "Secret id:", arn, "Access log")
+			allFound = false
+			continue
 		}
+		foundedAccessLog[arn] = val
+	}
 
-		allFound := true
-		foundedSecrets := make(map[string]types.Secret)
-		for _, arn := range *arnList {
-			key := aws.GetCacheSecretKey(arn)
-			val, err := storage.GetCacheValue[types.Secret](cacheInstance, key)
-			if err != nil {
-				// not in cache
-				allFound = false
-				continue
-			}
-			foundedSecrets[arn] = *val
-		}
+	if allFound {
+		var secretList []types.Secret
 
-		foundedAccessLog := make(map[string][]types.AccessLog)
-		for _, arn := range *arnList {
-			key := aws.GetCacheAccessKey(arn)
-			val, err := storage.GetCacheValue[[]types.AccessLog](cacheInstance, key)
-			if err != nil {
-				// not in cache
-				allFound = false
-				continue
-			}
-			foundedAccessLog[arn] = *val
+		for _, value := range foundedSecrets {
+			secretList = append(secretList, value)
 		}
 
-		if allFound {
-			// returning the value from the cache if all the value was in cache
-			// sending back to the client the anwser
-
-			var secretList []types.Secret
-
-			for _, value := range foundedSecrets {
-				secretList = append(secretList, value)
-			}
-
-			toSend := types.GetAllSecretsResponse{
-				Secrets:   secretList,
-				AccessLog: foundedAccessLog,
-			}
-
-			if err := GenericEncoding.WriteJson(rw, http.StatusOK, toSend); err != nil {
-				fmt.Println("MIDDILEWARE: failed to send back to client information")
-			}
-			return
+		return &toContext, &types.GetAllSecretsResponse{
+			Secrets:   secretList,
+			AccessLog: foundedAccessLog,
 		}
+	}
 
-		toContext.FoundedAccessLog = foundedAccessLog
-		toContext.FoundedSecrets = foundedSecrets
-		toContext.FoundedArnList = true
-		toContext.ArnList = *arnList
-
-		ctx = context.WithValue(ctx, contextKey, &toContext)
-
-		// Calling handler
-		next.ServeHTTP(rw, r.WithContext(ctx))
-	})
+	toContext.FoundedAccessLog = foundedAccessLog
+	toContext.FoundedSecrets = foundedSecrets
+	toContext.FoundedArnList = true
+	toContext.ArnList = arnList
+	return &toContext, nil
 }
 
 // Before handling the request checking if the Secret already in cache for fast access
-func GetReportMiddleware(next http.HandlerFunc) http.HandlerFunc {
+func (m *Middleware) GetReportMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
 		reqBody, err := GenericEncoding.JsonBodyDecoder[types.GetReportRequest](r.Body)
@@ -121,54 +131,80 @@ func GetReportMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		ctx := r.Context()
-
-		// retriving the cache instance
-		cacheInstance := storage.GetCacheInstance()
-
-		keyForSecret := aws.GetCacheSecretKey(reqBody.SecretID)
-		keyForAccessLog := aws.GetCacheAccessKey(reqBody.SecretID)
-
-		allFound := true
-
-		toContext := types.FromGetReportMiddlewareToHandler{
-			FoundedSecret:    nil,
-			FoundedAccessLog: nil,
-			PublicKey:        reqBody.PublicKey,
-			SecretKey:        reqBody.SecretKey,
-			SecretID:         reqBody.SecretID,
-			Region:           reqBody.Region,
-		}
-
-		if secret, err := storage.GetCacheValue[types.Secret](cacheInstance, keyForSecret); err != nil {
-			// secret not in memory
-			fmt.Println("MIDDILEWARE: Secret id:", reqBody.SecretID, "not in cache")
-			allFound = false
-		} else {
-			toContext.FoundedSecret = secret
-		}
-
-		if access, err := storage.GetCacheValue[[]types.AccessLog](cacheInstance, keyForAccessLog); err != nil {
-			fmt.Println("MIDDILEWARE: Secret id:", reqBody.SecretID, "Access log was not in cache")
-			allFound = false
-		} else {
-			toContext.FoundedAccessLog = *access
-		}
-
-		if !allFound {
-			// need to call the handler to retrive the missing information
-			contextKey := types.GetContextInforamtionKey()
-			ctx = context.WithValue(ctx, contextKey, &toContext)
-			next.ServeHTTP(rw, r.WithContext(ctx))
-		} else {
+		toContext, cached := m.LookupReport(*reqBody)
+		if cached != nil {
 			// sending to the user the report
-			report := aws.GenerateReportStringBySecret(*toContext.FoundedSecret, toContext.FoundedAccessLog)
-			toReturn := types.GetReportResponse{
-				Report: report,
-			}
-			if err := GenericEncoding.WriteJson(rw, http.StatusOK, toReturn); err != nil {
-				fmt.Println("MIDDILEWARE: failed to send back to client information")
+			if err := report.Respond(rw, r, *cached); err != nil {
+				m.app.Logger.Println("MIDDILEWARE: failed to send back to client information")
 			}
+			return
 		}
+
+		// need to call the handler to retrive the missing information
+		ctx := context.WithValue(r.Context(), types.GetContextInforamtionKey(), toContext)
+		next.ServeHTTP(rw, r.WithContext(ctx))
 	})
 }
+
+// Looking up the secret and its access log in the cache, the report is
+// returned when both were cached
+func (m *Middleware) LookupReport(reqBody types.GetReportRequest) (*types.FromGetReportMiddlewareToHandler, *types.SecretReport) {
+	keyForAccessLog := aws.GetCacheAccessKey(reqBody.SecretID)
+
+	allFound := true
+
+	toContext := types.FromGetReportMiddlewareToHandler{
+		FoundedSecret:    nil,
+		FoundedAccessLog: nil,
+		PublicKey:        reqBody.PublicKey,
+		SecretKey:        reqBody.SecretKey,
+		SecretID:         reqBody.SecretID,
+		Region:           reqBody.Region,
+		Filter:           reqBody.Filter,
+	}
+
+	if secret := m.LookupSecret(reqBody.SecretID); secret == nil {
+		allFound = false
+	} else {
+		toContext.FoundedSecret = secret
+	}
+
+	if access, err := m.app.AWS.AccessLogs.Get(keyForAccessLog); err != nil {
+		m.logCacheMiss(err, "Secret id:", reqBody.SecretID, "Access log")
+		allFound = false
+	} else {
+		toContext.FoundedAccessLog = aws.FilterAccessLogs(access, reqBody.Filter)
+	}
+
+	if !allFound {
+		return &toContext, nil
+	}
+	m.app.Logger.Println("MIDDILEWARE: Generating Report For", toContext.FoundedSecret.ARN)
+	secretReport := report.Build(*toContext.FoundedSecret, toContext.FoundedAccessLog, toContext.Filter)
+	return &toContext, &secretReport
+}
+
+// The cached secret, nil when it isn't cached
+func (m *Middleware) LookupSecret(secretID string) *types.Secret {
+	secret, err := m.app.AWS.Secrets.Get(aws.GetCacheSecretKey(secretID))
+	if err != nil {
+		// secret not in memory
+		m.logCacheMiss(err, "Secret id:", secretID, "secret")
+		return nil
+	}
+	return &secret
+}
+
+// A missing key is normal, a key that can't be decoded means the cache entry
+// is corrupted and is logged as such
+func (m *Middleware) logCacheMiss(err error, kind string, id string, what string) {
+	if storage.IsDecodeError(err) {
+		m.app.Logger.Println("MIDDILEWARE:", kind, id, what, "is corrupted in the cache:", err)
+		return
+	}
+	if !storage.IsNotFound(err) {
+		m.app.Logger.Println("MIDDILEWARE:", kind, id, what, "failed to read from the cache:", err)
+		return
+	}
+	m.app.Logger.Println("MIDDILEWARE:", kind, id, what, "was not found in the cache")
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"golang-secret-manager/types"
+	GenericEncoding "golang-secret-manager/utils/genericEncoding"
+	"net"
+	"net/http"
+)
+
+// Limiting the requests of each client address, the gRPC requests are
+// counted in the same budget
+func (m *Middleware) RateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		client := ClientAddr(r.RemoteAddr)
+		if !m.app.Limiter.Allow(client) {
+			m.app.Logger.Println("MIDDILEWARE: rate limited", client, "on", r.URL.Path)
+			rw.Header().Set("Retry-After", "1")
+			GenericEncoding.WriteJson(rw, http.StatusTooManyRequests, types.ApiError{Err: "too many requests", Status: http.StatusTooManyRequests})
+			return
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// The host of the remote address, the port changes between the connections
+// of a client
+func ClientAddr(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
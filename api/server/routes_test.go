@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"golang-secret-manager/api/app"
+	"golang-secret-manager/api/openapi"
+	"golang-secret-manager/api/server/config"
+	"golang-secret-manager/utils/storage"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestRouter(t *testing.T) *http.ServeMux {
+	t.Helper()
+	cache := storage.CreateFastCache(storage.FastCacheConfig{Expiration: time.Minute})
+	a := app.New(config.Config{AdminToken: "test-token"}, cache, storage.JSONCodec, nil, log.New(io.Discard, "", 0))
+	return NewRouter(context.Background(), a)
+}
+
+// Every operation of the document is registered by the server, and the
+// admin operations are refusing a request without the token
+func TestRoutesMatchDocument(t *testing.T) {
+	mux := newTestRouter(t)
+
+	for path, item := range openapi.Spec().Paths {
+		operations := map[string]*openapi.Operation{http.MethodGet: item.Get, http.MethodPost: item.Post}
+		for method, operation := range operations {
+			if operation == nil {
+				continue
+			}
+			t.Run(method+" "+path, func(t *testing.T) {
+				// the requests have no body, the routes that are not behind
+				// the token are refusing them before reaching AWS
+				req := httptest.NewRequest(method, path, nil)
+				if _, pattern := mux.Handler(req); pattern != path {
+					t.Fatalf("the server serves it with the pattern %q", pattern)
+				}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, req)
+				admin := len(operation.Security) > 0
+				switch {
+				case admin && rec.Code != http.StatusUnauthorized:
+					t.Errorf("the document has it behind the admin token, a request without it got %d", rec.Code)
+				case !admin && (rec.Code == http.StatusUnauthorized || rec.Code == http.StatusForbidden):
+					t.Errorf("the document has it open, a request without the admin token got %d", rec.Code)
+				}
+			})
+		}
+	}
+}
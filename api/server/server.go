@@ -2,45 +2,137 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"golang-secret-manager/api/app"
+	"golang-secret-manager/api/aws"
+	"golang-secret-manager/api/inventory"
+	"golang-secret-manager/api/notify"
+	"golang-secret-manager/api/rpc"
+	"golang-secret-manager/api/server/config"
 	"golang-secret-manager/api/server/handler"
 	"golang-secret-manager/api/server/middleware"
+	"golang-secret-manager/types"
+	"golang-secret-manager/utils/dirqueue"
+	"golang-secret-manager/utils/resp"
 	"golang-secret-manager/utils/storage"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+
+	"google.golang.org/grpc"
 )
 
 type HttpServer struct {
 	ctx    context.Context
+	app    *app.App
 	server *http.Server
 }
 
-func NewHttpServer(addr string, ctx context.Context) *HttpServer {
+func NewHttpServer(addr string, ctx context.Context, a *app.App) *HttpServer {
 	return &HttpServer{
 		ctx: ctx,
+		app: a,
 		server: &http.Server{
 			Addr: addr,
 		},
 	}
 }
 
-func (s *HttpServer) Start() error {
-	// Loading Routes
-	http.HandleFunc("/secrets", func(w http.ResponseWriter, r *http.Request) {
+// Registering the routes on a mux of this server only, with the rate limit
+// in front of them
+func (s *HttpServer) routes() http.Handler {
+	return middleware.New(s.app).RateLimitMiddleware(NewRouter(s.ctx, s.app))
+}
+
+// Building the mux of the routes, the requests that are not streams are
+// served with the context of the server
+func NewRouter(ctx context.Context, a *app.App) *http.ServeMux {
+	mux := http.NewServeMux()
+	m := middleware.New(a)
+	h := handler.New(a)
+
+	mux.HandleFunc("/secrets", func(w http.ResponseWriter, r *http.Request) {
 		// applying middileware
-		middleware.GetAllSecretsMiddleware(handler.MakeHTTPHandleFuncDecoder(handler.GetAllSecretsHandlers))(w, r.WithContext(s.ctx))
+		m.GetAllSecretsMiddleware(handler.MakeHTTPHandleFuncDecoder(h.GetAllSecretsHandlers))(w, r.WithContext(ctx))
 	})
 
-	http.HandleFunc("/reports", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/reports", func(w http.ResponseWriter, r *http.Request) {
 		// applying middileware
-		middleware.GetReportMiddleware(handler.MakeHTTPHandleFuncDecoder(handler.GetReportsHandler))(w, r.WithContext(s.ctx))
+		m.GetReportMiddleware(handler.MakeHTTPHandleFuncDecoder(h.GetReportsHandler))(w, r.WithContext(ctx))
+	})
+
+	mux.HandleFunc("/access-logs", func(w http.ResponseWriter, r *http.Request) {
+		handler.MakeHTTPHandleFuncDecoder(h.GetAccessLogHandler)(w, r.WithContext(ctx))
+	})
+
+	mux.HandleFunc("/hygiene", func(w http.ResponseWriter, r *http.Request) {
+		handler.MakeHTTPHandleFuncDecoder(h.GetHygieneReportHandler)(w, r.WithContext(ctx))
+	})
+
+	mux.HandleFunc("/v1/policy/check", func(w http.ResponseWriter, r *http.Request) {
+		handler.MakeHTTPHandleFuncDecoder(h.CheckPolicyHandler)(w, r.WithContext(ctx))
+	})
+
+	// the counters are telling how the cache is used, so it is an admin
+	// endpoint like the rest of the cache
+	mux.HandleFunc("/metrics", m.AdminMiddleware(handler.MakeHTTPHandleFuncDecoder(h.GetMetricsHandler)))
+	// the report lists the cache keys, so it is an admin endpoint
+	mux.HandleFunc("/cache/verify", m.AdminMiddleware(handler.MakeHTTPHandleFuncDecoder(h.VerifyCacheHandler)))
+
+	// the OpenAPI document of these routes, TestRoutesMatchDocument fails
+	// when its routes are not served like it describes
+	mux.HandleFunc("/openapi.json", handler.MakeHTTPHandleFuncDecoder(h.GetOpenAPIHandler))
+
+	// analysis of the cached access logs, it reads the logs of every
+	// credential so it is an admin endpoint
+	mux.HandleFunc("/v1/anomalies", m.AdminMiddleware(handler.MakeHTTPHandleFuncDecoder(h.GetAnomaliesHandler)))
+
+	// snapshots of the secrets inventory and the diff between them
+	mux.HandleFunc("/v1/inventory/snapshots", func(w http.ResponseWriter, r *http.Request) {
+		m.AdminMiddleware(handler.MakeHTTPHandleFuncDecoder(h.InventorySnapshotsHandler))(w, r.WithContext(ctx))
 	})
+	mux.HandleFunc("/v1/inventory/diff", func(w http.ResponseWriter, r *http.Request) {
+		m.AdminMiddleware(handler.MakeHTTPHandleFuncDecoder(h.InventoryDiffHandler))(w, r.WithContext(ctx))
+	})
+
+	// the accesses and the changes of the secrets as they are found, the
+	// request context is kept so a client that leaves is noticed
+	mux.HandleFunc("/v1/events", m.AdminMiddleware(handler.MakeHTTPHandleFuncDecoder(h.StreamEventsHandler)))
 
-	log.Println("SERVER: Starting Server on port", s.server.Addr)
-	return http.ListenAndServe(s.server.Addr, nil)
+	// crawl jobs of the background crawler, POST starts a crawl now
+	mux.HandleFunc("/v1/jobs", m.AdminMiddleware(handler.MakeHTTPHandleFuncDecoder(h.JobsHandler)))
+
+	// CloudTrail events that are changing the cached secrets
+	mux.HandleFunc("/admin/events", m.AdminMiddleware(handler.MakeHTTPHandleFuncDecoder(h.IngestEventsHandler)))
+
+	// admin endpoints of the cache
+	mux.HandleFunc("/admin/cache/keys", m.AdminMiddleware(handler.MakeHTTPHandleFuncDecoder(h.GetCacheKeysHandler)))
+	mux.HandleFunc("/admin/cache/entry", m.AdminMiddleware(handler.MakeHTTPHandleFuncDecoder(h.GetCacheEntryHandler)))
+	mux.HandleFunc("/admin/cache/invalidate", m.AdminMiddleware(handler.MakeHTTPHandleFuncDecoder(h.InvalidateCacheHandler)))
+	mux.HandleFunc("/admin/cache/flush", m.AdminMiddleware(handler.MakeHTTPHandleFuncDecoder(h.FlushCacheHandler)))
+	mux.HandleFunc("/admin/cache/evictions", m.AdminMiddleware(handler.MakeHTTPHandleFuncDecoder(h.PreviewEvictionsHandler)))
+	mux.HandleFunc("/admin/cache/migrations", m.AdminMiddleware(handler.MakeHTTPHandleFuncDecoder(h.GetCacheMigrationsHandler)))
+	mux.HandleFunc("/admin/cache/export", m.AdminMiddleware(handler.MakeHTTPHandleFuncDecoder(h.ExportCacheHandler)))
+	mux.HandleFunc("/admin/cache/import", m.AdminMiddleware(handler.MakeHTTPHandleFuncDecoder(h.ImportCacheHandler)))
+	return mux
+}
+
+func (s *HttpServer) Start() error {
+	// Loading Routes
+	s.server.Handler = s.routes()
+	// the open streams would hold the shutdown until its timeout
+	s.server.RegisterOnShutdown(s.app.Stream.Close)
+
+	s.app.Logger.Println("SERVER: Starting Server on port", s.server.Addr)
+	err := s.server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
 }
 
 func (s *HttpServer) ShutDown() {
@@ -54,24 +146,219 @@ func (s *HttpServer) ShutDown() {
 	}
 }
 
+// Creating the persist layer of the selected backend
+func setupPersistLayer(cfg config.Config) (storage.ICache, error) {
+	var layer storage.ICache
+	switch cfg.CacheBackend {
+	case config.BackendBolt:
+		boltCache, err := storage.NewBoltCache(storage.BoltCacheConfig{Path: cfg.BoltPath})
+		if err != nil {
+			return nil, err
+		}
+		if cfg.BoltCompact {
+			if err := boltCache.Compact(); err != nil {
+				log.Println("SERVER:", err)
+			}
+		}
+		layer = boltCache
+	case config.BackendRedis:
+		addr := cfg.RedisAddr
+		if cfg.RedisStandIn {
+			// in-process server for running without a real redis
+			standIn, err := resp.NewServer("127.0.0.1:0")
+			if err != nil {
+				return nil, err
+			}
+			addr = standIn.Addr()
+			log.Println("SERVER: started in-process redis stand-in on", addr)
+		}
+		redisCache, err := storage.NewRedisCache(storage.RedisCacheConfig{
+			Addr:       addr,
+			Prefix:     cfg.RedisPrefix,
+			DefaultTTL: cfg.CacheExpiration,
+		})
+		if err != nil {
+			return nil, err
+		}
+		// the shared cache can't be encrypted, ApplyEncryption refuses it
+		// when the encryption is enabled
+		layer = redisCache
+	case config.BackendFiles, "":
+		persistCache, err := storage.CreatePersistCache(cfg.PersistCacheDir)
+		if err != nil {
+			return nil, err
+		}
+		layer = persistCache
+	default:
+		return nil, fmt.Errorf("unknown cache backend: %s", cfg.CacheBackend)
+	}
+
+	if err := config.ApplyEncryption(layer, cfg.Encryption); err != nil {
+		if !storage.IsReencryptError(err) {
+			// never writing the secrets in plain text when the
+			// encryption was asked for
+			return nil, fmt.Errorf("cache encryption: %v", err)
+		}
+		// the entries that can't be re-encrypted are still readable by
+		// the keys that were loaded
+		log.Println("SERVER: cache encryption:", err)
+	}
+	return layer, nil
+}
+
 func main() {
+	cfg := config.Load()
+
 	// Setting up the cache system
-	// persist cache
-	persistCache := storage.NewPersistCache("./persist-cache/")
+	// persist layer
+	persistLayer, err := setupPersistLayer(cfg)
+	if err != nil {
+		log.Fatalln("failed to init persist cache:", err)
+	}
+
+	// the values are kept decoded in memory and encoded with the codec only
+	// when they are saved to the persist layer
+	codec, err := storage.CodecByName(cfg.CacheCodec)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// the cached types are saved with their schema version, older entries are
+	// migrated when they are loaded
+	schemas := storage.NewSchemaRegistry()
+	if err := aws.RegisterSchemas(schemas); err != nil {
+		log.Fatalln("failed to register cache schemas:", err)
+	}
 
 	// FastCache
-	cacheDuration := 5 * time.Minute
-	fastCache := storage.NewFastCache(cacheDuration)
+	fastCache := storage.CreateFastCache(storage.FastCacheConfig{
+		Expiration: cfg.CacheExpiration,
+		MaxEntries: cfg.CacheMaxEntries,
+		MaxBytes:   cfg.CacheMaxBytes,
+		Codec:      codec,
+		Schemas:    schemas,
+	})
 
-	if err := fastCache.SetCacheLayer(persistCache, true); err != nil {
+	if err := fastCache.SetCacheLayer(persistLayer, true); err != nil {
 		log.Fatalln("failed to init fast cache")
 	}
-	fastCache.ActivateLayerSavingRuntime(20 * time.Second)
+	report := fastCache.MigrationReport()
+	log.Printf("SERVER: loaded %d cache entries, migrated: %d, failed: %d", report.Total, report.Migrated, len(report.Failed))
+	for key, reason := range report.Failed {
+		log.Println("SERVER: failed to migrate cache key:", key, reason)
+	}
+	fastCache.ActivateLayerSavingRuntime(cfg.CacheSaveInterval)
+
+	// the other replicas are telling us which keys they changed
+	if redisCache, ok := persistLayer.(*storage.RedisCache); ok {
+		if err := redisCache.SubscribeInvalidations(fastCache.Invalidate); err != nil {
+			log.Fatalln("failed to subscribe to cache invalidations:", err)
+		}
+	}
 	// End setting up cache system
 
+	// everything the handlers need is passed in the app
+	a := app.New(cfg, fastCache, codec, aws.NewAWSClient, log.Default())
+
+	// the events that are dropped into the directory are applied like the
+	// ones that are sent to /admin/events
+	if cfg.EventsDir != "" {
+		poller, err := dirqueue.NewPoller(cfg.EventsDir, cfg.EventsPollInterval, func(name string, data []byte) error {
+			events, err := aws.ParseCloudTrailEvents(data)
+			if err != nil {
+				return err
+			}
+			result := a.AWS.ApplyEvents(events)
+			if result.Failed > 0 {
+				return fmt.Errorf("failed to apply %d of %d events", result.Failed, result.Received)
+			}
+			return nil
+		}, a.Logger)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		poller.Start()
+		defer poller.Stop()
+		log.Println("SERVER: polling CloudTrail events from", cfg.EventsDir)
+	}
+
+	// each crawl is compared with the previous one of the account and region,
+	// the changes are streamed and notified
+	tracker := inventory.NewTracker()
+	var notifyCrawl func(job types.CrawlJob, all *types.AllSecretWithAccessLog, snapshot types.InventorySnapshot, diff *types.InventoryDiff)
+	a.Crawler.OnCrawl = func(job types.CrawlJob, all *types.AllSecretWithAccessLog) {
+		snapshot := inventory.FromSecrets(all.Secrets, job.Region, types.InventoryTriggerLive, time.Now())
+		diff := tracker.Update(job.Profile, snapshot)
+		if diff != nil {
+			a.Stream.PublishInventory(job.Profile, snapshot, *diff)
+		}
+		if notifyCrawl != nil {
+			notifyCrawl(job, all, snapshot, diff)
+		}
+	}
+
+	// the changes and the findings of the crawls are sent to the webhooks
+	if cfg.NotifyFile != "" {
+		notifyConfig, err := notify.Load(cfg.NotifyFile)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		dispatcher := notify.NewDispatcher(notifyConfig, a.Logger)
+		dispatcher.Start()
+		defer dispatcher.Stop()
+		watcher := notify.NewWatcher()
+		notifyCrawl = func(job types.CrawlJob, all *types.AllSecretWithAccessLog, snapshot types.InventorySnapshot, diff *types.InventoryDiff) {
+			notifications := watcher.Crawled(job.Profile, job.Region, all, snapshot, diff, time.Now())
+			if len(notifications) > 0 {
+				a.Logger.Println("SERVER: notifications of crawl", job.ID+":", notify.Summary(notifications))
+				dispatcher.Notify(notifications...)
+			}
+		}
+		log.Println("SERVER: sending notifications to", len(notifyConfig.Webhooks), "webhooks")
+	}
+
+	// the crawls of the configured profiles are keeping the cache warm
+	defer a.Crawler.Stop()
+	if len(cfg.Crawler.Profiles) > 0 {
+		a.Crawler.Start()
+		log.Printf("SERVER: crawling %d profiles every %s", len(cfg.Crawler.Profiles), cfg.Crawler.Interval)
+	}
+
+	a.Inventory, err = inventory.NewStore(cfg.InventoryDir, cfg.InventoryKeep)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if cfg.InventoryInterval > 0 && cfg.InventoryPublicKey != "" && cfg.InventorySecretKey != "" {
+		scheduler := inventory.NewScheduler(a.AWS, a.Inventory, cfg.InventoryInterval,
+			cfg.InventoryPublicKey, cfg.InventorySecretKey, cfg.InventoryRegion, a.Logger)
+		scheduler.Start()
+		defer scheduler.Stop()
+		log.Println("SERVER: taking inventory snapshots every", cfg.InventoryInterval, "to", cfg.InventoryDir)
+	}
+
+	if cfg.AdminToken == "" {
+		log.Println("SERVER: ADMIN_TOKEN is not set, the admin endpoints are refusing every request")
+	}
+
 	ctx := context.Background()
 
-	httpServer := NewHttpServer(":8080", ctx)
+	httpServer := NewHttpServer(cfg.Addr, ctx, a)
+
+	// the gRPC API is served next to the HTTP one
+	var grpcServer *grpc.Server
+	if cfg.GrpcAddr != "" {
+		listener, err := net.Listen("tcp", cfg.GrpcAddr)
+		if err != nil {
+			log.Fatalln("failed to listen for gRPC:", err)
+		}
+		grpcServer = rpc.NewGRPCServer(ctx, a)
+		go func() {
+			log.Println("SERVER: Starting gRPC Server on", cfg.GrpcAddr)
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Println("SERVER: gRPC server stopped:", err)
+			}
+		}()
+	}
 
 	// Thread that handle the Ctrl + C signal
 	ch := make(chan os.Signal, 1)
@@ -80,11 +367,17 @@ func main() {
 		<-ch
 		// exiting program
 		log.Println("Shuting down server...")
+		// the streams are closed by the shutdown of the HTTP server, the
+		// gRPC calls are given the same time to finish
 		httpServer.ShutDown()
+		if grpcServer != nil {
+			timer := time.AfterFunc(5*time.Second, grpcServer.Stop)
+			grpcServer.GracefulStop()
+			timer.Stop()
+		}
 	}()
 
 	if err := httpServer.Start(); err != nil {
-		log.Println("SERVER: failed to create server")
+		log.Println("SERVER: failed to create server:", err)
 	}
-
 }
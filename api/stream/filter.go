@@ -0,0 +1,84 @@
+package stream
+
+import (
+	"fmt"
+	"golang-secret-manager/types"
+	"net/url"
+	"strings"
+)
+
+// Selecting the events of a client, the empty fields match every event. The
+// values of a field are alternatives, the fields must all match
+type Filter struct {
+	ARNs []string
+
+	// The secret must have every tag, "*" matches any value
+	Tags map[string]string
+
+	// The event name of the accesses (GetSecretValue, ...) or the type of
+	// the event (access_log, secret_created, ...)
+	Events []string
+}
+
+// Reading the filter from ?arn=, ?tag=key=value (or key for any value) and
+// ?event=, each can be repeated or separated by commas
+func ParseFilter(query url.Values) (Filter, error) {
+	filter := Filter{
+		ARNs:   values(query["arn"]),
+		Events: values(query["event"]),
+	}
+	for _, tag := range values(query["tag"]) {
+		key, value, found := strings.Cut(tag, "=")
+		if key == "" {
+			return filter, fmt.Errorf("invalid tag filter: %s", tag)
+		}
+		if !found {
+			value = "*"
+		}
+		if filter.Tags == nil {
+			filter.Tags = make(map[string]string)
+		}
+		filter.Tags[key] = value
+	}
+	return filter, nil
+}
+
+func values(raw []string) []string {
+	var list []string
+	for _, value := range raw {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				list = append(list, part)
+			}
+		}
+	}
+	return list
+}
+
+func (f Filter) Match(event types.StreamEvent) bool {
+	if event.Type == types.StreamGap {
+		return true
+	}
+	if len(f.ARNs) > 0 && !contains(f.ARNs, event.SecretARN) {
+		return false
+	}
+	if len(f.Events) > 0 && !contains(f.Events, event.Type) && (event.EventName == "" || !contains(f.Events, event.EventName)) {
+		return false
+	}
+	for key, value := range f.Tags {
+		actual, found := event.Tags[key]
+		if !found || (value != "*" && value != actual) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+package stream
+
+import (
+	"golang-secret-manager/types"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Events that are kept for the clients that resume with Last-Event-ID
+	DefaultBufferSize = 1000
+
+	// Events that wait for a client, a client that falls further behind is
+	// disconnected and resumes from the buffer when it reconnects
+	subscriberBuffer = 256
+
+	// Ids of the access log events that were published, the sync finds again
+	// the events that were applied from CloudTrail
+	seenSize = 10000
+)
+
+// Hub fans out the events of the secrets to the clients of the stream. The
+// events are numbered from 1 when the server starts and the last ones are
+// kept so a client that reconnects gets what it missed
+type Hub struct {
+	size int
+
+	mu          sync.Mutex
+	seq         uint64
+	events      []types.StreamEvent
+	subscribers map[*Subscription]struct{}
+	seen        map[string]bool
+	seenOrder   []string
+	closed      bool
+}
+
+// A client of the stream, Events is closed when the client falls behind or
+// the hub is closed
+type Subscription struct {
+	Events chan types.StreamEvent
+	Filter Filter
+
+	// The client was disconnected because it fell behind
+	Lagged bool
+}
+
+func NewHub(size int) *Hub {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	return &Hub{
+		size:        size,
+		subscribers: make(map[*Subscription]struct{}),
+		seen:        make(map[string]bool),
+	}
+}
+
+// Numbering the events, keeping them and sending them to the clients whose
+// filter they match
+func (h *Hub) Publish(events ...types.StreamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	for _, event := range events {
+		h.seq++
+		event.ID = h.seq
+		h.events = append(h.events, event)
+		if len(h.events) > h.size {
+			h.events = h.events[len(h.events)-h.size:]
+		}
+		for sub := range h.subscribers {
+			if !sub.Filter.Match(event) {
+				continue
+			}
+			select {
+			case sub.Events <- event:
+			default:
+				sub.Lagged = true
+				h.remove(sub)
+			}
+		}
+	}
+}
+
+// Publishing the access log events of the secret that were not published yet
+func (h *Hub) PublishAccessLogs(secret types.Secret, accessLogs []types.AccessLog) {
+	var events []types.StreamEvent
+	h.mu.Lock()
+	for i := len(accessLogs) - 1; i >= 0; i-- {
+		accessLog := accessLogs[i]
+		id := accessLog.EventID
+		if id == "" {
+			id = accessLog.EventTime.UTC().Format(time.RFC3339Nano) + "|" + accessLog.EventName + "|" + accessLog.User
+		}
+		id = secret.ARN + "|" + id
+		if h.seen[id] {
+			continue
+		}
+		h.seen[id] = true
+		h.seenOrder = append(h.seenOrder, id)
+		events = append(events, types.StreamEvent{
+			Type:       types.StreamAccessLog,
+			Time:       accessLog.EventTime.UTC(),
+			SecretARN:  secret.ARN,
+			SecretName: secret.Name,
+			Tags:       secret.Tags,
+			EventName:  accessLog.EventName,
+			AccessLog:  &accessLog,
+		})
+	}
+	if len(h.seenOrder) > seenSize {
+		for _, id := range h.seenOrder[:len(h.seenOrder)-seenSize] {
+			delete(h.seen, id)
+		}
+		h.seenOrder = append([]string{}, h.seenOrder[len(h.seenOrder)-seenSize:]...)
+	}
+	h.mu.Unlock()
+
+	// the sync returns the newest event first, the stream is oldest first
+	h.Publish(events...)
+}
+
+// Publishing the changes of the secrets that a crawl found
+func (h *Hub) PublishInventory(profile string, snapshot types.InventorySnapshot, diff types.InventoryDiff) {
+	var events []types.StreamEvent
+	event := func(kind string, secret types.InventorySecret) types.StreamEvent {
+		return types.StreamEvent{
+			Type:       kind,
+			Time:       snapshot.TakenAt,
+			SecretARN:  secret.ARN,
+			SecretName: secret.Name,
+			Tags:       secret.Tags,
+			Profile:    profile,
+			Region:     snapshot.Region,
+		}
+	}
+	for i := range diff.Added {
+		added := event(types.StreamSecretCreated, diff.Added[i])
+		added.Secret = &diff.Added[i]
+		events = append(events, added)
+	}
+	for i := range diff.Removed {
+		removed := event(types.StreamSecretDeleted, diff.Removed[i])
+		removed.Secret = &diff.Removed[i]
+		events = append(events, removed)
+	}
+	current := make(map[string]types.InventorySecret, len(snapshot.Secrets))
+	for _, secret := range snapshot.Secrets {
+		current[secret.ARN] = secret
+	}
+	for _, change := range diff.Changed {
+		secret := current[change.ARN]
+		changed := event(types.StreamSecretChanged, secret)
+		changed.Secret = &secret
+		changed.Changes = change.Changes
+		events = append(events, changed)
+	}
+	h.Publish(events...)
+}
+
+// Adding a client, the kept events after lastID that match the filter are
+// returned to be sent first. An empty lastID is a new client that gets only
+// the new events
+func (h *Hub) Subscribe(filter Filter, lastID string) (*Subscription, []types.StreamEvent) {
+	sub := &Subscription{
+		Events: make(chan types.StreamEvent, subscriberBuffer),
+		Filter: filter,
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(sub.Events)
+		return sub, nil
+	}
+	h.subscribers[sub] = struct{}{}
+
+	last, err := strconv.ParseUint(lastID, 10, 64)
+	if lastID == "" || err != nil {
+		return sub, nil
+	}
+
+	var backlog []types.StreamEvent
+	oldest := h.seq + 1
+	if len(h.events) > 0 {
+		oldest = h.events[0].ID
+	}
+	// the ids start over when the server restarts, a larger id is of the
+	// previous run. The gap moves the client to the oldest kept event
+	if last > h.seq || last+1 < oldest {
+		backlog = append(backlog, types.StreamEvent{
+			ID:      oldest - 1,
+			Type:    types.StreamGap,
+			Time:    time.Now().UTC(),
+			Message: "the events after " + lastID + " are no longer kept, some events were missed",
+		})
+		last = 0
+	}
+	for _, event := range h.events {
+		if event.ID > last && filter.Match(event) {
+			backlog = append(backlog, event)
+		}
+	}
+	return sub, backlog
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(sub)
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	if _, found := h.subscribers[sub]; found {
+		delete(h.subscribers, sub)
+		close(sub.Events)
+	}
+}
+
+// Disconnecting the clients, the server doesn't wait for the streams when it
+// shuts down
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subscribers {
+		h.remove(sub)
+	}
+}
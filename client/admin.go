@@ -0,0 +1,252 @@
+package client
+
+import (
+	"context"
+	"golang-secret-manager/types"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// The routes of this file are behind the admin token of the server
+
+// Header of the passphrase of an encrypted snapshot
+const snapshotPassphraseHeader = "X-Snapshot-Passphrase"
+
+// The counters of the cache
+func (c *Client) GetMetrics(ctx context.Context) (*types.GetMetricsResponse, error) {
+	var response types.GetMetricsResponse
+	if err := c.getJSON(ctx, "/metrics", nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Checking that every entry of the cache can be read and decrypted, the
+// report is returned also when some of the entries failed
+func (c *Client) VerifyCache(ctx context.Context) (*types.CacheVerifyReport, error) {
+	var response types.CacheVerifyReport
+	body, err := c.do(ctx, request{method: http.MethodGet, path: "/cache/verify", accept: []int{http.StatusConflict}})
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeJSON(body, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// The keys of the cache, only the ones of the namespace (secret, access,
+// acsync, arnlst) when it is not empty
+func (c *Client) GetCacheKeys(ctx context.Context, namespace string) (*types.GetCacheKeysResponse, error) {
+	query := url.Values{}
+	if namespace != "" {
+		query.Set("namespace", namespace)
+	}
+	var response types.GetCacheKeysResponse
+	if err := c.getJSON(ctx, "/admin/cache/keys", query, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// A single entry of the cache with its age and dirty flag
+func (c *Client) GetCacheEntry(ctx context.Context, key string) (*types.CacheEntryInfo, error) {
+	var response types.CacheEntryInfo
+	if err := c.getJSON(ctx, "/admin/cache/entry", url.Values{"key": {key}}, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Removing a single secret or everything of a credential from the cache
+func (c *Client) InvalidateCache(ctx context.Context, request types.CacheInvalidateRequest) (*types.CacheInvalidateResponse, error) {
+	var response types.CacheInvalidateResponse
+	if err := c.postJSON(ctx, "/admin/cache/invalidate", nil, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Saving the changed keys of the cache to the persist layer now
+func (c *Client) FlushCache(ctx context.Context) (*types.CacheFlushResponse, error) {
+	var response types.CacheFlushResponse
+	body, err := c.do(ctx, request{method: http.MethodPost, path: "/admin/cache/flush"})
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeJSON(body, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// The keys that would be evicted with the budget, zero is the budget of the
+// server
+func (c *Client) PreviewEvictions(ctx context.Context, maxEntries int) (*types.CacheEvictionPreviewResponse, error) {
+	query := url.Values{}
+	if maxEntries > 0 {
+		query.Set("max_entries", strconv.Itoa(maxEntries))
+	}
+	var response types.CacheEvictionPreviewResponse
+	if err := c.getJSON(ctx, "/admin/cache/evictions", query, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// The entries that couldn't be migrated to the current schema
+func (c *Client) GetCacheMigrations(ctx context.Context) (*types.CacheMigrationReport, error) {
+	var response types.CacheMigrationReport
+	if err := c.getJSON(ctx, "/admin/cache/migrations", nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Writing a snapshot of the whole cache to w, it is encrypted when the
+// passphrase is not empty. Returning the number of bytes that were written
+func (c *Client) ExportCache(ctx context.Context, w io.Writer, passphrase string) (int64, error) {
+	header := http.Header{}
+	if passphrase != "" {
+		header.Set(snapshotPassphraseHeader, passphrase)
+	}
+	body, err := c.stream(ctx, request{method: http.MethodGet, path: "/admin/cache/export", header: header})
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	return io.Copy(w, body)
+}
+
+// Loading a snapshot that was exported, mode is merge or replace
+func (c *Client) ImportCache(ctx context.Context, snapshot io.Reader, mode string, passphrase string) (*types.CacheImportReport, error) {
+	payload, err := io.ReadAll(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	if passphrase != "" {
+		header.Set(snapshotPassphraseHeader, passphrase)
+	}
+	body, err := c.do(ctx, request{
+		method:      http.MethodPost,
+		path:        "/admin/cache/import",
+		query:       url.Values{"mode": {mode}},
+		payload:     payload,
+		contentType: "application/gzip",
+		header:      header,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var response types.CacheImportReport
+	if err := decodeJSON(body, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Applying CloudTrail events to the cache, a CloudTrail log file, an
+// EventBridge event or a list of records
+func (c *Client) ApplyEvents(ctx context.Context, events io.Reader) (*types.EventIngestResponse, error) {
+	payload, err := io.ReadAll(events)
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.do(ctx, request{method: http.MethodPost, path: "/admin/events", payload: payload})
+	if err != nil {
+		return nil, err
+	}
+	var response types.EventIngestResponse
+	if err := decodeJSON(body, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// The cached access logs of the secrets checked against their baseline,
+// rendered in the format (text or json). All the secrets when arns is empty
+func (c *Client) RenderAnomalies(ctx context.Context, arns []string, window string, format string) ([]byte, error) {
+	query := url.Values{"arn": arns, "format": {format}}
+	if window != "" {
+		query.Set("window", window)
+	}
+	return c.do(ctx, request{method: http.MethodGet, path: "/v1/anomalies", query: query})
+}
+
+// The saved snapshots of the secrets inventory
+func (c *Client) GetInventorySnapshots(ctx context.Context) (*types.InventorySnapshotList, error) {
+	var response types.InventorySnapshotList
+	if err := c.getJSON(ctx, "/v1/inventory/snapshots", nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Saving a snapshot of the secrets of the credential now
+func (c *Client) TakeInventorySnapshot(ctx context.Context, request types.InventorySnapshotRequest) (*types.InventorySnapshotInfo, error) {
+	var response types.InventorySnapshotInfo
+	if err := c.postJSON(ctx, "/v1/inventory/snapshots", nil, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// The secrets that were added, removed and changed between two snapshots,
+// rendered in the format (text or json)
+func (c *Client) RenderInventoryDiff(ctx context.Context, request types.InventoryDiffRequest, format string) ([]byte, error) {
+	return c.postRaw(ctx, "/v1/inventory/diff", url.Values{"format": {format}}, request)
+}
+
+// The crawl jobs and the schedule of the profiles, the empty filters and a
+// limit of zero are not applied
+func (c *Client) GetJobs(ctx context.Context, status string, profile string, limit int) (*types.JobList, error) {
+	query := url.Values{}
+	if status != "" {
+		query.Set("status", status)
+	}
+	if profile != "" {
+		query.Set("profile", profile)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var response types.JobList
+	if err := c.getJSON(ctx, "/v1/jobs", query, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// A single crawl job
+func (c *Client) GetJob(ctx context.Context, id string) (*types.CrawlJob, error) {
+	var response types.CrawlJob
+	if err := c.getJSON(ctx, "/v1/jobs", url.Values{"id": {id}}, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Crawling a profile of the server now
+func (c *Client) Crawl(ctx context.Context, request types.CrawlRequest) (*types.CrawlResponse, error) {
+	var response types.CrawlResponse
+	if err := c.postJSON(ctx, "/v1/jobs", nil, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Opening the server-sent events stream of the secrets, the events after
+// lastEventID are sent first when it is not empty. The stream is open until
+// the context is done or the server closes it
+func (c *Client) StreamEvents(ctx context.Context, arns []string, tags []string, events []string, lastEventID string) (io.ReadCloser, error) {
+	header := http.Header{}
+	header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		header.Set("Last-Event-ID", lastEventID)
+	}
+	query := url.Values{"arn": arns, "tag": tags, "event": events}
+	return c.stream(ctx, request{method: http.MethodGet, path: "/v1/events", query: query, header: header})
+}
@@ -0,0 +1,319 @@
+// Package client is the Go client of the HTTP API of the server, the request
+// and response bodies are the ones of the types package that the OpenAPI
+// document at /openapi.json is derived from
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"golang-secret-manager/types"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Crawling all the secrets of a credential with their access logs can
+	// take minutes
+	DefaultTimeout = 5 * time.Minute
+	DefaultRetries = 2
+	DefaultBackoff = 500 * time.Millisecond
+
+	// Longest wait between two tries
+	maxBackoff = 10 * time.Second
+
+	// Largest error body that is read
+	maxErrorBody = 64 << 10
+)
+
+type Client struct {
+	// http://localhost:8080, the paths of the routes are added to it
+	BaseURL string
+
+	// Sent with every request, only the admin routes are checking it
+	AdminToken string
+
+	// Its Timeout is the timeout of a single try
+	HTTPClient *http.Client
+
+	// Tries after the first one of the GET requests that failed to reach the
+	// server or were answered with 429, 502, 503 or 504. The other requests
+	// may have been handled before they failed, they are retried only on 429
+	Retries int
+
+	// Wait before the first retry, it is doubled for each retry. A
+	// Retry-After of the server is used instead
+	Backoff time.Duration
+}
+
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    baseURL,
+		HTTPClient: &http.Client{Timeout: DefaultTimeout},
+		Retries:    DefaultRetries,
+		Backoff:    DefaultBackoff,
+	}
+}
+
+// All the secrets of the credential with their access logs
+func (c *Client) GetAllSecrets(ctx context.Context, request types.GetAllSecretsRequest) (*types.GetAllSecretsResponse, error) {
+	var response types.GetAllSecretsResponse
+	if err := c.postJSON(ctx, "/secrets", nil, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// The report of a single secret with the text report of the older clients
+// and the structured one
+func (c *Client) GetReport(ctx context.Context, request types.GetReportRequest) (*types.GetReportResponse, error) {
+	var response types.GetReportResponse
+	if err := c.postJSON(ctx, "/reports", nil, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// The report of a single secret rendered by the server in the format (text,
+// json, markdown, html or csv)
+func (c *Client) RenderReport(ctx context.Context, request types.GetReportRequest, format string) ([]byte, error) {
+	return c.postRaw(ctx, "/reports", url.Values{"format": {format}}, request)
+}
+
+// The access log of a single secret, only the events of the filter
+func (c *Client) GetAccessLog(ctx context.Context, request types.GetAccessLogRequest) (*types.GetAccessLogResponse, error) {
+	var response types.GetAccessLogResponse
+	if err := c.postJSON(ctx, "/access-logs", nil, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// The hygiene findings of all the secrets of the credential
+func (c *Client) GetHygieneReport(ctx context.Context, request types.HygieneRequest) (*types.HygieneReport, error) {
+	var response types.HygieneReport
+	if err := c.postJSON(ctx, "/hygiene", nil, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// The hygiene findings rendered by the server in the format (text, json or
+// html)
+func (c *Client) RenderHygieneReport(ctx context.Context, request types.HygieneRequest, format string) ([]byte, error) {
+	return c.postRaw(ctx, "/hygiene", url.Values{"format": {format}}, request)
+}
+
+// Checking the secrets of the credential against the policy of the request,
+// or the policy file of the server when it has none
+func (c *Client) CheckPolicy(ctx context.Context, request types.PolicyCheckRequest) (*types.PolicyReport, error) {
+	var response types.PolicyReport
+	if err := c.postJSON(ctx, "/v1/policy/check", nil, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// A request of the client, the payload is kept so it can be sent again
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	payload     []byte
+	contentType string
+	header      http.Header
+
+	// statuses other than 200 that are answered with the response body
+	accept []int
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, response interface{}) error {
+	body, err := c.do(ctx, request{method: http.MethodGet, path: path, query: query})
+	if err != nil {
+		return err
+	}
+	return decodeJSON(body, response)
+}
+
+func (c *Client) postJSON(ctx context.Context, path string, query url.Values, request interface{}, response interface{}) error {
+	body, err := c.postRaw(ctx, path, query, request)
+	if err != nil {
+		return err
+	}
+	return decodeJSON(body, response)
+}
+
+func (c *Client) postRaw(ctx context.Context, path string, query url.Values, body interface{}) ([]byte, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(ctx, request{method: http.MethodPost, path: path, query: query, payload: payload})
+}
+
+func decodeJSON(body []byte, response interface{}) error {
+	if err := json.Unmarshal(body, response); err != nil {
+		return &DecodeError{Err: err}
+	}
+	return nil
+}
+
+// Sending the request until it is answered with 200 or it can't be retried,
+// returning the body of the response
+func (c *Client) do(ctx context.Context, req request) ([]byte, error) {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	var body []byte
+	err := c.retry(ctx, req.method, func() (time.Duration, error) {
+		res, retryAfter, err := c.try(ctx, httpClient, req)
+		if err != nil {
+			return retryAfter, err
+		}
+		defer res.Body.Close()
+		if body, err = io.ReadAll(res.Body); err != nil {
+			// the connection was lost while reading, the request can be
+			// sent again
+			return 0, &RequestError{Method: req.method, URL: c.url(req), Err: err}
+		}
+		return 0, nil
+	})
+	return body, err
+}
+
+// Like do, returning the body of the response open. The stream is read
+// until the context is done, the timeout of the http client is not used
+func (c *Client) stream(ctx context.Context, req request) (io.ReadCloser, error) {
+	httpClient := http.Client{}
+	if c.HTTPClient != nil {
+		httpClient = *c.HTTPClient
+	}
+	httpClient.Timeout = 0
+	var res *http.Response
+	err := c.retry(ctx, req.method, func() (time.Duration, error) {
+		var retryAfter time.Duration
+		var err error
+		res, retryAfter, err = c.try(ctx, &httpClient, req)
+		return retryAfter, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+// Calling send until it succeeds or its error can't be retried, waiting the
+// time it returned or the backoff between the tries
+func (c *Client) retry(ctx context.Context, method string, send func() (time.Duration, error)) error {
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := send()
+		if err == nil {
+			return nil
+		}
+		if attempt >= c.Retries || !retryable(ctx, method, err) {
+			return err
+		}
+
+		wait := retryAfter
+		if wait <= 0 {
+			wait = c.Backoff << attempt
+		}
+		if wait > maxBackoff || wait < 0 {
+			wait = maxBackoff
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) url(req request) string {
+	route := strings.TrimRight(c.BaseURL, "/") + req.path
+	if len(req.query) > 0 {
+		route += "?" + req.query.Encode()
+	}
+	return route
+}
+
+// A single try of the request, returning the response with its body open
+// or the wait the server asked for when it refused it
+func (c *Client) try(ctx context.Context, httpClient *http.Client, req request) (*http.Response, time.Duration, error) {
+	var reader io.Reader
+	if req.payload != nil {
+		reader = bytes.NewReader(req.payload)
+	}
+	route := c.url(req)
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, route, reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	for name, values := range req.header {
+		httpReq.Header[name] = values
+	}
+	if req.payload != nil {
+		contentType := req.contentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	if c.AdminToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.AdminToken)
+	}
+
+	res, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, 0, &RequestError{Method: req.method, URL: route, Err: err}
+	}
+	if res.StatusCode == http.StatusOK || slices.Contains(req.accept, res.StatusCode) {
+		return res, 0, nil
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+	return nil, retryAfter(res.Header.Get("Retry-After")), decodeError(res.StatusCode, body)
+}
+
+// The statuses of a server that is busy or restarting are retried, unless
+// the context is done. A request that failed on the way or was answered by
+// a proxy may have been handled already, so only the GET and HEAD requests
+// are retried then, the others only when the rate limit refused them
+func retryable(ctx context.Context, method string, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if StatusCode(err) == http.StatusTooManyRequests {
+		return true
+	}
+	if method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+	var requestErr *RequestError
+	if errors.As(err, &requestErr) {
+		return true
+	}
+	switch StatusCode(err) {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Only the seconds form of Retry-After is sent by the server
+func retryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"golang-secret-manager/types"
+	GenericEncoding "golang-secret-manager/utils/genericEncoding"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// A server that answers the tries of a route with the statuses in order, a
+// status of zero drops the connection. The last status is repeated
+func newTestServer(t *testing.T, statuses ...int) (*Client, *atomic.Int32) {
+	t.Helper()
+	var tries atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		try := int(tries.Add(1)) - 1
+		status := statuses[min(try, len(statuses)-1)]
+		if status == 0 {
+			conn, _, err := rw.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Errorf("Hijack: %v", err)
+				return
+			}
+			conn.Close()
+			return
+		}
+		if status == http.StatusOK {
+			GenericEncoding.WriteJson(rw, status, struct{}{})
+			return
+		}
+		GenericEncoding.WriteJson(rw, status, types.ApiError{Err: http.StatusText(status), Status: status})
+	}))
+	t.Cleanup(server.Close)
+
+	api := New(server.URL)
+	api.Backoff = time.Millisecond
+	return api, &tries
+}
+
+func TestRetries(t *testing.T) {
+	get := func(api *Client) error {
+		_, err := api.GetMetrics(context.Background())
+		return err
+	}
+	post := func(api *Client) error {
+		_, err := api.GetAllSecrets(context.Background(), types.GetAllSecretsRequest{})
+		return err
+	}
+
+	tests := []struct {
+		name      string
+		send      func(api *Client) error
+		statuses  []int
+		wantTries int32
+		wantErr   bool
+	}{
+		{name: "get succeeds after a server error", send: get, statuses: []int{http.StatusServiceUnavailable, http.StatusOK}, wantTries: 2},
+		{name: "get retries a dropped connection", send: get, statuses: []int{0, 0, http.StatusOK}, wantTries: 3},
+		{name: "get gives up after the retries", send: get, statuses: []int{http.StatusBadGateway}, wantTries: DefaultRetries + 1, wantErr: true},
+		{name: "get doesn't retry a bad request", send: get, statuses: []int{http.StatusBadRequest}, wantTries: 1, wantErr: true},
+		{name: "post doesn't retry a dropped connection", send: post, statuses: []int{0, http.StatusOK}, wantTries: 1, wantErr: true},
+		{name: "post doesn't retry a server error", send: post, statuses: []int{http.StatusServiceUnavailable, http.StatusOK}, wantTries: 1, wantErr: true},
+		{name: "post retries the rate limit", send: post, statuses: []int{http.StatusTooManyRequests, http.StatusOK}, wantTries: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, tries := newTestServer(t, tt.statuses...)
+			err := tt.send(api)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got := tries.Load(); got != tt.wantTries {
+				t.Errorf("got %d tries, want %d", got, tt.wantTries)
+			}
+		})
+	}
+}
+
+func TestRetryErrors(t *testing.T) {
+	api, _ := newTestServer(t, 0)
+	_, err := api.GetAllSecrets(context.Background(), types.GetAllSecretsRequest{})
+	var requestErr *RequestError
+	if !errors.As(err, &requestErr) {
+		t.Errorf("a dropped connection: got %T %v, want a RequestError", err, err)
+	}
+
+	api, _ = newTestServer(t, http.StatusTooManyRequests)
+	api.Retries = 1
+	if _, err := api.GetMetrics(context.Background()); !IsRateLimited(err) {
+		t.Errorf("the last status: got %v, want 429", err)
+	}
+}
+
+func TestRetryStopsWithTheContext(t *testing.T) {
+	api, tries := newTestServer(t, http.StatusServiceUnavailable)
+	api.Retries = 5
+	api.Backoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := api.GetMetrics(ctx); StatusCode(err) != http.StatusServiceUnavailable {
+		t.Errorf("got %v, want the 503 of the last try", err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("waited %v after the context was done", waited)
+	}
+	if got := tries.Load(); got != 1 {
+		t.Errorf("got %d tries, want 1", got)
+	}
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang-secret-manager/types"
+	"net/http"
+	"strings"
+)
+
+// The server answered with a status that is not 200
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("server responded with %d %s", e.Status, http.StatusText(e.Status))
+	}
+	return e.Message
+}
+
+// The request couldn't reach the server or the server didn't answer, after
+// all the retries
+type RequestError struct {
+	Method string
+	URL    string
+	Err    error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("error sending %s %s: %v", e.Method, e.URL, e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// The server answered 200 with a body that couldn't be decoded
+type DecodeError struct {
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("error decoding response: %v", e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Returning the status of the APIError in the chain, 0 when there is none
+func StatusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Status
+	}
+	return 0
+}
+
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+// The admin token is missing or wrong
+func IsUnauthorized(err error) bool {
+	status := StatusCode(err)
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
+
+func IsRateLimited(err error) bool {
+	return StatusCode(err) == http.StatusTooManyRequests
+}
+
+// Decoding the error body, the server is writing every error as a
+// types.ApiError
+func decodeError(status int, body []byte) *APIError {
+	apiErr := &APIError{Status: status}
+	var valErr types.ApiError
+	if err := json.Unmarshal(body, &valErr); err == nil && valErr.Err != "" {
+		apiErr.Message = valErr.Err
+		return apiErr
+	}
+	// not one of the errors of the server, a proxy in the middle maybe
+	apiErr.Message = strings.TrimSpace(string(body))
+	return apiErr
+}
@@ -0,0 +1,37 @@
+package command
+
+import "context"
+
+type AnomaliesCommand struct {
+	BaseURL    string
+	AdminToken string
+	ARNs       []string
+	Window     string
+
+	// text or json
+	Format   string
+	Rendered []byte
+}
+
+func CreateAnomaliesCommand(BaseURL string, AdminToken string, ARNs []string, Window string, Format string) *AnomaliesCommand {
+	return &AnomaliesCommand{
+		BaseURL:    BaseURL,
+		AdminToken: AdminToken,
+		ARNs:       ARNs,
+		Window:     Window,
+		Format:     Format,
+	}
+}
+
+func (s *AnomaliesCommand) Execute() error {
+	format := s.Format
+	if format == "" {
+		format = "text"
+	}
+	rendered, err := adminClient(s.BaseURL, s.AdminToken).RenderAnomalies(context.Background(), s.ARNs, s.Window, format)
+	if err != nil {
+		return err
+	}
+	s.Rendered = rendered
+	return nil
+}
@@ -0,0 +1,278 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"golang-secret-manager/types"
+	"os"
+)
+
+type VerifyCacheCommand struct {
+	BaseURL    string
+	AdminToken string
+	Response   types.CacheVerifyReport
+}
+
+func CreateVerifyCacheCommand(BaseURL string, AdminToken string) *VerifyCacheCommand {
+	return &VerifyCacheCommand{
+		BaseURL:    BaseURL,
+		AdminToken: AdminToken,
+	}
+}
+
+func (s *VerifyCacheCommand) Execute() error {
+	response, err := adminClient(s.BaseURL, s.AdminToken).VerifyCache(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to verify cache: %v", err)
+	}
+	s.Response = *response
+	if !response.OK {
+		return fmt.Errorf("%d of %d cache entries can't be decrypted", len(response.Failed), response.Total)
+	}
+	return nil
+}
+
+type CacheKeysCommand struct {
+	BaseURL    string
+	AdminToken string
+	Namespace  string
+	Response   types.GetCacheKeysResponse
+}
+
+func CreateCacheKeysCommand(BaseURL string, AdminToken string, Namespace string) *CacheKeysCommand {
+	return &CacheKeysCommand{
+		BaseURL:    BaseURL,
+		AdminToken: AdminToken,
+		Namespace:  Namespace,
+	}
+}
+
+func (s *CacheKeysCommand) Execute() error {
+	response, err := adminClient(s.BaseURL, s.AdminToken).GetCacheKeys(context.Background(), s.Namespace)
+	if err != nil {
+		return err
+	}
+	s.Response = *response
+	return nil
+}
+
+type CacheEntryCommand struct {
+	BaseURL    string
+	AdminToken string
+	Key        string
+	Response   types.CacheEntryInfo
+}
+
+func CreateCacheEntryCommand(BaseURL string, AdminToken string, Key string) *CacheEntryCommand {
+	return &CacheEntryCommand{
+		BaseURL:    BaseURL,
+		AdminToken: AdminToken,
+		Key:        Key,
+	}
+}
+
+func (s *CacheEntryCommand) Execute() error {
+	response, err := adminClient(s.BaseURL, s.AdminToken).GetCacheEntry(context.Background(), s.Key)
+	if err != nil {
+		return err
+	}
+	s.Response = *response
+	return nil
+}
+
+type InvalidateCacheCommand struct {
+	BaseURL    string
+	AdminToken string
+	Request    types.CacheInvalidateRequest
+	Response   types.CacheInvalidateResponse
+}
+
+// Only one of ARN and PublicKey should be set
+func CreateInvalidateCacheCommand(BaseURL string, AdminToken string, ARN string, PublicKey string, DryRun bool) *InvalidateCacheCommand {
+	return &InvalidateCacheCommand{
+		BaseURL:    BaseURL,
+		AdminToken: AdminToken,
+		Request: types.CacheInvalidateRequest{
+			ARN:       ARN,
+			PublicKey: PublicKey,
+			DryRun:    DryRun,
+		},
+	}
+}
+
+func (s *InvalidateCacheCommand) Execute() error {
+	response, err := adminClient(s.BaseURL, s.AdminToken).InvalidateCache(context.Background(), s.Request)
+	if err != nil {
+		return err
+	}
+	s.Response = *response
+	return nil
+}
+
+type FlushCacheCommand struct {
+	BaseURL    string
+	AdminToken string
+	Response   types.CacheFlushResponse
+}
+
+func CreateFlushCacheCommand(BaseURL string, AdminToken string) *FlushCacheCommand {
+	return &FlushCacheCommand{
+		BaseURL:    BaseURL,
+		AdminToken: AdminToken,
+	}
+}
+
+func (s *FlushCacheCommand) Execute() error {
+	response, err := adminClient(s.BaseURL, s.AdminToken).FlushCache(context.Background())
+	if err != nil {
+		return err
+	}
+	s.Response = *response
+	return nil
+}
+
+type PreviewEvictionsCommand struct {
+	BaseURL    string
+	AdminToken string
+	MaxEntries int
+	Response   types.CacheEvictionPreviewResponse
+}
+
+// MaxEntries of zero is previewing the budget of the server
+func CreatePreviewEvictionsCommand(BaseURL string, AdminToken string, MaxEntries int) *PreviewEvictionsCommand {
+	return &PreviewEvictionsCommand{
+		BaseURL:    BaseURL,
+		AdminToken: AdminToken,
+		MaxEntries: MaxEntries,
+	}
+}
+
+func (s *PreviewEvictionsCommand) Execute() error {
+	response, err := adminClient(s.BaseURL, s.AdminToken).PreviewEvictions(context.Background(), s.MaxEntries)
+	if err != nil {
+		return err
+	}
+	s.Response = *response
+	return nil
+}
+
+type ExportCacheCommand struct {
+	BaseURL    string
+	AdminToken string
+	Path       string
+	Passphrase string
+	Written    int64
+}
+
+// The snapshot is encrypted when the passphrase is not empty
+func CreateExportCacheCommand(BaseURL string, AdminToken string, Path string, Passphrase string) *ExportCacheCommand {
+	return &ExportCacheCommand{
+		BaseURL:    BaseURL,
+		AdminToken: AdminToken,
+		Path:       Path,
+		Passphrase: Passphrase,
+	}
+}
+
+func (s *ExportCacheCommand) Execute() error {
+	file, err := os.OpenFile(s.Path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("error creating file: %v", err)
+	}
+	written, err := adminClient(s.BaseURL, s.AdminToken).ExportCache(context.Background(), file, s.Passphrase)
+	if err != nil {
+		file.Close()
+		os.Remove(s.Path)
+		return err
+	}
+	s.Written = written
+	return file.Close()
+}
+
+type ImportCacheCommand struct {
+	BaseURL    string
+	AdminToken string
+	Path       string
+	Mode       string
+	Passphrase string
+	Response   types.CacheImportReport
+}
+
+// Mode is merge or replace
+func CreateImportCacheCommand(BaseURL string, AdminToken string, Path string, Mode string, Passphrase string) *ImportCacheCommand {
+	return &ImportCacheCommand{
+		BaseURL:    BaseURL,
+		AdminToken: AdminToken,
+		Path:       Path,
+		Mode:       Mode,
+		Passphrase: Passphrase,
+	}
+}
+
+func (s *ImportCacheCommand) Execute() error {
+	file, err := os.Open(s.Path)
+	if err != nil {
+		return fmt.Errorf("error opening file: %v", err)
+	}
+	defer file.Close()
+
+	response, err := adminClient(s.BaseURL, s.AdminToken).ImportCache(context.Background(), file, s.Mode, s.Passphrase)
+	if err != nil {
+		return err
+	}
+	s.Response = *response
+	return nil
+}
+
+type CacheMigrationsCommand struct {
+	BaseURL    string
+	AdminToken string
+	Response   types.CacheMigrationReport
+}
+
+func CreateCacheMigrationsCommand(BaseURL string, AdminToken string) *CacheMigrationsCommand {
+	return &CacheMigrationsCommand{
+		BaseURL:    BaseURL,
+		AdminToken: AdminToken,
+	}
+}
+
+func (s *CacheMigrationsCommand) Execute() error {
+	response, err := adminClient(s.BaseURL, s.AdminToken).GetCacheMigrations(context.Background())
+	if err != nil {
+		return err
+	}
+	s.Response = *response
+	return nil
+}
+
+type ApplyEventsCommand struct {
+	BaseURL    string
+	AdminToken string
+	Path       string
+	Response   types.EventIngestResponse
+}
+
+// Path is a CloudTrail log file, an EventBridge event or a list of records
+func CreateApplyEventsCommand(BaseURL string, AdminToken string, Path string) *ApplyEventsCommand {
+	return &ApplyEventsCommand{
+		BaseURL:    BaseURL,
+		AdminToken: AdminToken,
+		Path:       Path,
+	}
+}
+
+func (s *ApplyEventsCommand) Execute() error {
+	file, err := os.Open(s.Path)
+	if err != nil {
+		return fmt.Errorf("error opening file: %v", err)
+	}
+	defer file.Close()
+
+	response, err := adminClient(s.BaseURL, s.AdminToken).ApplyEvents(context.Background(), file)
+	if err != nil {
+		return err
+	}
+	s.Response = *response
+	return nil
+}
@@ -1,6 +1,15 @@
 package command
 
+import "golang-secret-manager/client"
+
 // Basic command interface
 type ICommand interface {
 	Execute() error
 }
+
+// The client of the routes that are behind the admin token
+func adminClient(BaseURL string, AdminToken string) *client.Client {
+	api := client.New(BaseURL)
+	api.AdminToken = AdminToken
+	return api
+}
@@ -1,68 +1,41 @@
 package command
 
 import (
-	"bytes"
-	"fmt"
+	"context"
+	"golang-secret-manager/client"
 	"golang-secret-manager/types"
-	GenericEncoding "golang-secret-manager/utils/genericEncoding"
-	"net/http"
 )
 
 type GetSecretsCommand struct {
 	PublicKey string
 	SecretKey string
-	ApiRoute  string
+	BaseURL   string
 	Region    string
 	Response  types.GetAllSecretsResponse
 }
 
 func CreateGetSecretsCommand(PublicKey string,
 	SecretKey string,
-	ApiRoute string,
+	BaseURL string,
 	Region string) *GetSecretsCommand {
 	return &GetSecretsCommand{
 		PublicKey: PublicKey,
 		SecretKey: SecretKey,
-		ApiRoute:  ApiRoute,
+		BaseURL:   BaseURL,
 		Region:    Region,
 	}
 }
 
 func (s *GetSecretsCommand) Execute() error {
-
-	// Request Body
-	// PublicKey string `json:"public_key"`
-	// SecretKey string `json:"secret_key"`
-	// Region    string `json:"region"`
-
-	data := []byte(fmt.Sprintf(`{"public_key": "%s", "secret_key": "%s",  "region":"%s"}`, s.PublicKey, s.SecretKey, s.Region))
-	payload := bytes.NewBuffer(data)
-
-	// sending to the server using POST request
-	req, err := http.Post(s.ApiRoute, "application/json", payload)
+	response, err := client.New(s.BaseURL).GetAllSecrets(context.Background(), types.GetAllSecretsRequest{
+		PublicKey: s.PublicKey,
+		SecretKey: s.SecretKey,
+		Region:    s.Region,
+	})
 	if err != nil {
-		// Handle the error
-		return fmt.Errorf("error retrieving secrets from server: %v", err)
-	}
-
-	defer req.Body.Close()
-	if req.StatusCode == http.StatusOK {
-		// retriving the list of secrets from the request
-		valRes, err := GenericEncoding.JsonBodyDecoder[types.GetAllSecretsResponse](req.Body)
-		if err != nil {
-			return fmt.Errorf("failed to decode error from the server: %v", err)
-		}
-		s.Response = *valRes
-	} else {
-		// printing the error
-		valErr, err := GenericEncoding.JsonBodyDecoder[types.ApiError](req.Body)
-		if err != nil {
-			// failed to decode error
-			return fmt.Errorf("failed to decode error from the server")
-		} else {
-			return fmt.Errorf(valErr.Err)
-		}
+		return err
 	}
+	s.Response = *response
 	return nil
 }
 
@@ -70,61 +43,96 @@ type GetReportByIdCommand struct {
 	PublicKey string
 	SecretKey string
 	SecretID  string
-	ApiRoute  string
+	BaseURL   string
 	Region    string
+	Filter    *types.AccessLogFilter
 	Response  types.GetReportResponse
+
+	// With a format (json, markdown, html, csv) the rendered report is
+	// returned in Rendered instead of Response
+	Format   string
+	Rendered []byte
 }
 
 func CreateGetReportByIdCommand(PublicKey string,
 	SecretKey string,
 	SecretID string,
-	ApiRoute string,
-	Region string) *GetReportByIdCommand {
+	BaseURL string,
+	Region string,
+	Filter *types.AccessLogFilter) *GetReportByIdCommand {
 	return &GetReportByIdCommand{
 		PublicKey: PublicKey,
 		SecretKey: SecretKey,
 		SecretID:  SecretID,
-		ApiRoute:  ApiRoute,
+		BaseURL:   BaseURL,
 		Region:    Region,
+		Filter:    Filter,
 	}
 }
 
 func (s *GetReportByIdCommand) Execute() error {
+	request := types.GetReportRequest{
+		PublicKey: s.PublicKey,
+		SecretKey: s.SecretKey,
+		Region:    s.Region,
+		SecretID:  s.SecretID,
+		Filter:    s.Filter,
+	}
 
-	// Request Body
-	// PublicKey string `json:"public_key"`
-	// SecretKey string `json:"secret_key"`
-	// Region    string `json:"region"`
-	// SecretID  string `json:"secret_id"`
-
-	data := []byte(fmt.Sprintf(`{"public_key": "%s", "secret_key": "%s",  "region":"%s", "secret_id":"%s"}`, s.PublicKey, s.SecretKey, s.Region, s.SecretID))
-	payload := bytes.NewBuffer(data)
-
-	// sending to the server using POST request
-	req, err := http.Post(s.ApiRoute, "application/json", payload)
+	api := client.New(s.BaseURL)
+	if s.Format != "" {
+		rendered, err := api.RenderReport(context.Background(), request, s.Format)
+		if err != nil {
+			return err
+		}
+		s.Rendered = rendered
+		return nil
+	}
+	response, err := api.GetReport(context.Background(), request)
 	if err != nil {
-		// Handle the error
-		return fmt.Errorf("error retrieving secrets from server: %v", err)
+		return err
 	}
+	s.Response = *response
+	return nil
+}
 
-	defer req.Body.Close()
+type GetAccessLogCommand struct {
+	PublicKey string
+	SecretKey string
+	SecretID  string
+	BaseURL   string
+	Region    string
+	Filter    *types.AccessLogFilter
+	Response  types.GetAccessLogResponse
+}
 
-	if req.StatusCode == http.StatusOK {
-		// retriving the list of secrets from the request
-		valRes, err := GenericEncoding.JsonBodyDecoder[types.GetReportResponse](req.Body)
-		if err != nil {
-			return fmt.Errorf("error decoding response: %v", err)
-		}
-		s.Response = *valRes
-	} else {
-		// printing the error
-		valErr, err := GenericEncoding.JsonBodyDecoder[types.ApiError](req.Body)
-		if err != nil {
-			// failed to decode error
-			return fmt.Errorf("failed to retrive report from the server")
-		} else {
-			return fmt.Errorf(valErr.Err)
-		}
+func CreateGetAccessLogCommand(PublicKey string,
+	SecretKey string,
+	SecretID string,
+	BaseURL string,
+	Region string,
+	Filter *types.AccessLogFilter) *GetAccessLogCommand {
+	return &GetAccessLogCommand{
+		PublicKey: PublicKey,
+		SecretKey: SecretKey,
+		SecretID:  SecretID,
+		BaseURL:   BaseURL,
+		Region:    Region,
+		Filter:    Filter,
+	}
+}
+
+func (s *GetAccessLogCommand) Execute() error {
+	response, err := client.New(s.BaseURL).GetAccessLog(context.Background(), types.GetAccessLogRequest{
+		PublicKey: s.PublicKey,
+		SecretKey: s.SecretKey,
+		Region:    s.Region,
+		SecretID:  s.SecretID,
+		Filter:    s.Filter,
+	})
+	if err != nil {
+		return err
 	}
+	s.Response = *response
 	return nil
 }
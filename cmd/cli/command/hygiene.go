@@ -0,0 +1,37 @@
+package command
+
+import (
+	"context"
+	"golang-secret-manager/client"
+	"golang-secret-manager/types"
+)
+
+type HygieneReportCommand struct {
+	BaseURL string
+	Request types.HygieneRequest
+
+	// text, json or html
+	Format   string
+	Rendered []byte
+}
+
+func CreateHygieneReportCommand(BaseURL string, Request types.HygieneRequest, Format string) *HygieneReportCommand {
+	return &HygieneReportCommand{
+		BaseURL: BaseURL,
+		Request: Request,
+		Format:  Format,
+	}
+}
+
+func (s *HygieneReportCommand) Execute() error {
+	format := s.Format
+	if format == "" {
+		format = "text"
+	}
+	rendered, err := client.New(s.BaseURL).RenderHygieneReport(context.Background(), s.Request, format)
+	if err != nil {
+		return err
+	}
+	s.Rendered = rendered
+	return nil
+}
@@ -0,0 +1,84 @@
+package command
+
+import (
+	"context"
+	"golang-secret-manager/types"
+)
+
+type InventorySnapshotsCommand struct {
+	BaseURL    string
+	AdminToken string
+	Response   types.InventorySnapshotList
+}
+
+func CreateInventorySnapshotsCommand(BaseURL string, AdminToken string) *InventorySnapshotsCommand {
+	return &InventorySnapshotsCommand{
+		BaseURL:    BaseURL,
+		AdminToken: AdminToken,
+	}
+}
+
+func (s *InventorySnapshotsCommand) Execute() error {
+	response, err := adminClient(s.BaseURL, s.AdminToken).GetInventorySnapshots(context.Background())
+	if err != nil {
+		return err
+	}
+	s.Response = *response
+	return nil
+}
+
+type TakeInventorySnapshotCommand struct {
+	BaseURL    string
+	AdminToken string
+	Request    types.InventorySnapshotRequest
+	Response   types.InventorySnapshotInfo
+}
+
+func CreateTakeInventorySnapshotCommand(BaseURL string, AdminToken string, Request types.InventorySnapshotRequest) *TakeInventorySnapshotCommand {
+	return &TakeInventorySnapshotCommand{
+		BaseURL:    BaseURL,
+		AdminToken: AdminToken,
+		Request:    Request,
+	}
+}
+
+func (s *TakeInventorySnapshotCommand) Execute() error {
+	response, err := adminClient(s.BaseURL, s.AdminToken).TakeInventorySnapshot(context.Background(), s.Request)
+	if err != nil {
+		return err
+	}
+	s.Response = *response
+	return nil
+}
+
+type InventoryDiffCommand struct {
+	BaseURL    string
+	AdminToken string
+	Request    types.InventoryDiffRequest
+
+	// text or json
+	Format   string
+	Rendered []byte
+}
+
+func CreateInventoryDiffCommand(BaseURL string, AdminToken string, Request types.InventoryDiffRequest, Format string) *InventoryDiffCommand {
+	return &InventoryDiffCommand{
+		BaseURL:    BaseURL,
+		AdminToken: AdminToken,
+		Request:    Request,
+		Format:     Format,
+	}
+}
+
+func (s *InventoryDiffCommand) Execute() error {
+	format := s.Format
+	if format == "" {
+		format = "text"
+	}
+	rendered, err := adminClient(s.BaseURL, s.AdminToken).RenderInventoryDiff(context.Background(), s.Request, format)
+	if err != nil {
+		return err
+	}
+	s.Rendered = rendered
+	return nil
+}
@@ -0,0 +1,82 @@
+package command
+
+import (
+	"context"
+	"golang-secret-manager/types"
+)
+
+type JobsCommand struct {
+	BaseURL    string
+	AdminToken string
+	Status     string
+	Profile    string
+	Limit      int
+	Response   types.JobList
+}
+
+func CreateJobsCommand(BaseURL string, AdminToken string, Status string, Profile string, Limit int) *JobsCommand {
+	return &JobsCommand{
+		BaseURL:    BaseURL,
+		AdminToken: AdminToken,
+		Status:     Status,
+		Profile:    Profile,
+		Limit:      Limit,
+	}
+}
+
+func (s *JobsCommand) Execute() error {
+	response, err := adminClient(s.BaseURL, s.AdminToken).GetJobs(context.Background(), s.Status, s.Profile, s.Limit)
+	if err != nil {
+		return err
+	}
+	s.Response = *response
+	return nil
+}
+
+type JobCommand struct {
+	BaseURL    string
+	AdminToken string
+	ID         string
+	Response   types.CrawlJob
+}
+
+func CreateJobCommand(BaseURL string, AdminToken string, ID string) *JobCommand {
+	return &JobCommand{
+		BaseURL:    BaseURL,
+		AdminToken: AdminToken,
+		ID:         ID,
+	}
+}
+
+func (s *JobCommand) Execute() error {
+	response, err := adminClient(s.BaseURL, s.AdminToken).GetJob(context.Background(), s.ID)
+	if err != nil {
+		return err
+	}
+	s.Response = *response
+	return nil
+}
+
+type CrawlCommand struct {
+	BaseURL    string
+	AdminToken string
+	Request    types.CrawlRequest
+	Response   types.CrawlResponse
+}
+
+func CreateCrawlCommand(BaseURL string, AdminToken string, Request types.CrawlRequest) *CrawlCommand {
+	return &CrawlCommand{
+		BaseURL:    BaseURL,
+		AdminToken: AdminToken,
+		Request:    Request,
+	}
+}
+
+func (s *CrawlCommand) Execute() error {
+	response, err := adminClient(s.BaseURL, s.AdminToken).Crawl(context.Background(), s.Request)
+	if err != nil {
+		return err
+	}
+	s.Response = *response
+	return nil
+}
@@ -0,0 +1,29 @@
+package command
+
+import (
+	"context"
+	"golang-secret-manager/client"
+	"golang-secret-manager/types"
+)
+
+type PolicyCheckCommand struct {
+	BaseURL  string
+	Request  types.PolicyCheckRequest
+	Response types.PolicyReport
+}
+
+func CreatePolicyCheckCommand(BaseURL string, Request types.PolicyCheckRequest) *PolicyCheckCommand {
+	return &PolicyCheckCommand{
+		BaseURL: BaseURL,
+		Request: Request,
+	}
+}
+
+func (s *PolicyCheckCommand) Execute() error {
+	response, err := client.New(s.BaseURL).CheckPolicy(context.Background(), s.Request)
+	if err != nil {
+		return err
+	}
+	s.Response = *response
+	return nil
+}
@@ -0,0 +1,76 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	pb "golang-secret-manager/proto/secretmanagerpb"
+	"golang-secret-manager/types"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Connecting to the gRPC API of the server, the admin token is sent with
+// every call
+func DialRPC(addr string, adminToken string) (*grpc.ClientConn, pb.SecretManagerClient, error) {
+	options := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if adminToken != "" {
+		options = append(options, grpc.WithPerRPCCredentials(bearerToken(adminToken)))
+	}
+	conn, err := grpc.Dial(addr, options...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error connecting to the gRPC server: %v", err)
+	}
+	return conn, pb.NewSecretManagerClient(conn), nil
+}
+
+type bearerToken string
+
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// The server is reached without TLS like the HTTP API
+func (t bearerToken) RequireTransportSecurity() bool {
+	return false
+}
+
+// Like GetSecretsCommand over the gRPC API
+type GetSecretsRPCCommand struct {
+	PublicKey string
+	SecretKey string
+	Addr      string
+	Region    string
+	Timeout   time.Duration
+	Response  types.GetAllSecretsResponse
+}
+
+func CreateGetSecretsRPCCommand(PublicKey string, SecretKey string, Addr string, Region string) *GetSecretsRPCCommand {
+	return &GetSecretsRPCCommand{
+		PublicKey: PublicKey,
+		SecretKey: SecretKey,
+		Addr:      Addr,
+		Region:    Region,
+		Timeout:   5 * time.Minute,
+	}
+}
+
+func (s *GetSecretsRPCCommand) Execute() error {
+	conn, client, err := DialRPC(s.Addr, "")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+	res, err := client.GetAllSecrets(ctx, &pb.GetAllSecretsRequest{
+		Credential: &pb.Credential{PublicKey: s.PublicKey, SecretKey: s.SecretKey, Region: s.Region},
+	})
+	if err != nil {
+		return fmt.Errorf("error retrieving secrets from server: %v", err)
+	}
+	s.Response = res.ToType()
+	return nil
+}
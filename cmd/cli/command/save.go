@@ -1,7 +1,9 @@
 package command
 
 import (
+	"encoding/csv"
 	"fmt"
+	"golang-secret-manager/api/report"
 	"golang-secret-manager/types"
 	"os"
 )
@@ -32,16 +34,21 @@ func (s *SaveToFileSecretsCommand) Execute() error {
 		temp := s.value.AccessLog[secret.ARN]
 		length := len(temp)
 		if length > 0 {
-			_, err = file.WriteString("ACCESS LOG\nUser, Event Time, Event Name\n")
+			_, err = file.WriteString("ACCESS LOG\n")
 			if err != nil {
 				return fmt.Errorf("error writing to CSV: %v", err)
 			}
 
+			// the user agents and ARNs can hold commas, the rows are quoted
+			// by the csv writer
+			writer := csv.NewWriter(file)
+			writer.Write(report.AccessLogCSVHeader)
 			for _, accessLog := range temp {
-				_, err = file.WriteString(fmt.Sprintf("%s,%s,%s\n", accessLog.User, accessLog.EventTime, accessLog.EventName))
-				if err != nil {
-					return fmt.Errorf("error writing to CSV: %v", err)
-				}
+				writer.Write(report.AccessLogCSVRecord(accessLog))
+			}
+			writer.Flush()
+			if err := writer.Error(); err != nil {
+				return fmt.Errorf("error writing to CSV: %v", err)
 			}
 		}
 		_, err = file.WriteString("\n")
@@ -0,0 +1,134 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"golang-secret-manager/types"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tailing the events of the server until Stop is closed, the stream is
+// opened again after the retry delay when it drops and resumes after the
+// last event that was received
+type WatchCommand struct {
+	BaseURL     string
+	AdminToken  string
+	ARNs        []string
+	Tags        []string
+	Events      []string
+	LastEventID string
+
+	// Called with every event, and with the error of a stream that dropped
+	OnEvent      func(event types.StreamEvent)
+	OnDisconnect func(err error)
+	Stop         <-chan struct{}
+}
+
+func CreateWatchCommand(BaseURL string, AdminToken string, ARNs []string, Tags []string, Events []string, Stop <-chan struct{}) *WatchCommand {
+	return &WatchCommand{
+		BaseURL:    BaseURL,
+		AdminToken: AdminToken,
+		ARNs:       ARNs,
+		Tags:       Tags,
+		Events:     Events,
+		Stop:       Stop,
+	}
+}
+
+// Returning when Stop is closed, or with the error of the first connection
+// (a wrong token or filter doesn't get better by retrying)
+func (s *WatchCommand) Execute() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.Stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	api := adminClient(s.BaseURL, s.AdminToken)
+	// the stream is opened again by the loop
+	api.Retries = 0
+
+	retry := 3 * time.Second
+	connected := false
+	for {
+		body, err := api.StreamEvents(ctx, s.ARNs, s.Tags, s.Events, s.LastEventID)
+		if err == nil {
+			connected = true
+			err = s.read(body, &retry)
+			body.Close()
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !connected {
+			return err
+		}
+		if s.OnDisconnect != nil {
+			s.OnDisconnect(err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(retry):
+		}
+	}
+}
+
+// Reading the server-sent events until the stream ends
+func (s *WatchCommand) read(body io.Reader, retry *time.Duration) error {
+	reader := bufio.NewReader(body)
+	var id, data string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return errors.New("the server closed the stream")
+			}
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			// the end of the event
+			if data != "" {
+				var event types.StreamEvent
+				if err := json.Unmarshal([]byte(data), &event); err == nil && s.OnEvent != nil {
+					s.OnEvent(event)
+				}
+				if id != "" {
+					s.LastEventID = id
+				}
+			}
+			id, data = "", ""
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			// heartbeat
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			id = value
+		case "data":
+			if data != "" {
+				data += "\n"
+			}
+			data += value
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms > 0 {
+				*retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
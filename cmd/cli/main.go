@@ -2,10 +2,16 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"golang-secret-manager/api/policy"
 	"golang-secret-manager/cmd/cli/command"
+	"golang-secret-manager/types"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -13,20 +19,56 @@ import (
 // API request const
 const apiRoute = "http://localhost:8080/"
 
-// Routes
-const secretUri = "secrets"
-const reportUri = "reports"
+// The routes are sent by the client package
 
 // Global Vars
 var userPublicKey string
 var userSecretKey string
 var userRegion string = "eu-north-1"
 var userSavedLocation string = "./"
+var userAdminToken string
+
+// The secrets are retrived over gRPC when the address is loaded
+var userGrpcAddr string
 
 // Usage
-const loadUsage = "Load Usage:\nload			-- loading the public + secret key from .env\nload public <key> 	-- loading public key\nload secret <key> 	-- loading secret key\nload region <region> 	-- loading the AWS region"
-const getUsage = "Get Usage:\nget secrets		-- retriving all secret from AWS service\nget report <secret id> 	-- showing secret report"
-const reportUsage = "Report Usage:\nget report <secret id> 	-- showing secret report"
+const loadUsage = "Load Usage:\nload			-- loading the public + secret key from .env\nload public <key> 	-- loading public key\nload secret <key> 	-- loading secret key\nload region <region> 	-- loading the AWS region\nload admin <token> 	-- loading the admin token of the server\nload grpc <addr> 	-- retriving the secrets over the gRPC API (localhost:9090), off to use HTTP"
+const getUsage = "Get Usage:\nget secrets		-- retriving all secret from AWS service\nget report <secret id> [filters] [--format <format>] [-o <file>]	-- showing secret report\n" +
+	"get access-log <secret id> [filters]	-- showing the access log events of the secret\n" +
+	"get hygiene [--unused-days <days>] [--required-tag <tag>] [--format <format>] [-o <file>]\n" +
+	"			-- checking all the secrets (unused, rotation, deletion, tags, KMS key), format: text, json or html\n" + filterUsage
+const filterUsage = "Filters:\n--since <time>		-- events since the time (RFC3339, 2006-01-02 or a duration like 7d, 12h)\n" +
+	"--until <time>		-- events until the time\n" +
+	"--event <name>		-- only the events with the name, can be repeated (GetSecretValue...)\n" +
+	"--user <user>		-- only the events of the user name or ARN, can be repeated\n" +
+	"--exclude-service-principals	-- dropping the calls that AWS services made"
+const cacheUsage = "Cache Usage:\ncache verify		-- checking that the server cache can be decrypted\n" +
+	"cache keys [namespace]	-- listing the cache keys (secret, access, acsync, arnlst)\n" +
+	"cache show <key>	-- showing a cache entry with its age and dirty flag\n" +
+	"cache invalidate arn <arn> [--dry-run]		-- removing a single secret from the cache\n" +
+	"cache invalidate credential [public key] [--dry-run]	-- removing everything of the credential (default: loaded public key)\n" +
+	"cache flush		-- saving the changed keys to the persist layer now\n" +
+	"cache evictions [max entries]	-- previewing the keys that would be evicted\n" +
+	"cache migrations	-- showing the entries that couldn't be migrated to the current schema\n" +
+	"cache export <file> [--passphrase <passphrase>]	-- saving a snapshot of the whole cache\n" +
+	"cache import <file> [--replace] [--passphrase <passphrase>]	-- loading a snapshot (merged by default)\n" +
+	"cache events <file>	-- applying CloudTrail events of the file to the cache"
+const auditUsage = "Audit Usage:\naudit anomalies [--arn <arn>] [--window <window>] [--json]\n" +
+	"			-- checking the cached access logs against the baseline of each secret (window: 24h, 7d...)"
+const policyUsage = "Policy Usage:\npolicy check <file> [--json]	-- checking all the secrets against the rules of the YAML file\n" +
+	"			   (run as 'cli policy check <file>' the exit code is 1 when there are violations)"
+const inventoryUsage = "Inventory Usage:\ninventory snapshots	-- listing the saved snapshots of the secrets inventory\n" +
+	"inventory snapshot	-- saving a snapshot of the secrets of the loaded keys now\n" +
+	"inventory diff <from> [<to>|live] [--json]	-- showing the added, removed and changed secrets (default to: live)\n" +
+	"			   a snapshot is selected by its id, 'latest' or a date (the newest snapshot taken by then)"
+const crawlUsage = "Crawl Usage:\ncrawl [profile] [--region <region>] [--wait]	-- crawling a profile of the server now (default: the loaded keys and region)\n" +
+	"jobs [--status <status>] [--profile <profile>] [--limit <num>]	-- listing the crawl jobs and the schedule of the profiles\n" +
+	"jobs show <id>		-- showing a single crawl job"
+const watchUsage = "Watch Usage:\nwatch [--arn <arn>] [--tag <key>[=<value>]] [--event <event>]	-- following the accesses and the changes of the secrets as they happen, Enter stops\n" +
+	"			-- the filters can be repeated, the event is an event name (GetSecretValue) or a type (access_log, secret_created, secret_deleted, secret_changed)"
+const reportUsage = "Report Usage:\nget report <secret id> [filters] [--format <format>] [-o <file>]	-- showing secret report\n" +
+	"--format <format>	-- text, json, markdown, html or csv (default: text)\n" +
+	"-o <file>		-- saving the report to the file instead of printing it\n" + filterUsage
 
 // Reading the user input
 func readInput() string {
@@ -65,6 +107,8 @@ func handleLoad(args []string) {
 
 		userPublicKey = os.Getenv("public")
 		userSecretKey = os.Getenv("secret")
+		// the admin token is optional
+		userAdminToken = os.Getenv("admin")
 
 		if userPublicKey == "" || userSecretKey == "" {
 			fmt.Println("Error: couldn't find public or secret key in the .env file")
@@ -90,6 +134,19 @@ func handleLoad(args []string) {
 			userRegion = args[2]
 			fmt.Println(" ---- Region set to: '" + userRegion + "' ---- ")
 			return
+		} else if args[1] == "grpc" {
+			if args[2] == "off" {
+				userGrpcAddr = ""
+				fmt.Println(" ---- Using the HTTP API ---- ")
+				return
+			}
+			userGrpcAddr = args[2]
+			fmt.Println(" ---- gRPC address set to: '" + userGrpcAddr + "' ---- ")
+			return
+		} else if args[1] == "admin" {
+			userAdminToken = args[2]
+			fmt.Println(" ---- Admin token set ---- ")
+			return
 		} else {
 			fmt.Println(loadUsage)
 		}
@@ -98,18 +155,31 @@ func handleLoad(args []string) {
 
 func handleGetSecret() {
 	fmt.Println(" ---- Getting all secrets from the server ---- ")
-	com1 := command.CreateGetSecretsCommand(userPublicKey, userSecretKey, apiRoute+secretUri, userRegion)
-	err := com1.Execute()
-	if err != nil {
-		// failed to retrive the secrets
-		fmt.Println(" ----------- FAILED TO RETRIVE ----------- ")
-		return
+	var response types.GetAllSecretsResponse
+	if userGrpcAddr != "" {
+		com1 := command.CreateGetSecretsRPCCommand(userPublicKey, userSecretKey, userGrpcAddr, userRegion)
+		if err := com1.Execute(); err != nil {
+			fmt.Println(" ----------- FAILED TO RETRIVE ----------- ")
+			fmt.Println(err)
+			return
+		}
+		response = com1.Response
+	} else {
+		com1 := command.CreateGetSecretsCommand(userPublicKey, userSecretKey, apiRoute, userRegion)
+		err := com1.Execute()
+		if err != nil {
+			// failed to retrive the secrets
+			fmt.Println(" ----------- FAILED TO RETRIVE ----------- ")
+			fmt.Println(err)
+			return
+		}
+		response = com1.Response
 	}
 
 	// success
 	fmt.Printf(" ---- Saving all secrets to CSV file at %s ---- \n", userSavedLocation)
-	com2 := command.CreateSaveToFileSecretsCommand(userSavedLocation, com1.Response)
-	err = com2.Execute()
+	com2 := command.CreateSaveToFileSecretsCommand(userSavedLocation, response)
+	err := com2.Execute()
 	if err != nil {
 		// failed to retrive the secrets
 		fmt.Println(" ------------- FAILED TO SAVE ------------- ")
@@ -119,22 +189,211 @@ func handleGetSecret() {
 	fmt.Println("Done! ")
 }
 
-func handleGetReport(secretID string) {
+// Parsing the time of a filter flag, a duration is counted back from now
+func parseFilterTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if strings.HasSuffix(value, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil && days >= 0 {
+			return time.Now().Add(-time.Duration(days) * 24 * time.Hour), nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil && duration >= 0 {
+		return time.Now().Add(-duration), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %s", value)
+}
+
+// Splitting the access log filter flags from the other arguments, the filter
+// is nil when there are no flags
+func parseFilterArgs(args []string) (rest []string, filter *types.AccessLogFilter, err error) {
+	f := types.AccessLogFilter{}
+	found := false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--exclude-service-principals":
+			f.ExcludeServicePrincipals = true
+			found = true
+		case "--since", "--until", "--event", "--user":
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("missing value of %s", args[i])
+			}
+			value := args[i+1]
+			switch args[i] {
+			case "--since", "--until":
+				t, err := parseFilterTime(value)
+				if err != nil {
+					return nil, nil, err
+				}
+				if args[i] == "--since" {
+					f.Since = &t
+				} else {
+					f.Until = &t
+				}
+			case "--event":
+				f.Events = append(f.Events, value)
+			case "--user":
+				f.Users = append(f.Users, value)
+			}
+			found = true
+			i++
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	if f.Since != nil && f.Until != nil && f.Until.Before(*f.Since) {
+		return nil, nil, fmt.Errorf("--until is before --since")
+	}
+	if found {
+		filter = &f
+	}
+	return rest, filter, nil
+}
+
+// Splitting the --format and -o flags of the report from the other arguments
+func parseReportArgs(args []string) (rest []string, format string, output string, ok bool) {
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--format", "-o", "--output":
+			if i+1 >= len(args) {
+				return nil, "", "", false
+			}
+			if args[i] == "--format" {
+				format = args[i+1]
+			} else {
+				output = args[i+1]
+			}
+			i++
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return rest, format, output, true
+}
+
+func handleGetReport(secretID string, filter *types.AccessLogFilter, format string, output string) {
 	fmt.Println(" ---- Getting report about secret '" + secretID + "' from the server ---- ")
 
-	com := command.CreateGetReportByIdCommand(userPublicKey, userSecretKey, secretID, apiRoute+reportUri, userRegion)
+	com := command.CreateGetReportByIdCommand(userPublicKey, userSecretKey, secretID, apiRoute, userRegion, filter)
+	if (format != "" && format != "text") || output != "" {
+		// the text report is also rendered by the server when it is saved
+		com.Format = format
+		if com.Format == "" {
+			com.Format = "text"
+		}
+	}
 
 	if err := com.Execute(); err != nil {
 		// failed to get report
 		fmt.Println(" ----------- FAILED TO RETRIVE ----------- ")
-	} else {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("Done! ")
+	switch {
+	case output != "":
+		if err := os.WriteFile(output, com.Rendered, 0644); err != nil {
+			fmt.Println(" ------------- FAILED TO SAVE ------------- ")
+			fmt.Println(err)
+			return
+		}
+		fmt.Println("Saved the report to '" + output + "'")
+	case com.Format != "":
+		fmt.Println(string(com.Rendered))
+	default:
 		// printing the report
-		fmt.Println("Done! ")
 		fmt.Println(com.Response.Report)
 	}
 }
 
+func handleGetAccessLog(secretID string, filter *types.AccessLogFilter) {
+	fmt.Println(" ---- Getting the access log of secret '" + secretID + "' from the server ---- ")
+
+	com := command.CreateGetAccessLogCommand(userPublicKey, userSecretKey, secretID, apiRoute, userRegion, filter)
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO RETRIVE ----------- ")
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("%d events:\n", len(com.Response.AccessLog))
+	for _, event := range com.Response.AccessLog {
+		fmt.Printf(" - %s  %-20s %-30s %s\n", event.EventTime.Local().Format(time.RFC3339), event.EventName, event.User, event.SourceIP)
+	}
+}
+
+func handleGetHygiene(args []string, format string, output string) {
+	request := types.HygieneRequest{
+		PublicKey: userPublicKey,
+		SecretKey: userSecretKey,
+		Region:    userRegion,
+	}
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			fmt.Println(getUsage)
+			return
+		}
+		switch args[i] {
+		case "--unused-days":
+			days, err := strconv.Atoi(args[i+1])
+			if err != nil || days <= 0 {
+				fmt.Println(getUsage)
+				return
+			}
+			request.UnusedDays = days
+		case "--required-tag":
+			request.RequiredTags = append(request.RequiredTags, args[i+1])
+		default:
+			fmt.Println(getUsage)
+			return
+		}
+		i++
+	}
+
+	fmt.Println(" ---- Checking the hygiene of all the secrets ---- ")
+	com := command.CreateHygieneReportCommand(apiRoute, request, format)
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO RETRIVE ----------- ")
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("Done! ")
+	if output == "" {
+		fmt.Println(string(com.Rendered))
+		return
+	}
+	if err := os.WriteFile(output, com.Rendered, 0644); err != nil {
+		fmt.Println(" ------------- FAILED TO SAVE ------------- ")
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("Saved the report to '" + output + "'")
+}
+
 func handleGet(args []string) {
+	args, format, output, ok := parseReportArgs(args)
+	if !ok {
+		fmt.Println(reportUsage)
+		return
+	}
+	if len(args) >= 2 && args[1] == "hygiene" {
+		if userPublicKey == "" || userSecretKey == "" {
+			fmt.Println("Please load public and secret keys first")
+			return
+		}
+		handleGetHygiene(args[2:], format, output)
+		return
+	}
+	args, filter, err := parseFilterArgs(args)
+	if err != nil {
+		fmt.Println(err)
+		fmt.Println(getUsage)
+		return
+	}
 	length := len(args)
 	if length != 2 && length != 3 {
 		fmt.Println(getUsage)
@@ -148,15 +407,26 @@ func handleGet(args []string) {
 
 	switch args[1] {
 	case "secrets":
+		if filter != nil || format != "" || output != "" {
+			fmt.Println(getUsage)
+			return
+		}
 		handleGetSecret()
 		return
 	case "report":
 		if len(args) == 3 {
-			handleGetReport(args[2])
+			handleGetReport(args[2], filter, format, output)
 		} else {
 			fmt.Println(reportUsage)
 		}
 		return
+	case "access-log":
+		if len(args) == 3 && format == "" && output == "" {
+			handleGetAccessLog(args[2], filter)
+		} else {
+			fmt.Println(getUsage)
+		}
+		return
 	default:
 		{
 			fmt.Println(getUsage)
@@ -165,6 +435,705 @@ func handleGet(args []string) {
 
 }
 
+func handleCacheVerify() {
+	fmt.Println(" ---- Verifying the server cache ---- ")
+	com := command.CreateVerifyCacheCommand(apiRoute, userAdminToken)
+	err := com.Execute()
+	fmt.Printf("Key id: %s, entries: %d (encrypted: %d, plain: %d)\n",
+		com.Response.KeyID, com.Response.Total, com.Response.Encrypted, com.Response.Plain)
+	for key, reason := range com.Response.Failed {
+		fmt.Println(" - " + key + ": " + reason)
+	}
+	if err != nil {
+		fmt.Println(" ----------- FAILED TO VERIFY ----------- ")
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("Done! ")
+}
+
+func handleCacheKeys(namespace string) {
+	com := command.CreateCacheKeysCommand(apiRoute, userAdminToken, namespace)
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO RETRIVE ----------- ")
+		fmt.Println(err)
+		return
+	}
+	namespaces := make([]string, 0, len(com.Response.Keys))
+	for name := range com.Response.Keys {
+		namespaces = append(namespaces, name)
+	}
+	sort.Strings(namespaces)
+	for _, name := range namespaces {
+		fmt.Printf("%s (%d):\n", name, len(com.Response.Keys[name]))
+		for _, key := range com.Response.Keys[name] {
+			fmt.Println(" - " + key)
+		}
+	}
+}
+
+func handleCacheShow(key string) {
+	com := command.CreateCacheEntryCommand(apiRoute, userAdminToken, key)
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO RETRIVE ----------- ")
+		fmt.Println(err)
+		return
+	}
+	info := com.Response
+	fmt.Println("Key:       " + info.Key)
+	fmt.Println("Namespace: " + info.Namespace)
+	fmt.Printf("In memory: %t\nDirty:     %t\nSize:      %d bytes\n", info.InMemory, info.Dirty, info.SizeBytes)
+	if info.Age != "" {
+		fmt.Println("Age:       " + info.Age)
+	}
+	if info.ExpiresAt != nil {
+		fmt.Println("Expires:   " + info.ExpiresAt.Local().Format(time.RFC1123))
+	}
+	if info.Value != nil {
+		value, err := json.MarshalIndent(info.Value, "", "  ")
+		if err == nil {
+			fmt.Println("Value:")
+			fmt.Println(string(value))
+		}
+	}
+}
+
+func handleCacheInvalidate(args []string) {
+	dryRun := false
+	var rest []string
+	for _, arg := range args {
+		if arg == "--dry-run" {
+			dryRun = true
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+
+	var arn, publicKey string
+	switch {
+	case len(rest) == 2 && rest[0] == "arn":
+		arn = rest[1]
+	case len(rest) == 2 && rest[0] == "credential":
+		publicKey = rest[1]
+	case len(rest) == 1 && rest[0] == "credential":
+		if userPublicKey == "" {
+			fmt.Println("Please load public key first")
+			return
+		}
+		publicKey = userPublicKey
+	default:
+		fmt.Println(cacheUsage)
+		return
+	}
+
+	com := command.CreateInvalidateCacheCommand(apiRoute, userAdminToken, arn, publicKey, dryRun)
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO INVALIDATE ----------- ")
+		fmt.Println(err)
+		return
+	}
+	if dryRun {
+		fmt.Printf("Would remove %d keys:\n", len(com.Response.Keys))
+	} else {
+		fmt.Printf("Removed %d keys:\n", len(com.Response.Keys))
+	}
+	for _, key := range com.Response.Keys {
+		fmt.Println(" - " + key)
+	}
+}
+
+func handleCacheFlush() {
+	com := command.CreateFlushCacheCommand(apiRoute, userAdminToken)
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO FLUSH ----------- ")
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("Saved %d keys to the persist layer\n", com.Response.Flushed)
+}
+
+func handleCacheEvictions(args []string) {
+	maxEntries := 0
+	if len(args) == 1 {
+		num, err := strconv.Atoi(args[0])
+		if err != nil || num <= 0 {
+			fmt.Println(cacheUsage)
+			return
+		}
+		maxEntries = num
+	}
+	com := command.CreatePreviewEvictionsCommand(apiRoute, userAdminToken, maxEntries)
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO RETRIVE ----------- ")
+		fmt.Println(err)
+		return
+	}
+	if len(com.Response.Evictions) == 0 {
+		fmt.Println("Nothing would be evicted")
+		return
+	}
+	for _, eviction := range com.Response.Evictions {
+		dirty := ""
+		if eviction.Dirty {
+			dirty = " (dirty, saved before eviction)"
+		}
+		fmt.Printf(" - %s [%s] %d bytes%s\n", eviction.Key, eviction.Reason, eviction.SizeBytes, dirty)
+	}
+}
+
+func handleCacheMigrations() {
+	com := command.CreateCacheMigrationsCommand(apiRoute, userAdminToken)
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO RETRIVE ----------- ")
+		fmt.Println(err)
+		return
+	}
+	report := com.Response
+	fmt.Printf("Loaded entries: %d (current: %d, migrated: %d, failed: %d)\n", report.Total, report.Current, report.Migrated, len(report.Failed))
+	for key, reason := range report.Failed {
+		fmt.Println(" - " + key + ": " + reason)
+	}
+}
+
+// Splitting the --replace and --passphrase flags from the other arguments
+func parseSnapshotArgs(args []string) (rest []string, replace bool, passphrase string, ok bool) {
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--replace":
+			replace = true
+		case "--passphrase":
+			if i+1 >= len(args) {
+				return nil, false, "", false
+			}
+			passphrase = args[i+1]
+			i++
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return rest, replace, passphrase, true
+}
+
+func handleCacheExport(args []string) {
+	rest, replace, passphrase, ok := parseSnapshotArgs(args)
+	if !ok || replace || len(rest) != 1 {
+		fmt.Println(cacheUsage)
+		return
+	}
+	fmt.Println(" ---- Exporting the server cache to '" + rest[0] + "' ---- ")
+	com := command.CreateExportCacheCommand(apiRoute, userAdminToken, rest[0], passphrase)
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO EXPORT ----------- ")
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("Saved %d bytes\n", com.Written)
+	fmt.Println("Done! ")
+}
+
+func handleCacheImport(args []string) {
+	rest, replace, passphrase, ok := parseSnapshotArgs(args)
+	if !ok || len(rest) != 1 {
+		fmt.Println(cacheUsage)
+		return
+	}
+	mode := "merge"
+	if replace {
+		mode = "replace"
+	}
+	fmt.Println(" ---- Importing '" + rest[0] + "' to the server cache (" + mode + ") ---- ")
+	com := command.CreateImportCacheCommand(apiRoute, userAdminToken, rest[0], mode, passphrase)
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO IMPORT ----------- ")
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("Snapshot from %s (schema version %d): imported %d keys, removed %d keys, %d keys expired\n",
+		com.Response.CreatedAt.Local().Format(time.RFC1123), com.Response.SchemaVersion, com.Response.Imported, com.Response.Removed, com.Response.Expired)
+	fmt.Println("Done! ")
+}
+
+func handleCacheEvents(args []string) {
+	if len(args) != 1 {
+		fmt.Println(cacheUsage)
+		return
+	}
+	fmt.Println(" ---- Applying the events of '" + args[0] + "' to the server cache ---- ")
+	com := command.CreateApplyEventsCommand(apiRoute, userAdminToken, args[0])
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO APPLY ----------- ")
+		fmt.Println(err)
+		return
+	}
+	result := com.Response
+	fmt.Printf("Events: %d (applied: %d, ignored: %d, failed: %d)\n", result.Received, result.Applied, result.Ignored, result.Failed)
+	for _, key := range result.Keys {
+		fmt.Println(" - " + key)
+	}
+	fmt.Println("Done! ")
+}
+
+func handleAuditAnomalies(args []string) {
+	var arns []string
+	window := ""
+	format := "text"
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--json":
+			format = "json"
+		case args[i] == "--arn" && i+1 < len(args):
+			arns = append(arns, args[i+1])
+			i++
+		case args[i] == "--window" && i+1 < len(args):
+			window = args[i+1]
+			i++
+		default:
+			fmt.Println(auditUsage)
+			return
+		}
+	}
+
+	com := command.CreateAnomaliesCommand(apiRoute, userAdminToken, arns, window, format)
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO RETRIVE ----------- ")
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(string(com.Rendered))
+}
+
+func handleAudit(args []string) {
+	if len(args) < 2 {
+		fmt.Println(auditUsage)
+		return
+	}
+	switch args[1] {
+	case "anomalies":
+		handleAuditAnomalies(args[2:])
+	default:
+		fmt.Println(auditUsage)
+	}
+}
+
+// Exit codes of the policy check, for running it in CI
+const (
+	exitPassed     = 0
+	exitViolations = 1
+	exitError      = 2
+)
+
+func handlePolicyCheck(args []string) int {
+	asJson := false
+	var rest []string
+	for _, arg := range args {
+		if arg == "--json" {
+			asJson = true
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	if len(rest) != 1 {
+		fmt.Println(policyUsage)
+		return exitError
+	}
+	if userPublicKey == "" || userSecretKey == "" {
+		fmt.Println("Please load public and secret keys first")
+		return exitError
+	}
+
+	data, err := os.ReadFile(rest[0])
+	if err != nil {
+		fmt.Println(err)
+		return exitError
+	}
+	// the policy is checked before the secrets are crawled
+	if _, err := policy.Parse(data); err != nil {
+		fmt.Println(err)
+		return exitError
+	}
+
+	com := command.CreatePolicyCheckCommand(apiRoute, types.PolicyCheckRequest{
+		PublicKey: userPublicKey,
+		SecretKey: userSecretKey,
+		Region:    userRegion,
+		Policy:    string(data),
+	})
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO CHECK ----------- ")
+		fmt.Println(err)
+		return exitError
+	}
+
+	report := com.Response
+	if asJson {
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		fmt.Printf("Rules: %d, secrets: %d, checked: %d (passed: %d, failed: %d)\n", report.Rules, report.Secrets, report.Checked, report.Passed, report.Failed)
+		for _, violation := range report.Violations {
+			fmt.Printf("[%-6s] %s  %s\n", strings.ToUpper(violation.Severity), violation.RuleID, violation.SecretName)
+			for _, evidence := range violation.Evidence {
+				fmt.Println("         - " + evidence)
+			}
+		}
+	}
+	if report.Failed > 0 {
+		return exitViolations
+	}
+	return exitPassed
+}
+
+func handlePolicy(args []string) int {
+	if len(args) < 2 || args[1] != "check" {
+		fmt.Println(policyUsage)
+		return exitError
+	}
+	return handlePolicyCheck(args[2:])
+}
+
+func handleInventorySnapshots() {
+	com := command.CreateInventorySnapshotsCommand(apiRoute, userAdminToken)
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO RETRIVE ----------- ")
+		fmt.Println(err)
+		return
+	}
+	if len(com.Response.Snapshots) == 0 {
+		fmt.Println("No snapshots")
+		return
+	}
+	for _, snapshot := range com.Response.Snapshots {
+		fmt.Printf(" - %-20s %s  %-10s %s/%s  %d secrets\n", snapshot.ID, snapshot.TakenAt.Local().Format(time.RFC1123),
+			snapshot.Trigger, snapshot.Account, snapshot.Region, snapshot.Secrets)
+	}
+}
+
+func handleInventorySnapshot() {
+	if userPublicKey == "" || userSecretKey == "" {
+		fmt.Println("Please load public and secret keys first")
+		return
+	}
+	fmt.Println(" ---- Saving a snapshot of all the secrets ---- ")
+	com := command.CreateTakeInventorySnapshotCommand(apiRoute, userAdminToken, types.InventorySnapshotRequest{
+		PublicKey: userPublicKey,
+		SecretKey: userSecretKey,
+		Region:    userRegion,
+	})
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO SAVE ----------- ")
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("Saved snapshot %s of %d secrets\n", com.Response.ID, com.Response.Secrets)
+}
+
+func handleInventoryDiff(args []string) {
+	format := "text"
+	var rest []string
+	for _, arg := range args {
+		if arg == "--json" {
+			format = "json"
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	if len(rest) != 1 && len(rest) != 2 {
+		fmt.Println(inventoryUsage)
+		return
+	}
+
+	request := types.InventoryDiffRequest{From: rest[0], To: types.InventoryTriggerLive}
+	if len(rest) == 2 {
+		request.To = rest[1]
+	}
+	if request.To == types.InventoryTriggerLive {
+		// without loaded keys the server uses its inventory credential
+		request.PublicKey = userPublicKey
+		request.SecretKey = userSecretKey
+		request.Region = userRegion
+	}
+
+	com := command.CreateInventoryDiffCommand(apiRoute, userAdminToken, request, format)
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO RETRIVE ----------- ")
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(string(com.Rendered))
+}
+
+func handleInventory(args []string) {
+	if len(args) < 2 {
+		fmt.Println(inventoryUsage)
+		return
+	}
+	switch args[1] {
+	case "snapshots":
+		handleInventorySnapshots()
+	case "snapshot":
+		handleInventorySnapshot()
+	case "diff":
+		handleInventoryDiff(args[2:])
+	default:
+		fmt.Println(inventoryUsage)
+	}
+}
+
+func printJob(job types.CrawlJob) {
+	finished := ""
+	if job.FinishedAt != nil {
+		finished = fmt.Sprintf(" in %s, %d secrets, %d events", job.Duration, job.Secrets, job.AccessLogEvents)
+	}
+	fmt.Printf(" - #%-5s %-10s %-9s %s/%s  queued %s%s\n", job.ID, job.Status, job.Trigger, job.Profile, job.Region,
+		job.QueuedAt.Local().Format(time.RFC1123), finished)
+	for _, err := range job.Errors {
+		fmt.Println("          ! " + err)
+	}
+}
+
+func handleCrawl(args []string) {
+	request := types.CrawlRequest{}
+	wait := false
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--wait":
+			wait = true
+		case args[i] == "--region" && i+1 < len(args):
+			request.Region = args[i+1]
+			i++
+		case request.Profile == "" && !strings.HasPrefix(args[i], "--"):
+			request.Profile = args[i]
+		default:
+			fmt.Println(crawlUsage)
+			return
+		}
+	}
+	if request.Profile == "" {
+		if userPublicKey == "" || userSecretKey == "" {
+			fmt.Println("Please load public and secret keys first")
+			return
+		}
+		request.PublicKey = userPublicKey
+		request.SecretKey = userSecretKey
+		if request.Region == "" {
+			request.Region = userRegion
+		}
+	}
+
+	com := command.CreateCrawlCommand(apiRoute, userAdminToken, request)
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO CRAWL ----------- ")
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("Started %d crawl jobs\n", len(com.Response.Jobs))
+	if !wait {
+		for _, job := range com.Response.Jobs {
+			printJob(job)
+		}
+		return
+	}
+
+	// polling the jobs until all of them are finished
+	for _, job := range com.Response.Jobs {
+		for job.FinishedAt == nil {
+			time.Sleep(time.Second)
+			jobCom := command.CreateJobCommand(apiRoute, userAdminToken, job.ID)
+			if err := jobCom.Execute(); err != nil {
+				fmt.Println(err)
+				return
+			}
+			job = jobCom.Response
+		}
+		printJob(job)
+	}
+}
+
+func handleJobs(args []string) {
+	if len(args) == 2 && args[0] == "show" {
+		com := command.CreateJobCommand(apiRoute, userAdminToken, args[1])
+		if err := com.Execute(); err != nil {
+			fmt.Println(" ----------- FAILED TO RETRIVE ----------- ")
+			fmt.Println(err)
+			return
+		}
+		printJob(com.Response)
+		return
+	}
+
+	var status, profile string
+	limit := 20
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			fmt.Println(crawlUsage)
+			return
+		}
+		switch args[i] {
+		case "--status":
+			status = args[i+1]
+		case "--profile":
+			profile = args[i+1]
+		case "--limit":
+			num, err := strconv.Atoi(args[i+1])
+			if err != nil || num < 0 {
+				fmt.Println(crawlUsage)
+				return
+			}
+			limit = num
+		default:
+			fmt.Println(crawlUsage)
+			return
+		}
+		i++
+	}
+
+	com := command.CreateJobsCommand(apiRoute, userAdminToken, status, profile, limit)
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO RETRIVE ----------- ")
+		fmt.Println(err)
+		return
+	}
+	if len(com.Response.Targets) > 0 {
+		fmt.Println("Schedule:")
+		for _, target := range com.Response.Targets {
+			last := "never"
+			if target.LastSuccess != nil {
+				last = target.LastSuccess.Local().Format(time.RFC1123)
+			}
+			fmt.Printf(" - %s/%s  next: %s, last success: %s, failures: %d\n", target.Profile, target.Region,
+				target.NextRun.Local().Format(time.RFC1123), last, target.Failures)
+		}
+	}
+	fmt.Println("Jobs:")
+	if len(com.Response.Jobs) == 0 {
+		fmt.Println(" - none")
+	}
+	for _, job := range com.Response.Jobs {
+		printJob(job)
+	}
+}
+
+func printStreamEvent(event types.StreamEvent) {
+	name := event.SecretName
+	if name == "" {
+		name = event.SecretARN
+	}
+	at := event.Time.Local().Format(time.RFC1123)
+	switch event.Type {
+	case types.StreamGap:
+		fmt.Println(" ! " + event.Message)
+	case types.StreamAccessLog:
+		fmt.Printf("[%s] %-22s %s", at, event.EventName, name)
+		if event.AccessLog != nil {
+			fmt.Printf("  by %s", event.AccessLog.User)
+			if event.AccessLog.SourceIP != "" {
+				fmt.Printf(" (%s)", event.AccessLog.SourceIP)
+			}
+			if event.AccessLog.ErrorCode != "" {
+				fmt.Printf(" failed: %s", event.AccessLog.ErrorCode)
+			}
+		}
+		fmt.Println()
+	case types.StreamSecretChanged:
+		changes := make([]string, 0, len(event.Changes))
+		for _, change := range event.Changes {
+			changes = append(changes, fmt.Sprintf("%s: '%s' -> '%s'", change.Field, change.From, change.To))
+		}
+		fmt.Printf("[%s] %-22s %s  %s\n", at, event.Type, name, strings.Join(changes, ", "))
+	default:
+		fmt.Printf("[%s] %-22s %s  (%s/%s)\n", at, event.Type, name, event.Profile, event.Region)
+	}
+}
+
+func handleWatch(args []string) {
+	var arns, tags, events []string
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			fmt.Println(watchUsage)
+			return
+		}
+		switch args[i] {
+		case "--arn":
+			arns = append(arns, args[i+1])
+		case "--tag":
+			tags = append(tags, args[i+1])
+		case "--event":
+			events = append(events, args[i+1])
+		default:
+			fmt.Println(watchUsage)
+			return
+		}
+		i++
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		readInput()
+		close(stop)
+	}()
+	fmt.Println(" ---- Watching the events, press Enter to stop ---- ")
+
+	com := command.CreateWatchCommand(apiRoute, userAdminToken, arns, tags, events, stop)
+	com.OnEvent = printStreamEvent
+	com.OnDisconnect = func(err error) {
+		fmt.Println(" ! stream dropped, reconnecting:", err)
+	}
+	if err := com.Execute(); err != nil {
+		fmt.Println(" ----------- FAILED TO WATCH ----------- ")
+		fmt.Println(err)
+		// the Enter is still awaited
+		fmt.Println("Press Enter to continue")
+		<-stop
+	}
+}
+
+func handleCache(args []string) {
+	if len(args) < 2 {
+		fmt.Println(cacheUsage)
+		return
+	}
+	switch args[1] {
+	case "verify":
+		handleCacheVerify()
+	case "keys":
+		if len(args) > 3 {
+			fmt.Println(cacheUsage)
+			return
+		}
+		namespace := ""
+		if len(args) == 3 {
+			namespace = args[2]
+		}
+		handleCacheKeys(namespace)
+	case "show":
+		if len(args) != 3 {
+			fmt.Println(cacheUsage)
+			return
+		}
+		handleCacheShow(args[2])
+	case "invalidate":
+		handleCacheInvalidate(args[2:])
+	case "flush":
+		handleCacheFlush()
+	case "evictions":
+		if len(args) > 3 {
+			fmt.Println(cacheUsage)
+			return
+		}
+		handleCacheEvictions(args[2:])
+	case "migrations":
+		handleCacheMigrations()
+	case "export":
+		handleCacheExport(args[2:])
+	case "import":
+		handleCacheImport(args[2:])
+	case "events":
+		handleCacheEvents(args[2:])
+	default:
+		fmt.Println(cacheUsage)
+	}
+}
+
 func printBanner() {
 	fmt.Println(`
     _______    _______    _______    _______    _______   _________       _______    _______    _          _______    _______    _______    _______       
@@ -184,6 +1153,18 @@ func handleHelp() {
 	fmt.Println(loadUsage)
 	fmt.Println()
 	fmt.Println(getUsage)
+	fmt.Println()
+	fmt.Println(cacheUsage)
+	fmt.Println()
+	fmt.Println(auditUsage)
+	fmt.Println()
+	fmt.Println(policyUsage)
+	fmt.Println()
+	fmt.Println(inventoryUsage)
+	fmt.Println()
+	fmt.Println(crawlUsage)
+	fmt.Println()
+	fmt.Println(watchUsage)
 }
 
 func startCli() {
@@ -208,6 +1189,27 @@ func startCli() {
 		case "get":
 			handleGet(tokens)
 			continue
+		case "cache":
+			handleCache(tokens)
+			continue
+		case "audit":
+			handleAudit(tokens)
+			continue
+		case "policy":
+			handlePolicy(tokens)
+			continue
+		case "inventory":
+			handleInventory(tokens)
+			continue
+		case "crawl":
+			handleCrawl(tokens[1:])
+			continue
+		case "jobs":
+			handleJobs(tokens[1:])
+			continue
+		case "watch":
+			handleWatch(tokens[1:])
+			continue
 		case "clear":
 			handleClear()
 			continue
@@ -220,7 +1222,29 @@ func startCli() {
 	}
 }
 
+// Running a single command without the prompt (cli policy check <file>), the
+// keys are loaded from the environment or the .env file
+func runOnce(args []string) int {
+	godotenv.Load(".env")
+	userPublicKey = os.Getenv("public")
+	userSecretKey = os.Getenv("secret")
+	userAdminToken = os.Getenv("admin")
+	if region := os.Getenv("region"); region != "" {
+		userRegion = region
+	}
+
+	switch args[0] {
+	case "policy":
+		return handlePolicy(args)
+	}
+	fmt.Println("Only the policy command can run without the prompt")
+	return exitError
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runOnce(os.Args[1:]))
+	}
 	startCli()
 	os.Exit(0)
 }
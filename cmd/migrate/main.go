@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"golang-secret-manager/api/server/config"
+	"golang-secret-manager/utils/storage"
+	"os"
+)
+
+// Importing an existing persist-cache/ directory to the bolt backend, the
+// encryption keys are taken from the same environment of the server
+func main() {
+	cfg := config.Load()
+
+	from := flag.String("from", cfg.PersistCacheDir, "persist cache directory to import")
+	to := flag.String("to", cfg.BoltPath, "bolt file to import into")
+	compact := flag.Bool("compact", true, "compacting the bolt file after the import")
+	flag.Parse()
+
+	// the directory may be encrypted with the current key or a previous one
+	var providers []storage.KeyProvider
+	provider, err := config.BuildKeyProvider(cfg.Encryption)
+	if err != nil {
+		fmt.Println("failed to load cache encryption key:", err)
+		os.Exit(1)
+	}
+	if provider != nil {
+		providers = append(providers, provider)
+	}
+	previous, err := config.BuildPreviousKeyProviders(cfg.Encryption)
+	if err != nil {
+		fmt.Println("failed to load previous cache encryption key:", err)
+		os.Exit(1)
+	}
+	providers = append(providers, previous...)
+
+	boltCache, err := storage.NewBoltCache(storage.BoltCacheConfig{Path: *to})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer boltCache.Close()
+
+	if provider != nil {
+		if err := boltCache.SetEncryption(provider); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Printf(" ---- Importing %s into %s ---- \n", *from, *to)
+	report, err := storage.ImportPersistCache(*from, boltCache, providers...)
+	if err != nil {
+		fmt.Println("failed to import:", err)
+		os.Exit(1)
+	}
+	for key, reason := range report.Failed {
+		fmt.Println(" - failed " + key + ": " + reason)
+	}
+	fmt.Println("Imported entries:", report.Imported)
+
+	if *compact {
+		if err := boltCache.Compact(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	fmt.Println("Done! ")
+}
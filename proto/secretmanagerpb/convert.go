@@ -0,0 +1,302 @@
+package secretmanagerpb
+
+import (
+	"golang-secret-manager/types"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Converting between the messages and the types of the HTTP API, so the
+// server and the clients keep working with the same types. This file is not
+// generated
+
+// The zero time is sent as no timestamp
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func timeOf(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+func timestampPtr(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func timePtr(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+func NewSecret(secret types.Secret) *Secret {
+	return &Secret{
+		Name:            secret.Name,
+		Arn:             secret.ARN,
+		Version:         secret.Version,
+		CreatedAt:       timestamp(secret.CreatedAt),
+		LastAccessed:    timestamp(secret.LastAccessed),
+		RotationEnabled: secret.RotationEnabled,
+		RotationDays:    secret.RotationDays,
+		LastRotated:     timestamp(secret.LastRotated),
+		NextRotation:    timestamp(secret.NextRotation),
+		DeletedDate:     timestamp(secret.DeletedDate),
+		KmsKeyId:        secret.KMSKeyID,
+		Tags:            secret.Tags,
+	}
+}
+
+func (x *Secret) ToType() types.Secret {
+	return types.Secret{
+		Name:            x.GetName(),
+		ARN:             x.GetArn(),
+		Version:         x.GetVersion(),
+		CreatedAt:       timeOf(x.GetCreatedAt()),
+		LastAccessed:    timeOf(x.GetLastAccessed()),
+		RotationEnabled: x.GetRotationEnabled(),
+		RotationDays:    x.GetRotationDays(),
+		LastRotated:     timeOf(x.GetLastRotated()),
+		NextRotation:    timeOf(x.GetNextRotation()),
+		DeletedDate:     timeOf(x.GetDeletedDate()),
+		KMSKeyID:        x.GetKmsKeyId(),
+		Tags:            x.GetTags(),
+	}
+}
+
+func NewAccessLog(event types.AccessLog) *AccessLog {
+	return &AccessLog{
+		EventId:          event.EventID,
+		User:             event.User,
+		EventName:        event.EventName,
+		EventSource:      event.EventSource,
+		EventTime:        timestamp(event.EventTime),
+		SourceIp:         event.SourceIP,
+		UserAgent:        event.UserAgent,
+		UserIdentityType: event.UserIdentityType,
+		UserArn:          event.UserARN,
+		SessionIssuer:    event.SessionIssuer,
+		SessionName:      event.SessionName,
+		VersionStage:     event.VersionStage,
+		ErrorCode:        event.ErrorCode,
+		ReadOnly:         event.ReadOnly,
+	}
+}
+
+func (x *AccessLog) ToType() types.AccessLog {
+	return types.AccessLog{
+		EventID:          x.GetEventId(),
+		User:             x.GetUser(),
+		EventName:        x.GetEventName(),
+		EventSource:      x.GetEventSource(),
+		EventTime:        timeOf(x.GetEventTime()),
+		SourceIP:         x.GetSourceIp(),
+		UserAgent:        x.GetUserAgent(),
+		UserIdentityType: x.GetUserIdentityType(),
+		UserARN:          x.GetUserArn(),
+		SessionIssuer:    x.GetSessionIssuer(),
+		SessionName:      x.GetSessionName(),
+		VersionStage:     x.GetVersionStage(),
+		ErrorCode:        x.GetErrorCode(),
+		ReadOnly:         x.GetReadOnly(),
+	}
+}
+
+func NewAccessLogs(events []types.AccessLog) []*AccessLog {
+	list := make([]*AccessLog, 0, len(events))
+	for _, event := range events {
+		list = append(list, NewAccessLog(event))
+	}
+	return list
+}
+
+func AccessLogsToType(events []*AccessLog) []types.AccessLog {
+	list := make([]types.AccessLog, 0, len(events))
+	for _, event := range events {
+		list = append(list, event.ToType())
+	}
+	return list
+}
+
+func NewAccessLogFilter(filter *types.AccessLogFilter) *AccessLogFilter {
+	if filter == nil {
+		return nil
+	}
+	return &AccessLogFilter{
+		Since:                    timestampPtr(filter.Since),
+		Until:                    timestampPtr(filter.Until),
+		Events:                   filter.Events,
+		Users:                    filter.Users,
+		ExcludeServicePrincipals: filter.ExcludeServicePrincipals,
+	}
+}
+
+// nil when there is no filter
+func (x *AccessLogFilter) ToType() *types.AccessLogFilter {
+	if x == nil {
+		return nil
+	}
+	return &types.AccessLogFilter{
+		Since:                    timePtr(x.GetSince()),
+		Until:                    timePtr(x.GetUntil()),
+		Events:                   x.GetEvents(),
+		Users:                    x.GetUsers(),
+		ExcludeServicePrincipals: x.GetExcludeServicePrincipals(),
+	}
+}
+
+func NewGetAllSecretsResponse(response types.GetAllSecretsResponse) *GetAllSecretsResponse {
+	message := &GetAllSecretsResponse{
+		Secrets:    make([]*Secret, 0, len(response.Secrets)),
+		AccessLogs: make(map[string]*AccessLogList, len(response.AccessLog)),
+	}
+	for _, secret := range response.Secrets {
+		message.Secrets = append(message.Secrets, NewSecret(secret))
+	}
+	for arn, events := range response.AccessLog {
+		message.AccessLogs[arn] = &AccessLogList{Events: NewAccessLogs(events)}
+	}
+	return message
+}
+
+func (x *GetAllSecretsResponse) ToType() types.GetAllSecretsResponse {
+	response := types.GetAllSecretsResponse{
+		AccessLog: make(map[string][]types.AccessLog, len(x.GetAccessLogs())),
+	}
+	for _, secret := range x.GetSecrets() {
+		response.Secrets = append(response.Secrets, secret.ToType())
+	}
+	for arn, list := range x.GetAccessLogs() {
+		response.AccessLog[arn] = AccessLogsToType(list.GetEvents())
+	}
+	return response
+}
+
+func NewSecretReport(report types.SecretReport, text string) *SecretReport {
+	return &SecretReport{
+		Secret:    NewSecret(report.Secret),
+		AccessLog: NewAccessLogs(report.AccessLog),
+		Summary: &ReportSummary{
+			Events:     int32(report.Summary.Events),
+			Reads:      int32(report.Summary.Reads),
+			Errors:     int32(report.Summary.Errors),
+			Users:      report.Summary.Users,
+			FirstEvent: timestampPtr(report.Summary.FirstEvent),
+			LastEvent:  timestampPtr(report.Summary.LastEvent),
+		},
+		Filter:      NewAccessLogFilter(report.Filter),
+		GeneratedAt: timestamp(report.GeneratedAt),
+		Text:        text,
+	}
+}
+
+func (x *SecretReport) ToType() types.SecretReport {
+	summary := x.GetSummary()
+	return types.SecretReport{
+		Secret:    x.GetSecret().ToType(),
+		AccessLog: AccessLogsToType(x.GetAccessLog()),
+		Summary: types.ReportSummary{
+			Events:     int(summary.GetEvents()),
+			Reads:      int(summary.GetReads()),
+			Errors:     int(summary.GetErrors()),
+			Users:      summary.GetUsers(),
+			FirstEvent: timePtr(summary.GetFirstEvent()),
+			LastEvent:  timePtr(summary.GetLastEvent()),
+		},
+		Filter:      x.GetFilter().ToType(),
+		GeneratedAt: timeOf(x.GetGeneratedAt()),
+	}
+}
+
+func NewInventorySecret(secret types.InventorySecret) *InventorySecret {
+	return &InventorySecret{
+		Arn:             secret.ARN,
+		Name:            secret.Name,
+		Version:         secret.Version,
+		CreatedAt:       timestamp(secret.CreatedAt),
+		RotationEnabled: secret.RotationEnabled,
+		RotationDays:    secret.RotationDays,
+		LastRotated:     timestamp(secret.LastRotated),
+		DeletedDate:     timestamp(secret.DeletedDate),
+		KmsKeyId:        secret.KMSKeyID,
+		Tags:            secret.Tags,
+	}
+}
+
+func (x *InventorySecret) ToType() types.InventorySecret {
+	return types.InventorySecret{
+		ARN:             x.GetArn(),
+		Name:            x.GetName(),
+		Version:         x.GetVersion(),
+		CreatedAt:       timeOf(x.GetCreatedAt()),
+		RotationEnabled: x.GetRotationEnabled(),
+		RotationDays:    x.GetRotationDays(),
+		LastRotated:     timeOf(x.GetLastRotated()),
+		DeletedDate:     timeOf(x.GetDeletedDate()),
+		KMSKeyID:        x.GetKmsKeyId(),
+		Tags:            x.GetTags(),
+	}
+}
+
+func NewStreamEvent(event types.StreamEvent) *StreamEvent {
+	message := &StreamEvent{
+		Id:         event.ID,
+		Type:       event.Type,
+		Time:       timestamp(event.Time),
+		SecretArn:  event.SecretARN,
+		SecretName: event.SecretName,
+		Tags:       event.Tags,
+		EventName:  event.EventName,
+		Profile:    event.Profile,
+		Region:     event.Region,
+		Message:    event.Message,
+	}
+	if event.AccessLog != nil {
+		message.AccessLog = NewAccessLog(*event.AccessLog)
+	}
+	if event.Secret != nil {
+		message.Secret = NewInventorySecret(*event.Secret)
+	}
+	for _, change := range event.Changes {
+		message.Changes = append(message.Changes, &FieldChange{Field: change.Field, From: change.From, To: change.To})
+	}
+	return message
+}
+
+func (x *StreamEvent) ToType() types.StreamEvent {
+	event := types.StreamEvent{
+		ID:         x.GetId(),
+		Type:       x.GetType(),
+		Time:       timeOf(x.GetTime()),
+		SecretARN:  x.GetSecretArn(),
+		SecretName: x.GetSecretName(),
+		Tags:       x.GetTags(),
+		EventName:  x.GetEventName(),
+		Profile:    x.GetProfile(),
+		Region:     x.GetRegion(),
+		Message:    x.GetMessage(),
+	}
+	if x.GetAccessLog() != nil {
+		accessLog := x.GetAccessLog().ToType()
+		event.AccessLog = &accessLog
+	}
+	if x.GetSecret() != nil {
+		secret := x.GetSecret().ToType()
+		event.Secret = &secret
+	}
+	for _, change := range x.GetChanges() {
+		event.Changes = append(event.Changes, types.InventoryFieldChange{Field: change.GetField(), From: change.GetFrom(), To: change.GetTo()})
+	}
+	return event
+}
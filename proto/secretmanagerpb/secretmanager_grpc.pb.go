@@ -0,0 +1,367 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: secretmanager.proto
+
+package secretmanagerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	SecretManager_GetAllSecrets_FullMethodName   = "/secretmanager.v1.SecretManager/GetAllSecrets"
+	SecretManager_GetSecret_FullMethodName       = "/secretmanager.v1.SecretManager/GetSecret"
+	SecretManager_GetReport_FullMethodName       = "/secretmanager.v1.SecretManager/GetReport"
+	SecretManager_GetAccessLog_FullMethodName    = "/secretmanager.v1.SecretManager/GetAccessLog"
+	SecretManager_StreamInventory_FullMethodName = "/secretmanager.v1.SecretManager/StreamInventory"
+	SecretManager_StreamEvents_FullMethodName    = "/secretmanager.v1.SecretManager/StreamEvents"
+)
+
+// SecretManagerClient is the client API for SecretManager service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SecretManagerClient interface {
+	// The secrets of the credential with their access logs (POST /secrets),
+	// served from the cache when everything is cached
+	GetAllSecrets(ctx context.Context, in *GetAllSecretsRequest, opts ...grpc.CallOption) (*GetAllSecretsResponse, error)
+	// A single secret (its metadata, the value of a secret is never returned)
+	GetSecret(ctx context.Context, in *GetSecretRequest, opts ...grpc.CallOption) (*Secret, error)
+	// The report of a secret (POST /reports)
+	GetReport(ctx context.Context, in *GetReportRequest, opts ...grpc.CallOption) (*SecretReport, error)
+	// The access log of a secret (POST /access-logs)
+	GetAccessLog(ctx context.Context, in *GetAccessLogRequest, opts ...grpc.CallOption) (*GetAccessLogResponse, error)
+	// The secrets of a saved inventory snapshot or of a live crawl, one
+	// message per secret (admin)
+	StreamInventory(ctx context.Context, in *StreamInventoryRequest, opts ...grpc.CallOption) (SecretManager_StreamInventoryClient, error)
+	// The accesses and the changes of the secrets as they are found, like
+	// GET /v1/events (admin)
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (SecretManager_StreamEventsClient, error)
+}
+
+type secretManagerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSecretManagerClient(cc grpc.ClientConnInterface) SecretManagerClient {
+	return &secretManagerClient{cc}
+}
+
+func (c *secretManagerClient) GetAllSecrets(ctx context.Context, in *GetAllSecretsRequest, opts ...grpc.CallOption) (*GetAllSecretsResponse, error) {
+	out := new(GetAllSecretsResponse)
+	err := c.cc.Invoke(ctx, SecretManager_GetAllSecrets_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secretManagerClient) GetSecret(ctx context.Context, in *GetSecretRequest, opts ...grpc.CallOption) (*Secret, error) {
+	out := new(Secret)
+	err := c.cc.Invoke(ctx, SecretManager_GetSecret_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secretManagerClient) GetReport(ctx context.Context, in *GetReportRequest, opts ...grpc.CallOption) (*SecretReport, error) {
+	out := new(SecretReport)
+	err := c.cc.Invoke(ctx, SecretManager_GetReport_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secretManagerClient) GetAccessLog(ctx context.Context, in *GetAccessLogRequest, opts ...grpc.CallOption) (*GetAccessLogResponse, error) {
+	out := new(GetAccessLogResponse)
+	err := c.cc.Invoke(ctx, SecretManager_GetAccessLog_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secretManagerClient) StreamInventory(ctx context.Context, in *StreamInventoryRequest, opts ...grpc.CallOption) (SecretManager_StreamInventoryClient, error) {
+	stream, err := c.cc.NewStream(ctx, &SecretManager_ServiceDesc.Streams[0], SecretManager_StreamInventory_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &secretManagerStreamInventoryClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SecretManager_StreamInventoryClient interface {
+	Recv() (*InventorySecret, error)
+	grpc.ClientStream
+}
+
+type secretManagerStreamInventoryClient struct {
+	grpc.ClientStream
+}
+
+func (x *secretManagerStreamInventoryClient) Recv() (*InventorySecret, error) {
+	m := new(InventorySecret)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *secretManagerClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (SecretManager_StreamEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &SecretManager_ServiceDesc.Streams[1], SecretManager_StreamEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &secretManagerStreamEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SecretManager_StreamEventsClient interface {
+	Recv() (*StreamEvent, error)
+	grpc.ClientStream
+}
+
+type secretManagerStreamEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *secretManagerStreamEventsClient) Recv() (*StreamEvent, error) {
+	m := new(StreamEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SecretManagerServer is the server API for SecretManager service.
+// All implementations must embed UnimplementedSecretManagerServer
+// for forward compatibility
+type SecretManagerServer interface {
+	// The secrets of the credential with their access logs (POST /secrets),
+	// served from the cache when everything is cached
+	GetAllSecrets(context.Context, *GetAllSecretsRequest) (*GetAllSecretsResponse, error)
+	// A single secret (its metadata, the value of a secret is never returned)
+	GetSecret(context.Context, *GetSecretRequest) (*Secret, error)
+	// The report of a secret (POST /reports)
+	GetReport(context.Context, *GetReportRequest) (*SecretReport, error)
+	// The access log of a secret (POST /access-logs)
+	GetAccessLog(context.Context, *GetAccessLogRequest) (*GetAccessLogResponse, error)
+	// The secrets of a saved inventory snapshot or of a live crawl, one
+	// message per secret (admin)
+	StreamInventory(*StreamInventoryRequest, SecretManager_StreamInventoryServer) error
+	// The accesses and the changes of the secrets as they are found, like
+	// GET /v1/events (admin)
+	StreamEvents(*StreamEventsRequest, SecretManager_StreamEventsServer) error
+	mustEmbedUnimplementedSecretManagerServer()
+}
+
+// UnimplementedSecretManagerServer must be embedded to have forward compatible implementations.
+type UnimplementedSecretManagerServer struct {
+}
+
+func (UnimplementedSecretManagerServer) GetAllSecrets(context.Context, *GetAllSecretsRequest) (*GetAllSecretsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAllSecrets not implemented")
+}
+func (UnimplementedSecretManagerServer) GetSecret(context.Context, *GetSecretRequest) (*Secret, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSecret not implemented")
+}
+func (UnimplementedSecretManagerServer) GetReport(context.Context, *GetReportRequest) (*SecretReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetReport not implemented")
+}
+func (UnimplementedSecretManagerServer) GetAccessLog(context.Context, *GetAccessLogRequest) (*GetAccessLogResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAccessLog not implemented")
+}
+func (UnimplementedSecretManagerServer) StreamInventory(*StreamInventoryRequest, SecretManager_StreamInventoryServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamInventory not implemented")
+}
+func (UnimplementedSecretManagerServer) StreamEvents(*StreamEventsRequest, SecretManager_StreamEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedSecretManagerServer) mustEmbedUnimplementedSecretManagerServer() {}
+
+// UnsafeSecretManagerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SecretManagerServer will
+// result in compilation errors.
+type UnsafeSecretManagerServer interface {
+	mustEmbedUnimplementedSecretManagerServer()
+}
+
+func RegisterSecretManagerServer(s grpc.ServiceRegistrar, srv SecretManagerServer) {
+	s.RegisterService(&SecretManager_ServiceDesc, srv)
+}
+
+func _SecretManager_GetAllSecrets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAllSecretsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretManagerServer).GetAllSecrets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SecretManager_GetAllSecrets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretManagerServer).GetAllSecrets(ctx, req.(*GetAllSecretsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SecretManager_GetSecret_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSecretRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretManagerServer).GetSecret(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SecretManager_GetSecret_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretManagerServer).GetSecret(ctx, req.(*GetSecretRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SecretManager_GetReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretManagerServer).GetReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SecretManager_GetReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretManagerServer).GetReport(ctx, req.(*GetReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SecretManager_GetAccessLog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAccessLogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretManagerServer).GetAccessLog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SecretManager_GetAccessLog_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretManagerServer).GetAccessLog(ctx, req.(*GetAccessLogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SecretManager_StreamInventory_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamInventoryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SecretManagerServer).StreamInventory(m, &secretManagerStreamInventoryServer{stream})
+}
+
+type SecretManager_StreamInventoryServer interface {
+	Send(*InventorySecret) error
+	grpc.ServerStream
+}
+
+type secretManagerStreamInventoryServer struct {
+	grpc.ServerStream
+}
+
+func (x *secretManagerStreamInventoryServer) Send(m *InventorySecret) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _SecretManager_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SecretManagerServer).StreamEvents(m, &secretManagerStreamEventsServer{stream})
+}
+
+type SecretManager_StreamEventsServer interface {
+	Send(*StreamEvent) error
+	grpc.ServerStream
+}
+
+type secretManagerStreamEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *secretManagerStreamEventsServer) Send(m *StreamEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// SecretManager_ServiceDesc is the grpc.ServiceDesc for SecretManager service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SecretManager_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "secretmanager.v1.SecretManager",
+	HandlerType: (*SecretManagerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetAllSecrets",
+			Handler:    _SecretManager_GetAllSecrets_Handler,
+		},
+		{
+			MethodName: "GetSecret",
+			Handler:    _SecretManager_GetSecret_Handler,
+		},
+		{
+			MethodName: "GetReport",
+			Handler:    _SecretManager_GetReport_Handler,
+		},
+		{
+			MethodName: "GetAccessLog",
+			Handler:    _SecretManager_GetAccessLog_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamInventory",
+			Handler:       _SecretManager_StreamInventory_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _SecretManager_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "secretmanager.proto",
+}
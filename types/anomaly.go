@@ -0,0 +1,51 @@
+package types
+
+import "time"
+
+// Kinds of the access anomalies
+const (
+	AnomalyNewPrincipal       = "new-principal"
+	AnomalyVolumeSpike        = "volume-spike"
+	AnomalyUnusualHour        = "unusual-hour"
+	AnomalyUnusualSourceIP    = "unusual-source-ip"
+	AnomalyHumanOnServiceOnly = "human-on-service-secret"
+)
+
+// The normal access of a secret, built from its access log before the
+// detection window
+type AccessBaseline struct {
+	Events       int            `json:"events"`
+	Days         float64        `json:"days"`
+	DailyAverage float64        `json:"daily_average"`
+	Principals   map[string]int `json:"principals"`
+	SourceIPs    map[string]int `json:"source_ips"`
+	Hours        [24]int        `json:"hours"`
+
+	// Only roles and AWS services read the secret
+	ServiceOnly bool `json:"service_only"`
+}
+
+// An access of the detection window that doesn't match the baseline
+type Anomaly struct {
+	SecretARN  string    `json:"secret_arn"`
+	SecretName string    `json:"secret_name,omitempty"`
+	Kind       string    `json:"kind"`
+	Severity   string    `json:"severity"`
+	Message    string    `json:"message"`
+	Principal  string    `json:"principal,omitempty"`
+	SourceIP   string    `json:"source_ip,omitempty"`
+	EventID    string    `json:"event_id,omitempty"`
+	EventTime  time.Time `json:"event_time"`
+}
+
+type AnomalyReport struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Window      string    `json:"window"`
+	Secrets     int       `json:"secrets"`
+	Anomalies   []Anomaly `json:"anomalies"`
+
+	// The baseline of every secret that had enough events to build one, the
+	// other secrets are not checked
+	Baselines            map[string]AccessBaseline `json:"baselines"`
+	InsufficientBaseline []string                  `json:"insufficient_baseline"`
+}
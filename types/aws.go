@@ -11,14 +11,46 @@ type Secret struct {
 	Version      string
 	CreatedAt    time.Time
 	LastAccessed time.Time
+
+	// Returned by the listing of the secrets only
+	RotationEnabled bool
+	RotationDays    int64
+	LastRotated     time.Time
+	NextRotation    time.Time
+	DeletedDate     time.Time
+	KMSKeyID        string
+	Tags            map[string]string
 }
 
 // Holding the information of the accesslog
 type AccessLog struct {
+	EventID     string
 	User        string
 	EventName   string
 	EventSource string
 	EventTime   time.Time
+
+	SourceIP         string
+	UserAgent        string
+	UserIdentityType string
+	UserARN          string
+
+	// The role that issued the session when the caller assumed a role
+	SessionIssuer string
+	SessionName   string
+
+	// The version stage that was requested (AWSCURRENT, AWSPREVIOUS...)
+	VersionStage string
+	ErrorCode    string
+	ReadOnly     bool
+}
+
+// The access log of a secret that was synced from CloudTrail, the next sync
+// only asks for the events since NewestEventTime
+type AccessLogSync struct {
+	NewestEventTime time.Time
+	SyncedAt        time.Time
+	Events          []AccessLog
 }
 
 // Struct to store only 1 secret with it's paring accesslog
@@ -0,0 +1,47 @@
+package types
+
+import "time"
+
+// A single CloudTrail record, as it is delivered in the CloudTrail log files,
+// in the detail of an EventBridge event and in the CloudTrailEvent field of
+// LookupEvents. Only the fields that are used are decoded
+type CloudTrailEvent struct {
+	EventID             string                 `json:"eventID"`
+	EventTime           time.Time              `json:"eventTime"`
+	EventName           string                 `json:"eventName"`
+	EventSource         string                 `json:"eventSource"`
+	EventType           string                 `json:"eventType"`
+	AWSRegion           string                 `json:"awsRegion"`
+	SourceIPAddress     string                 `json:"sourceIPAddress"`
+	UserAgent           string                 `json:"userAgent"`
+	ErrorCode           string                 `json:"errorCode"`
+	ReadOnly            *bool                  `json:"readOnly"`
+	UserIdentity        CloudTrailUserIdentity `json:"userIdentity"`
+	RequestParameters   map[string]interface{} `json:"requestParameters"`
+	ResponseElements    map[string]interface{} `json:"responseElements"`
+	AdditionalEventData map[string]interface{} `json:"additionalEventData"`
+	Resources           []CloudTrailResource   `json:"resources"`
+}
+
+type CloudTrailUserIdentity struct {
+	Type           string                    `json:"type"`
+	PrincipalID    string                    `json:"principalId"`
+	ARN            string                    `json:"arn"`
+	AccountID      string                    `json:"accountId"`
+	UserName       string                    `json:"userName"`
+	InvokedBy      string                    `json:"invokedBy"`
+	SessionContext *CloudTrailSessionContext `json:"sessionContext"`
+}
+
+type CloudTrailSessionContext struct {
+	SessionIssuer struct {
+		Type     string `json:"type"`
+		ARN      string `json:"arn"`
+		UserName string `json:"userName"`
+	} `json:"sessionIssuer"`
+}
+
+type CloudTrailResource struct {
+	ARN  string `json:"ARN"`
+	Type string `json:"type"`
+}
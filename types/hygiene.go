@@ -0,0 +1,55 @@
+package types
+
+import "time"
+
+// Severities of the findings, from the most severe
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+	SeverityInfo   = "info"
+)
+
+// Checks of the hygiene report
+const (
+	CheckNeverAccessed    = "never-accessed"
+	CheckUnused           = "unused"
+	CheckRotationDisabled = "rotation-disabled"
+	CheckRotationOverdue  = "rotation-overdue"
+	CheckPendingDeletion  = "pending-deletion"
+	CheckMissingTags      = "missing-tags"
+	CheckDefaultKMSKey    = "default-kms-key"
+)
+
+type HygieneRequest struct {
+	PublicKey string `json:"public_key"`
+	SecretKey string `json:"secret_key"`
+	Region    string `json:"region"`
+
+	// Secrets that weren't accessed for this many days are unused (default 90)
+	UnusedDays int `json:"unused_days,omitempty"`
+
+	// Tags that every secret must have
+	RequiredTags []string `json:"required_tags,omitempty"`
+}
+
+// A problem of a single secret
+type HygieneFinding struct {
+	SecretARN  string `json:"secret_arn"`
+	SecretName string `json:"secret_name"`
+	Check      string `json:"check"`
+	Severity   string `json:"severity"`
+	Message    string `json:"message"`
+}
+
+type HygieneReport struct {
+	Region       string           `json:"region"`
+	GeneratedAt  time.Time        `json:"generated_at"`
+	UnusedDays   int              `json:"unused_days"`
+	RequiredTags []string         `json:"required_tags"`
+	Secrets      int              `json:"secrets"`
+	Findings     []HygieneFinding `json:"findings"`
+
+	// Number of findings of each severity
+	Severities map[string]int `json:"severities"`
+}
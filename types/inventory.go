@@ -0,0 +1,93 @@
+package types
+
+import "time"
+
+// The state of a secret that is saved in an inventory snapshot, the access
+// times are left out since they change all the time
+type InventorySecret struct {
+	ARN             string            `json:"arn"`
+	Name            string            `json:"name"`
+	Version         string            `json:"version"`
+	CreatedAt       time.Time         `json:"created_at"`
+	RotationEnabled bool              `json:"rotation_enabled"`
+	RotationDays    int64             `json:"rotation_days"`
+	LastRotated     time.Time         `json:"last_rotated"`
+	DeletedDate     time.Time         `json:"deleted_date"`
+	KMSKeyID        string            `json:"kms_key_id"`
+	Tags            map[string]string `json:"tags"`
+}
+
+// The secrets of a credential at a point in time
+type InventorySnapshot struct {
+	ID      string    `json:"id"`
+	TakenAt time.Time `json:"taken_at"`
+	Region  string    `json:"region"`
+	Account string    `json:"account"`
+
+	// schedule, manual or live (a live snapshot is never saved)
+	Trigger string            `json:"trigger"`
+	Secrets []InventorySecret `json:"secrets"`
+}
+
+// A snapshot without its secrets, for listing them
+type InventorySnapshotInfo struct {
+	ID      string    `json:"id"`
+	TakenAt time.Time `json:"taken_at"`
+	Region  string    `json:"region"`
+	Account string    `json:"account"`
+	Trigger string    `json:"trigger"`
+	Secrets int       `json:"secrets"`
+}
+
+const (
+	InventoryTriggerSchedule = "schedule"
+	InventoryTriggerManual   = "manual"
+	InventoryTriggerLive     = "live"
+)
+
+type InventorySnapshotList struct {
+	Snapshots []InventorySnapshotInfo `json:"snapshots"`
+}
+
+// Taking a snapshot now, the credential of the server is used when the
+// request has none
+type InventorySnapshotRequest struct {
+	PublicKey string `json:"public_key"`
+	SecretKey string `json:"secret_key"`
+	Region    string `json:"region"`
+}
+
+// Comparing two snapshots. From and To are a snapshot id, "latest" or a date
+// (the newest snapshot taken by then). When To is empty or "live" the secrets
+// are crawled now with the credential of the request
+type InventoryDiffRequest struct {
+	PublicKey string `json:"public_key"`
+	SecretKey string `json:"secret_key"`
+	Region    string `json:"region"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+}
+
+type InventoryFieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type InventoryChange struct {
+	ARN     string                 `json:"arn"`
+	Name    string                 `json:"name"`
+	Changes []InventoryFieldChange `json:"changes"`
+}
+
+type InventoryDiff struct {
+	From      InventorySnapshotInfo `json:"from"`
+	To        InventorySnapshotInfo `json:"to"`
+	Added     []InventorySecret     `json:"added"`
+	Removed   []InventorySecret     `json:"removed"`
+	Changed   []InventoryChange     `json:"changed"`
+	Unchanged int                   `json:"unchanged"`
+
+	// Set when the snapshots are of another account or region
+	Warning string `json:"warning,omitempty"`
+}
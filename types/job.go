@@ -0,0 +1,64 @@
+package types
+
+import "time"
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+const (
+	JobTriggerSchedule = "schedule"
+	JobTriggerManual   = "manual"
+)
+
+// A single crawl of the secrets of a profile in a region
+type CrawlJob struct {
+	ID      string `json:"id"`
+	Profile string `json:"profile"`
+	Region  string `json:"region"`
+	Trigger string `json:"trigger"`
+	Status  string `json:"status"`
+
+	QueuedAt   time.Time  `json:"queued_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Duration   string     `json:"duration,omitempty"`
+
+	Secrets int `json:"secrets"`
+
+	// The access log events of all the secrets
+	AccessLogEvents int      `json:"access_log_events"`
+	Errors          []string `json:"errors"`
+}
+
+// The schedule of a profile in a region
+type CrawlTarget struct {
+	Profile     string     `json:"profile"`
+	Region      string     `json:"region"`
+	NextRun     time.Time  `json:"next_run"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+
+	// Failed crawls since the last one that succeeded
+	Failures int `json:"failures"`
+}
+
+type JobList struct {
+	Jobs    []CrawlJob    `json:"jobs"`
+	Targets []CrawlTarget `json:"targets"`
+}
+
+// Crawling now a configured profile (all its regions or the one of the
+// request), or the credential of the request
+type CrawlRequest struct {
+	Profile   string `json:"profile,omitempty"`
+	PublicKey string `json:"public_key,omitempty"`
+	SecretKey string `json:"secret_key,omitempty"`
+	Region    string `json:"region,omitempty"`
+}
+
+type CrawlResponse struct {
+	Jobs []CrawlJob `json:"jobs"`
+}
@@ -17,4 +17,5 @@ type FromGetReportMiddlewareToHandler struct {
 	SecretKey        string
 	SecretID         string
 	Region           string
+	Filter           *AccessLogFilter
 }
@@ -0,0 +1,41 @@
+package types
+
+import "time"
+
+// The kinds of the notifications
+const (
+	NotifySecretCreated    = "secret_created"
+	NotifySecretDeleted    = "secret_deleted"
+	NotifySecretRotated    = "secret_rotated"
+	NotifyUnexpectedAccess = "unexpected_access"
+	NotifyRotationOverdue  = "rotation_overdue"
+)
+
+// A change or a finding about a secret that is sent to the webhooks
+type Notification struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	Severity   string    `json:"severity"`
+	SecretARN  string    `json:"secret_arn"`
+	SecretName string    `json:"secret_name"`
+	Message    string    `json:"message"`
+	OccurredAt time.Time `json:"occurred_at"`
+
+	// The principal of an access, and the crawl that found it
+	Principal string `json:"principal,omitempty"`
+	Profile   string `json:"profile,omitempty"`
+	Region    string `json:"region,omitempty"`
+
+	// The tags of the secret, used by the routing rules
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// A notification that couldn't be delivered, saved to the dead letter file
+type DeadLetter struct {
+	Webhook      string       `json:"webhook"`
+	URL          string       `json:"url"`
+	Attempts     int          `json:"attempts"`
+	LastError    string       `json:"last_error"`
+	FailedAt     time.Time    `json:"failed_at"`
+	Notification Notification `json:"notification"`
+}
@@ -0,0 +1,37 @@
+package types
+
+import "time"
+
+// Checking the secrets of the credential against a policy, the policy is the
+// YAML document of the rules. When it is empty the policy file of the server
+// is used
+type PolicyCheckRequest struct {
+	PublicKey string `json:"public_key"`
+	SecretKey string `json:"secret_key"`
+	Region    string `json:"region"`
+	Policy    string `json:"policy,omitempty"`
+}
+
+// The result of a single rule on a single secret
+type PolicyResult struct {
+	RuleID      string   `json:"rule_id"`
+	Description string   `json:"description,omitempty"`
+	Severity    string   `json:"severity"`
+	SecretARN   string   `json:"secret_arn"`
+	SecretName  string   `json:"secret_name"`
+	Passed      bool     `json:"passed"`
+	Evidence    []string `json:"evidence"`
+}
+
+type PolicyReport struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Rules       int       `json:"rules"`
+	Secrets     int       `json:"secrets"`
+	Checked     int       `json:"checked"`
+	Passed      int       `json:"passed"`
+	Failed      int       `json:"failed"`
+
+	// Only the failed results, every result is in Results
+	Violations []PolicyResult `json:"violations"`
+	Results    []PolicyResult `json:"results"`
+}
@@ -0,0 +1,23 @@
+package types
+
+import "time"
+
+// The report of a single secret, it is rendered to the format that the
+// client asked for (text, JSON, Markdown, HTML or CSV)
+type SecretReport struct {
+	Secret      Secret           `json:"secret"`
+	AccessLog   []AccessLog      `json:"access_log"`
+	Summary     ReportSummary    `json:"summary"`
+	Filter      *AccessLogFilter `json:"filter,omitempty"`
+	GeneratedAt time.Time        `json:"generated_at"`
+}
+
+// Counters of the access log of the report
+type ReportSummary struct {
+	Events     int        `json:"events"`
+	Reads      int        `json:"reads"`
+	Errors     int        `json:"errors"`
+	Users      []string   `json:"users"`
+	FirstEvent *time.Time `json:"first_event,omitempty"`
+	LastEvent  *time.Time `json:"last_event,omitempty"`
+}
@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 type GetAllSecretsRequest struct {
 	PublicKey string `json:"public_key"`
 	SecretKey string `json:"secret_key"`
@@ -7,10 +9,41 @@ type GetAllSecretsRequest struct {
 }
 
 type GetReportRequest struct {
+	PublicKey string           `json:"public_key"`
+	SecretKey string           `json:"secret_key"`
+	Region    string           `json:"region"`
+	SecretID  string           `json:"secret_id"`
+	Filter    *AccessLogFilter `json:"filter,omitempty"`
+}
+
+type GetAccessLogRequest struct {
+	PublicKey string           `json:"public_key"`
+	SecretKey string           `json:"secret_key"`
+	Region    string           `json:"region"`
+	SecretID  string           `json:"secret_id"`
+	Filter    *AccessLogFilter `json:"filter,omitempty"`
+}
+
+// Selecting the access log events of a report, the empty fields are not
+// filtering anything
+type AccessLogFilter struct {
+	Since *time.Time `json:"since,omitempty"`
+	Until *time.Time `json:"until,omitempty"`
+
+	// Event names (GetSecretValue...) and users (name or ARN)
+	Events []string `json:"events,omitempty"`
+	Users  []string `json:"users,omitempty"`
+
+	// Dropping the calls that AWS services made on behalf of the user
+	ExcludeServicePrincipals bool `json:"exclude_service_principals,omitempty"`
+}
+
+// Invalidating a single secret by its ARN or everything that was cached for
+// the credential of the public key
+type CacheInvalidateRequest struct {
+	ARN       string `json:"arn"`
 	PublicKey string `json:"public_key"`
-	SecretKey string `json:"secret_key"`
-	Region    string `json:"region"`
-	SecretID  string `json:"secret_id"`
+	DryRun    bool   `json:"dry_run"`
 }
 
 // When passing the value of the context to another handler/middleware
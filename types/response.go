@@ -1,10 +1,118 @@
 package types
 
+import "time"
+
 type GetAllSecretsResponse struct {
 	Secrets   []Secret               `json:"secrets"`
 	AccessLog map[string][]AccessLog `json:"access_logs"`
 }
 
+// The text report is kept for the older clients, the structured report is
+// in Data
 type GetReportResponse struct {
-	Report string `json:"report"`
+	Report string        `json:"report"`
+	Data   *SecretReport `json:"data,omitempty"`
+}
+
+type GetAccessLogResponse struct {
+	SecretID  string      `json:"secret_id"`
+	AccessLog []AccessLog `json:"access_log"`
+}
+
+type GetMetricsResponse struct {
+	Cache CacheStats `json:"cache"`
+}
+
+// Counters of the fast cache
+type CacheStats struct {
+	Hits        uint64 `json:"hits"`
+	Misses      uint64 `json:"misses"`
+	Evictions   uint64 `json:"evictions"`
+	Expirations uint64 `json:"expirations"`
+	Entries     int    `json:"entries"`
+	UsedBytes   int64  `json:"used_bytes"`
+	MaxEntries  int    `json:"max_entries"`
+	MaxBytes    int64  `json:"max_bytes"`
+}
+
+// Result of checking that the persist cache can be decrypted
+type CacheVerifyReport struct {
+	OK        bool              `json:"ok"`
+	KeyID     string            `json:"key_id"`
+	Total     int               `json:"total"`
+	Encrypted int               `json:"encrypted"`
+	Plain     int               `json:"plain"`
+	Failed    map[string]string `json:"failed"`
+}
+
+// Information about a single key of the cache
+type CacheEntryInfo struct {
+	Key       string      `json:"key"`
+	Namespace string      `json:"namespace"`
+	InMemory  bool        `json:"in_memory"`
+	Dirty     bool        `json:"dirty"`
+	SizeBytes int64       `json:"size_bytes"`
+	SetAt     *time.Time  `json:"set_at,omitempty"`
+	Age       string      `json:"age,omitempty"`
+	ExpiresAt *time.Time  `json:"expires_at,omitempty"`
+	Value     interface{} `json:"value,omitempty"`
+}
+
+// The cache keys grouped by their namespace
+type GetCacheKeysResponse struct {
+	Keys map[string][]string `json:"keys"`
+}
+
+type CacheInvalidateResponse struct {
+	DryRun bool     `json:"dry_run"`
+	Keys   []string `json:"keys"`
+}
+
+type CacheFlushResponse struct {
+	Flushed int `json:"flushed"`
+}
+
+// A key that would be removed from the fast cache
+type CacheEviction struct {
+	Key       string `json:"key"`
+	Namespace string `json:"namespace"`
+	Reason    string `json:"reason"`
+	Dirty     bool   `json:"dirty"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+type CacheEvictionPreviewResponse struct {
+	Evictions []CacheEviction `json:"evictions"`
+}
+
+// Result of importing a cache snapshot
+type CacheImportReport struct {
+	Mode          string    `json:"mode"`
+	SchemaVersion int       `json:"schema_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	Imported      int       `json:"imported"`
+	Removed       int       `json:"removed"`
+
+	// Entries that expired since the snapshot was exported, they are not
+	// imported
+	Expired int `json:"expired"`
+}
+
+// Result of migrating the cache entries to the current schema versions when
+// they were loaded, the failed entries are left in the persist layer as they
+// are
+type CacheMigrationReport struct {
+	Total    int               `json:"total"`
+	Current  int               `json:"current"`
+	Migrated int               `json:"migrated"`
+	Failed   map[string]string `json:"failed"`
+}
+
+// Result of applying CloudTrail events to the cache
+type EventIngestResponse struct {
+	Received int      `json:"received"`
+	Applied  int      `json:"applied"`
+	Ignored  int      `json:"ignored"`
+	Failed   int      `json:"failed"`
+	Keys     []string `json:"keys"`
 }
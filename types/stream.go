@@ -0,0 +1,38 @@
+package types
+
+import "time"
+
+// The kinds of the events of the stream
+const (
+	StreamAccessLog     = "access_log"
+	StreamSecretCreated = "secret_created"
+	StreamSecretDeleted = "secret_deleted"
+	StreamSecretChanged = "secret_changed"
+
+	// Sent first when the events after the Last-Event-ID are no longer kept,
+	// the client missed some events
+	StreamGap = "gap"
+)
+
+// An access or a change of a secret as it is discovered, sent to the clients
+// of /v1/events
+type StreamEvent struct {
+	ID         uint64            `json:"id"`
+	Type       string            `json:"type"`
+	Time       time.Time         `json:"time"`
+	SecretARN  string            `json:"secret_arn,omitempty"`
+	SecretName string            `json:"secret_name,omitempty"`
+	Tags       map[string]string `json:"tags,omitempty"`
+
+	// The event name of an access (GetSecretValue, ...)
+	EventName string `json:"event_name,omitempty"`
+
+	// The crawl that found a change
+	Profile string `json:"profile,omitempty"`
+	Region  string `json:"region,omitempty"`
+
+	AccessLog *AccessLog             `json:"access_log,omitempty"`
+	Secret    *InventorySecret       `json:"secret,omitempty"`
+	Changes   []InventoryFieldChange `json:"changes,omitempty"`
+	Message   string                 `json:"message,omitempty"`
+}
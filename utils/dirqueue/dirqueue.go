@@ -0,0 +1,111 @@
+package dirqueue
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sub directories of the processed and failed messages
+const (
+	ProcessedDir = "processed"
+	FailedDir    = "failed"
+)
+
+// Poller is a queue over a local directory, every file is a message. The
+// files are handled by their name order and then moved to the processed or
+// failed dir, so a message is handled once. Writers should create the file
+// under another name (starting with ".") and rename it when it is complete
+type Poller struct {
+	Dir      string
+	Interval time.Duration
+	Handle   func(name string, data []byte) error
+	Logger   *log.Logger
+
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+func NewPoller(dir string, interval time.Duration, handle func(name string, data []byte) error, logger *log.Logger) (*Poller, error) {
+	if logger == nil {
+		logger = log.Default()
+	}
+	for _, sub := range []string{"", ProcessedDir, FailedDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, fmt.Errorf("DIRQUEUE: failed to create the dir: %v", err)
+		}
+	}
+	return &Poller{
+		Dir:      dir,
+		Interval: interval,
+		Handle:   handle,
+		Logger:   logger,
+		stop:     make(chan struct{}),
+	}, nil
+}
+
+// Polling the directory each interval in another goroutine until Stop
+func (p *Poller) Start() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.Interval)
+		defer ticker.Stop()
+		for {
+			p.Poll()
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (p *Poller) Stop() {
+	p.once.Do(func() {
+		close(p.stop)
+	})
+	p.wg.Wait()
+}
+
+// Handling all the messages that are in the directory now, returning how
+// many were handled
+func (p *Poller) Poll() int {
+	files, err := os.ReadDir(p.Dir)
+	if err != nil {
+		p.Logger.Println("DIRQUEUE: failed to read the dir:", err)
+		return 0
+	}
+	var names []string
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		names = append(names, file.Name())
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := filepath.Join(p.Dir, name)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			err = p.Handle(name, data)
+		}
+		target := ProcessedDir
+		if err != nil {
+			p.Logger.Println("DIRQUEUE: failed to handle message:", name, err)
+			target = FailedDir
+		}
+		if err := os.Rename(path, filepath.Join(p.Dir, target, name)); err != nil {
+			p.Logger.Println("DIRQUEUE: failed to move message:", name, err)
+		}
+	}
+	return len(names)
+}
@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// The buckets of the clients that were idle for this long are dropped
+const idleAfter = 10 * time.Minute
+
+// Limiter is a token bucket per client, each client can send Rate requests a
+// second with bursts of Burst requests. The HTTP and the gRPC servers share
+// it so a client has a single budget
+type Limiter struct {
+	Rate  float64
+	Burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	pruned  time.Time
+}
+
+type bucket struct {
+	tokens float64
+	seen   time.Time
+}
+
+// A limiter without a rate allows everything
+func New(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		Rate:    rate,
+		Burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Taking a token of the client, false when it has none left
+func (l *Limiter) Allow(client string) bool {
+	if l == nil || l.Rate <= 0 {
+		return true
+	}
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, found := l.buckets[client]
+	if !found {
+		b = &bucket{tokens: l.Burst}
+		l.buckets[client] = b
+	} else {
+		b.tokens += now.Sub(b.seen).Seconds() * l.Rate
+		if b.tokens > l.Burst {
+			b.tokens = l.Burst
+		}
+	}
+	b.seen = now
+
+	if now.Sub(l.pruned) > idleAfter {
+		for key, other := range l.buckets {
+			if now.Sub(other.seen) > idleAfter {
+				delete(l.buckets, key)
+			}
+		}
+		l.pruned = now
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,144 @@
+package resp
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Client for servers that speak the Redis protocol, a single connection is
+// used for the commands and it is opened again after a network error
+type Client struct {
+	addr    string
+	timeout time.Duration
+
+	mutex  sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+func NewClient(addr string, timeout time.Duration) *Client {
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	return &Client{addr: addr, timeout: timeout}
+}
+
+// Sending a command and returning the reply, an error reply of the server is
+// returned as the error
+func (c *Client) Do(args ...interface{}) (interface{}, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.conn == nil {
+		if err := c.dial(); err != nil {
+			return nil, err
+		}
+	}
+
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if err := writeCommand(c.writer, args); err != nil {
+		c.close()
+		return nil, fmt.Errorf("resp: failed to send command: %v", err)
+	}
+	reply, err := readReply(c.reader)
+	if err != nil {
+		c.close()
+		return nil, fmt.Errorf("resp: failed to read reply: %v", err)
+	}
+	if respErr, ok := reply.(Error); ok {
+		return nil, respErr
+	}
+	return reply, nil
+}
+
+func (c *Client) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.close()
+}
+
+func (c *Client) dial() error {
+	conn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+	if err != nil {
+		return fmt.Errorf("resp: failed to connect to %s: %v", c.addr, err)
+	}
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+	c.writer = bufio.NewWriter(conn)
+	return nil
+}
+
+func (c *Client) close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// Subscription on its own connection, a subscribed connection can't send
+// other commands
+type Subscription struct {
+	conn   net.Conn
+	closed chan struct{}
+}
+
+// Subscribing to the channel and calling the handler for every message until
+// the subscription is closed
+func (c *Client) Subscribe(channel string, handler func(channel string, message []byte)) (*Subscription, error) {
+	conn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("resp: failed to connect to %s: %v", c.addr, err)
+	}
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+
+	conn.SetDeadline(time.Now().Add(c.timeout))
+	if err := writeCommand(writer, []interface{}{"SUBSCRIBE", channel}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// the first reply confirms the subscription
+	if _, err := readReply(reader); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	sub := &Subscription{conn: conn, closed: make(chan struct{})}
+	go func() {
+		defer close(sub.closed)
+		for {
+			reply, err := readReply(reader)
+			if err != nil {
+				return
+			}
+			// message replies are ["message", channel, payload]
+			items, ok := reply.([]interface{})
+			if !ok || len(items) != 3 {
+				continue
+			}
+			kind, _ := items[0].([]byte)
+			from, _ := items[1].([]byte)
+			payload, _ := items[2].([]byte)
+			if string(kind) == "message" {
+				handler(string(from), payload)
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// Closed is closed when the connection of the subscription is lost
+func (s *Subscription) Closed() <-chan struct{} {
+	return s.closed
+}
+
+func (s *Subscription) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,317 @@
+package resp
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a small in-process stand-in of Redis, it keeps everything in
+// memory and supports the commands that the cache is using (strings with
+// ttl, keys listing and pub/sub). It is used for local development and tests
+type Server struct {
+	listener net.Listener
+
+	mutex       sync.Mutex
+	data        map[string]serverItem
+	subscribers map[string]map[*serverConn]bool
+}
+
+type serverItem struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func (i serverItem) expired() bool {
+	return !i.expiresAt.IsZero() && time.Now().After(i.expiresAt)
+}
+
+type serverConn struct {
+	conn   net.Conn
+	mutex  sync.Mutex
+	writer *bufio.Writer
+}
+
+func (c *serverConn) write(reply interface{}) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	writeReply(c.writer, reply)
+	return c.writer.Flush()
+}
+
+// Starting the server on the address, use "127.0.0.1:0" for a random port
+func NewServer(addr string) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		listener:    listener,
+		data:        make(map[string]serverItem),
+		subscribers: make(map[string]map[*serverConn]bool),
+	}
+	go s.serve()
+	return s, nil
+}
+
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	c := &serverConn{conn: conn, writer: bufio.NewWriter(conn)}
+	reader := bufio.NewReader(conn)
+	defer func() {
+		s.unsubscribeAll(c)
+		conn.Close()
+	}()
+
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		name := strings.ToUpper(string(args[0]))
+		if name == "QUIT" {
+			c.write("OK")
+			return
+		}
+		if err := c.write(s.execute(c, name, args[1:])); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) execute(c *serverConn, name string, args [][]byte) interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch name {
+	case "PING":
+		return "PONG"
+	case "ECHO":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		return args[0]
+	case "GET":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		item, ok := s.lookup(string(args[0]))
+		if !ok {
+			return nil
+		}
+		return item.value
+	case "SET":
+		return s.set(args)
+	case "DEL":
+		deleted := 0
+		for _, key := range args {
+			if _, ok := s.lookup(string(key)); ok {
+				delete(s.data, string(key))
+				deleted++
+			}
+		}
+		return deleted
+	case "EXISTS":
+		found := 0
+		for _, key := range args {
+			if _, ok := s.lookup(string(key)); ok {
+				found++
+			}
+		}
+		return found
+	case "PEXPIRE":
+		if len(args) != 2 {
+			return wrongArgs(name)
+		}
+		ms, err := strconv.ParseInt(string(args[1]), 10, 64)
+		if err != nil {
+			return Error("ERR value is not an integer or out of range")
+		}
+		item, ok := s.lookup(string(args[0]))
+		if !ok {
+			return 0
+		}
+		item.expiresAt = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		s.data[string(args[0])] = item
+		return 1
+	case "PTTL":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		item, ok := s.lookup(string(args[0]))
+		if !ok {
+			return -2
+		}
+		if item.expiresAt.IsZero() {
+			return -1
+		}
+		return int64(time.Until(item.expiresAt) / time.Millisecond)
+	case "KEYS":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		return s.keys(string(args[0]))
+	case "SCAN":
+		// all the keys are returned in one page
+		pattern := "*"
+		for i := 1; i+1 < len(args); i += 2 {
+			if strings.ToUpper(string(args[i])) == "MATCH" {
+				pattern = string(args[i+1])
+			}
+		}
+		return []interface{}{[]byte("0"), s.keys(pattern)}
+	case "DBSIZE":
+		return len(s.keys("*"))
+	case "FLUSHALL":
+		s.data = make(map[string]serverItem)
+		return "OK"
+	case "PUBLISH":
+		if len(args) != 2 {
+			return wrongArgs(name)
+		}
+		return s.publish(string(args[0]), args[1])
+	case "SUBSCRIBE":
+		for i, channel := range args {
+			if s.subscribers[string(channel)] == nil {
+				s.subscribers[string(channel)] = make(map[*serverConn]bool)
+			}
+			s.subscribers[string(channel)][c] = true
+			if i < len(args)-1 {
+				go c.write([]interface{}{[]byte("subscribe"), channel, i + 1})
+			}
+		}
+		if len(args) == 0 {
+			return wrongArgs(name)
+		}
+		return []interface{}{[]byte("subscribe"), args[len(args)-1], len(args)}
+	case "UNSUBSCRIBE":
+		for _, channel := range args {
+			delete(s.subscribers[string(channel)], c)
+		}
+		return []interface{}{[]byte("unsubscribe"), nil, 0}
+	}
+	return Error(fmt.Sprintf("ERR unknown command '%s'", name))
+}
+
+// SET key value [EX seconds | PX milliseconds]
+func (s *Server) set(args [][]byte) interface{} {
+	if len(args) != 2 && len(args) != 4 {
+		return wrongArgs("SET")
+	}
+	item := serverItem{value: append([]byte(nil), args[1]...)}
+	if len(args) == 4 {
+		amount, err := strconv.ParseInt(string(args[3]), 10, 64)
+		if err != nil || amount <= 0 {
+			return Error("ERR invalid expire time in 'set' command")
+		}
+		switch strings.ToUpper(string(args[2])) {
+		case "EX":
+			item.expiresAt = time.Now().Add(time.Duration(amount) * time.Second)
+		case "PX":
+			item.expiresAt = time.Now().Add(time.Duration(amount) * time.Millisecond)
+		default:
+			return Error("ERR syntax error")
+		}
+	}
+	s.data[string(args[0])] = item
+	return "OK"
+}
+
+func (s *Server) lookup(key string) (serverItem, bool) {
+	item, ok := s.data[key]
+	if ok && item.expired() {
+		delete(s.data, key)
+		return item, false
+	}
+	return item, ok
+}
+
+func (s *Server) keys(pattern string) []interface{} {
+	var names []string
+	for key := range s.data {
+		if _, ok := s.lookup(key); !ok {
+			continue
+		}
+		if globMatch(pattern, key) {
+			names = append(names, key)
+		}
+	}
+	sort.Strings(names)
+	list := make([]interface{}, len(names))
+	for i, name := range names {
+		list[i] = []byte(name)
+	}
+	return list
+}
+
+func (s *Server) publish(channel string, message []byte) int {
+	receivers := 0
+	for c := range s.subscribers[channel] {
+		receivers++
+		// writing outside of the server lock, a slow subscriber won't
+		// block the other commands
+		go c.write([]interface{}{[]byte("message"), []byte(channel), message})
+	}
+	return receivers
+}
+
+func (s *Server) unsubscribeAll(c *serverConn) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, conns := range s.subscribers {
+		delete(conns, c)
+	}
+}
+
+func wrongArgs(name string) Error {
+	return Error(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+}
+
+// Matching the key like Redis does, * matches any sequence (also '/') and ?
+// matches a single character
+func globMatch(pattern string, key string) bool {
+	if pattern == "" {
+		return key == ""
+	}
+	switch pattern[0] {
+	case '*':
+		for i := 0; i <= len(key); i++ {
+			if globMatch(pattern[1:], key[i:]) {
+				return true
+			}
+		}
+		return false
+	case '?':
+		return key != "" && globMatch(pattern[1:], key[1:])
+	case '\\':
+		if len(pattern) > 1 {
+			pattern = pattern[1:]
+		}
+	}
+	return key != "" && key[0] == pattern[0] && globMatch(pattern[1:], key[1:])
+}
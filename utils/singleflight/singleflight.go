@@ -0,0 +1,77 @@
+package singleflight
+
+import (
+	"fmt"
+	"sync"
+)
+
+// call is a single in-flight (or finished) execution of a function
+type call[T any] struct {
+	wg   sync.WaitGroup
+	val  T
+	err  error
+	dups int
+}
+
+// Group coalesces concurrent calls that share the same key, only the first
+// caller runs the function and all the others wait for its result
+type Group[T any] struct {
+	mutex sync.Mutex
+	calls map[string]*call[T]
+}
+
+// Do executes fn once for all the concurrent callers with the same key.
+// shared is true when the result was handed to more than one caller
+func (g *Group[T]) Do(key string, fn func() (T, error)) (v T, err error, shared bool) {
+	g.mutex.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call[T])
+	}
+	if c, ok := g.calls[key]; ok {
+		// someone is already fetching this key, waiting for him
+		c.dups++
+		g.mutex.Unlock()
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+	c := new(call[T])
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mutex.Unlock()
+
+	g.doCall(c, key, fn)
+	return c.val, c.err, c.dups > 0
+}
+
+// Forget drops the running call of the key, the next caller of the key runs
+// the function again instead of waiting for it. The callers that are already
+// waiting still get its result
+func (g *Group[T]) Forget(key string) {
+	g.mutex.Lock()
+	delete(g.calls, key)
+	g.mutex.Unlock()
+}
+
+// InFlight returns the number of keys that are currently being fetched
+func (g *Group[T]) InFlight() int {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return len(g.calls)
+}
+
+func (g *Group[T]) doCall(c *call[T], key string, fn func() (T, error)) {
+	defer func() {
+		// a panic inside fn must not leave the waiters blocked forever
+		if r := recover(); r != nil {
+			c.err = fmt.Errorf("singleflight: call for key %s panicked: %v", key, r)
+		}
+		g.mutex.Lock()
+		if g.calls[key] == c {
+			// the key may have been forgotten and called again
+			delete(g.calls, key)
+		}
+		g.mutex.Unlock()
+		c.wg.Done()
+	}()
+	c.val, c.err = fn()
+}
@@ -0,0 +1,210 @@
+package singleflight
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Waiting until the key has a running call with n waiting callers
+func waitForDups[T any](t *testing.T, g *Group[T], key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		g.mutex.Lock()
+		c, ok := g.calls[key]
+		running := ok && c.dups >= n
+		g.mutex.Unlock()
+		if running {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("the call of %s didn't get %d waiting callers", key, n)
+}
+
+func TestDoSuppressesDuplicates(t *testing.T) {
+	var g Group[string]
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func() (string, error) {
+		calls.Add(1)
+		<-release
+		return "secrets", nil
+	}
+
+	const callers = 5
+	type result struct {
+		val    string
+		err    error
+		shared bool
+	}
+	results := make(chan result, callers)
+	go func() {
+		val, err, shared := g.Do("key", fn)
+		results <- result{val, err, shared}
+	}()
+	waitForDups(t, &g, "key", 0)
+	for i := 1; i < callers; i++ {
+		go func() {
+			val, err, shared := g.Do("key", fn)
+			results <- result{val, err, shared}
+		}()
+	}
+	waitForDups(t, &g, "key", callers-1)
+	if n := g.InFlight(); n != 1 {
+		t.Errorf("InFlight: got %d, want 1", n)
+	}
+	close(release)
+
+	for i := 0; i < callers; i++ {
+		r := <-results
+		if r.val != "secrets" || r.err != nil || !r.shared {
+			t.Errorf("Do: got (%q, %v, %v), want (secrets, nil, true)", r.val, r.err, r.shared)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("the function ran %d times, want 1", n)
+	}
+	if n := g.InFlight(); n != 0 {
+		t.Errorf("InFlight after the call: got %d, want 0", n)
+	}
+
+	// a later call runs the function again and is not shared
+	val, err, shared := g.Do("key", func() (string, error) { return "again", nil })
+	if val != "again" || err != nil || shared {
+		t.Errorf("Do after the call: got (%q, %v, %v)", val, err, shared)
+	}
+}
+
+func TestDoKeysAreIndependent(t *testing.T) {
+	var g Group[int]
+	var wg sync.WaitGroup
+	var calls atomic.Int32
+	for _, key := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			g.Do(key, func() (int, error) {
+				calls.Add(1)
+				return 0, nil
+			})
+		}(key)
+	}
+	wg.Wait()
+	if n := calls.Load(); n != 3 {
+		t.Errorf("the function ran %d times for 3 keys", n)
+	}
+}
+
+func TestDoPropagatesTheError(t *testing.T) {
+	var g Group[string]
+	want := errors.New("throttled")
+	release := make(chan struct{})
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err, _ := g.Do("key", func() (string, error) {
+			<-release
+			return "", want
+		})
+		errs <- err
+	}()
+	waitForDups(t, &g, "key", 0)
+	go func() {
+		_, err, _ := g.Do("key", func() (string, error) { return "not called", nil })
+		errs <- err
+	}()
+	waitForDups(t, &g, "key", 1)
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		if err := <-errs; !errors.Is(err, want) {
+			t.Errorf("Do: got %v, want %v", err, want)
+		}
+	}
+}
+
+func TestDoRecoversThePanic(t *testing.T) {
+	var g Group[string]
+	release := make(chan struct{})
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err, _ := g.Do("key", func() (string, error) {
+			<-release
+			panic("boom")
+		})
+		errs <- err
+	}()
+	waitForDups(t, &g, "key", 0)
+	go func() {
+		_, err, _ := g.Do("key", func() (string, error) { return "not called", nil })
+		errs <- err
+	}()
+	waitForDups(t, &g, "key", 1)
+	close(release)
+
+	// the waiting caller is not blocked and both get the panic as an error
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if err == nil || !strings.Contains(err.Error(), "boom") {
+				t.Errorf("Do: got %v, want the panic as an error", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("a caller is blocked after the panic")
+		}
+	}
+	if n := g.InFlight(); n != 0 {
+		t.Errorf("InFlight after the panic: got %d, want 0", n)
+	}
+}
+
+func TestForget(t *testing.T) {
+	var g Group[string]
+	release := make(chan struct{})
+
+	first := make(chan string, 1)
+	go func() {
+		val, _, _ := g.Do("key", func() (string, error) {
+			<-release
+			return "old", nil
+		})
+		first <- val
+	}()
+	waitForDups(t, &g, "key", 0)
+
+	// after Forget the key is called again instead of waiting
+	g.Forget("key")
+	val, err, shared := g.Do("key", func() (string, error) { return "new", nil })
+	if val != "new" || err != nil || shared {
+		t.Errorf("Do after Forget: got (%q, %v, %v), want (new, nil, false)", val, err, shared)
+	}
+
+	// a call that is running while the forgotten one finishes stays in flight
+	second := make(chan struct{})
+	done := make(chan string, 1)
+	go func() {
+		val, _, _ := g.Do("key", func() (string, error) {
+			<-second
+			return "second", nil
+		})
+		done <- val
+	}()
+	waitForDups(t, &g, "key", 0)
+	close(release)
+	if val := <-first; val != "old" {
+		t.Errorf("the forgotten call returned %q, want old", val)
+	}
+	if n := g.InFlight(); n != 1 {
+		t.Errorf("InFlight: got %d, want the running call", n)
+	}
+	close(second)
+	if val := <-done; val != "second" {
+		t.Errorf("Do: got %q, want second", val)
+	}
+}
@@ -0,0 +1,396 @@
+package storage
+
+import (
+	"fmt"
+	"golang-secret-manager/types"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Config of the embedded key value store
+type BoltCacheConfig struct {
+	Path string
+
+	// Each namespace is saved in its own bucket
+	Namespaces []string
+
+	// Max size of a single transaction while compacting
+	CompactTxMaxSize int64
+}
+
+// BoltCache keeps all the entries in a single bbolt file, with a bucket per
+// key namespace. The values are saved with the same entry format of the
+// persist cache, so they have a checksum and can be encrypted
+type BoltCache struct {
+	mutex  sync.RWMutex
+	db     *bolt.DB
+	config BoltCacheConfig
+	layer  ICache
+	codec  entryCodec
+}
+
+func NewBoltCache(config BoltCacheConfig) (*BoltCache, error) {
+	if len(config.Namespaces) == 0 {
+		config.Namespaces = DefaultNamespaces
+	}
+	if config.CompactTxMaxSize == 0 {
+		config.CompactTxMaxSize = 64 << 20
+	}
+
+	b := &BoltCache{config: config, codec: newEntryCodec()}
+	if err := b.open(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// The bolt cache implements the following interface:
+// type ICache interface {
+// 	Get(key string) (interface{}, error)
+// 	Set(key string, value interface{}, opts ...SetOption) error
+// 	Delete(key string) error
+// 	SetCacheLayer(layer ICache) error
+// 	ActivateLayerSavingRuntime(intervals time.Duration) error
+// 	LayerSet(key string, value interface{}, opts ...SetOption) error
+// }
+
+func (b *BoltCache) Get(key string) (interface{}, error) {
+	val, _, err := b.GetWithExpiry(key)
+	return val, err
+}
+
+// Getting the key with the time it expires at, an expired key is removed
+func (b *BoltCache) GetWithExpiry(key string) (interface{}, time.Time, error) {
+	b.mutex.RLock()
+	var raw []byte
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if val := tx.Bucket(b.bucketOf(key)).Get([]byte(key)); val != nil {
+			// the value is only valid inside the transaction
+			raw = append([]byte(nil), val...)
+		}
+		return nil
+	})
+	b.mutex.RUnlock()
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("BoltCache: failed to read key: %s: %v", key, err)
+	}
+
+	if raw != nil {
+		entry, err := b.decode(key, raw)
+		if err == nil && entry.expired(time.Now()) {
+			b.Delete(key)
+			err = &NotFoundError{Key: key}
+		}
+		var value []byte
+		if err == nil {
+			value, err = b.codec.open(entry)
+		}
+		if err == nil {
+			return value, entry.expiresAt, nil
+		}
+		if b.layer == nil {
+			if IsNotFound(err) {
+				return nil, time.Time{}, err
+			}
+			return nil, time.Time{}, fmt.Errorf("BoltCache: %v", err)
+		}
+		if !IsNotFound(err) {
+			fmt.Println("BoltCache:", err)
+		}
+	}
+
+	if b.layer == nil {
+		return nil, time.Time{}, &NotFoundError{Key: key}
+	}
+
+	// searching in the lower layer
+	val, expiresAt, err := GetWithExpiry(b.layer, key)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if err := b.SetManyWithExpiry(map[string]interface{}{key: val}, map[string]time.Time{key: expiresAt}); err != nil {
+		return nil, time.Time{}, err
+	}
+	return val, expiresAt, nil
+}
+
+func (b *BoltCache) Set(key string, value interface{}, opts ...SetOption) error {
+	// like the persist cache the expiry is saved with the entry, the keys
+	// that were set without a ttl are kept until they are deleted
+	return b.SetManyWithExpiry(map[string]interface{}{key: value}, map[string]time.Time{key: ExpiryOf(opts, NoExpiration)})
+}
+
+// Setting all the values in one transaction, either all of them are saved or
+// none of them
+func (b *BoltCache) SetMany(values map[string]interface{}) error {
+	return b.SetManyWithExpiry(values, nil)
+}
+
+// Like SetMany, the values that have no expiry are never expiring
+func (b *BoltCache) SetManyWithExpiry(values map[string]interface{}, expires map[string]time.Time) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.db.Update(func(tx *bolt.Tx) error {
+		for key, value := range values {
+			val, ok := value.([]byte)
+			if !ok {
+				return fmt.Errorf("BoltCache: Error converting value of key: %s to []byte", key)
+			}
+			entry, err := b.codec.seal(key, val)
+			if err != nil {
+				return fmt.Errorf("BoltCache: %v", err)
+			}
+			entry.expiresAt = expires[key]
+			if err := tx.Bucket(b.bucketOf(key)).Put([]byte(key), encodeEntry(entry)); err != nil {
+				return fmt.Errorf("BoltCache: failed to save key: %s: %v", key, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (b *BoltCache) Delete(key string) error {
+	return b.DeleteMany([]string{key})
+}
+
+// Deleting all the keys in one transaction
+func (b *BoltCache) DeleteMany(keys []string) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.db.Update(func(tx *bolt.Tx) error {
+		for _, key := range keys {
+			if err := tx.Bucket(b.bucketOf(key)).Delete([]byte(key)); err != nil {
+				return fmt.Errorf("BoltCache: failed to delete key: %s: %v", key, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (b *BoltCache) SetCacheLayer(layer ICache, load bool) error {
+	if b.layer != nil {
+		return fmt.Errorf("cannot changed layer")
+	}
+	b.layer = layer
+	return nil
+}
+
+func (b *BoltCache) ActivateLayerSavingRuntime(intervals time.Duration) error {
+	// NONE every Set is already committed to the file
+	return nil
+}
+
+func (b *BoltCache) LayerSet(key string, value interface{}, opts ...SetOption) error {
+	return b.Set(key, value, opts...)
+}
+
+func (b *BoltCache) GetAllKeys() []string {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	var list []string
+	b.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(_ []byte, bucket *bolt.Bucket) error {
+			return bucket.ForEach(func(k, _ []byte) error {
+				list = append(list, string(k))
+				return nil
+			})
+		})
+	})
+	sort.Strings(list)
+	return list
+}
+
+// Removing the expired keys in one transaction, only the headers of the
+// entries are read. Returning how many were removed
+func (b *BoltCache) DeleteExpired() int {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	now := time.Now()
+	removed := 0
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(_ []byte, bucket *bolt.Bucket) error {
+			var expired [][]byte
+			err := bucket.ForEach(func(k, raw []byte) error {
+				if entry, err := decodeEntryHeader(raw); err == nil && entry.expired(now) {
+					expired = append(expired, append([]byte(nil), k...))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			// changing the bucket while iterating it is not allowed
+			for _, k := range expired {
+				if err := bucket.Delete(k); err != nil {
+					return err
+				}
+			}
+			removed += len(expired)
+			return nil
+		})
+	})
+	if err != nil {
+		fmt.Println("BoltCache: failed to remove the expired keys:", err)
+		return 0
+	}
+	return removed
+}
+
+// Encrypting the values like the persist cache, all the values are written
+// again with the current key
+func (b *BoltCache) SetEncryption(provider KeyProvider, previous ...KeyProvider) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if err := b.codec.useKeys(provider, previous...); err != nil {
+		return fmt.Errorf("BoltCache: %v", err)
+	}
+
+	// the values that can't be opened are left as they are, like in the
+	// persist cache
+	failed := 0
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		failed = 0
+		return tx.ForEach(func(_ []byte, bucket *bolt.Bucket) error {
+			updated := make(map[string][]byte)
+			err := bucket.ForEach(func(k, raw []byte) error {
+				entry, err := decodeEntry(raw)
+				if err != nil || entry.keyID == b.codec.keyID() {
+					return nil
+				}
+				value, err := b.codec.open(entry)
+				if err != nil {
+					fmt.Println("BoltCache: failed to re-encrypt key:", string(k), err)
+					failed++
+					return nil
+				}
+				sealed, err := b.codec.seal(entry.key, value)
+				if err != nil {
+					return err
+				}
+				sealed.expiresAt = entry.expiresAt
+				updated[string(k)] = encodeEntry(sealed)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			// changing the bucket while iterating it is not allowed
+			for k, raw := range updated {
+				if err := bucket.Put([]byte(k), raw); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return &ReencryptError{Layer: "BoltCache", Failed: failed}
+	}
+	return nil
+}
+
+// Checking that every value can be read and decrypted
+func (b *BoltCache) Verify() types.CacheVerifyReport {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	report := types.CacheVerifyReport{KeyID: b.codec.keyID(), Failed: make(map[string]string)}
+	b.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(_ []byte, bucket *bolt.Bucket) error {
+			return bucket.ForEach(func(k, raw []byte) error {
+				report.Total++
+				entry, err := decodeEntry(raw)
+				if err == nil {
+					_, err = b.codec.open(entry)
+				}
+				if err != nil {
+					report.Failed[string(k)] = err.Error()
+				} else if entry.encrypted() {
+					report.Encrypted++
+				} else {
+					report.Plain++
+				}
+				return nil
+			})
+		})
+	})
+	report.OK = len(report.Failed) == 0
+	return report
+}
+
+// Copying all the buckets to a new file and replacing the old one, bbolt never
+// shrinks the file by itself so the space of deleted keys is given back here
+func (b *BoltCache) Compact() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	tempPath := b.config.Path + ".compact"
+	os.Remove(tempPath)
+	dst, err := bolt.Open(tempPath, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return fmt.Errorf("BoltCache: failed to create compact file: %v", err)
+	}
+	if err := bolt.Compact(dst, b.db, b.config.CompactTxMaxSize); err != nil {
+		dst.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("BoltCache: failed to compact: %v", err)
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	if err := b.db.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, b.config.Path); err != nil {
+		// reopening the old file so the cache is still usable
+		b.open()
+		return fmt.Errorf("BoltCache: failed to replace file: %v", err)
+	}
+	return b.open()
+}
+
+func (b *BoltCache) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.db.Close()
+}
+
+func (b *BoltCache) open() error {
+	db, err := bolt.Open(b.config.Path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return fmt.Errorf("BoltCache: failed to open %s: %v", b.config.Path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		namespaces := append([]string{DefaultNamespace}, b.config.Namespaces...)
+		for _, namespace := range namespaces {
+			if _, err := tx.CreateBucketIfNotExists([]byte(namespace)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("BoltCache: failed to create buckets: %v", err)
+	}
+	b.db = db
+	return nil
+}
+
+func (b *BoltCache) bucketOf(key string) []byte {
+	return []byte(KeyNamespace(key, b.config.Namespaces))
+}
+
+func (b *BoltCache) decode(key string, raw []byte) (*fileEntry, error) {
+	entry, err := decodeEntry(raw)
+	if err != nil {
+		return nil, fmt.Errorf("key: %s is corrupted: %v", key, err)
+	}
+	return entry, nil
+}
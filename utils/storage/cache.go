@@ -1,9 +1,8 @@
 package storage
 
 import (
-	"fmt"
-	GenericEncoding "golang-secret-manager/utils/genericEncoding"
-	"reflect"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -15,8 +14,8 @@ type ICache interface {
 	// Getting all the current keys
 	GetAllKeys() []string
 
-	// Setting a new value to the cache
-	Set(key string, value interface{}) error
+	// Setting a new value to the cache, options can change the ttl of the key
+	Set(key string, value interface{}, opts ...SetOption) error
 
 	// Deleting the value from the cache
 	Delete(key string) error
@@ -30,37 +29,141 @@ type ICache interface {
 	ActivateLayerSavingRuntime(intervals time.Duration) error
 
 	// Setting the value to the lower cache MAYBE DONT NEED!!!!!
-	LayerSet(key string, value interface{}) error
+	LayerSet(key string, value interface{}, opts ...SetOption) error
 }
 
-func GetCacheValue[T any](cache ICache, key string) (*T, error) {
-	// getting the value from the ICache
-	val, err := cache.Get(key)
-	if err != nil {
-		// couldn't found
-		return nil, fmt.Errorf("couldn't found key: %s in cache: %v", key, err)
+// Passing DefaultTTL will use the default expiration of the cache and
+// NoExpiration will keep the key until it is deleted or evicted
+const (
+	DefaultTTL   time.Duration = 0
+	NoExpiration time.Duration = -1
+)
+
+// Options of a single Set call
+type SetOptions struct {
+	TTL time.Duration
+}
+
+type SetOption func(*SetOptions)
+
+// Setting the time to live of the key
+func WithTTL(ttl time.Duration) SetOption {
+	return func(o *SetOptions) {
+		o.TTL = ttl
 	}
-	// converting the value of the result
-	result, err := GenericEncoding.FromJson[T](val)
-	if err != nil {
-		// couldn't found
-		return nil, fmt.Errorf("couldn't convert key: %s in cache found type: %T error: %v", key, reflect.TypeOf(result), err)
+}
+
+// Applying all the options on top of the default ones
+func ApplySetOptions(opts ...SetOption) SetOptions {
+	options := SetOptions{TTL: DefaultTTL}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+	return options
+}
+
+// Returning the time the ttl of the options ends at from now, the zero time
+// means the key never expires. A DefaultTTL is the default of the cache
+func ExpiryOf(opts []SetOption, defaultTTL time.Duration) time.Time {
+	ttl := ApplySetOptions(opts...).TTL
+	if ttl == DefaultTTL {
+		ttl = defaultTTL
 	}
-	if result != nil {
-		return result, nil
+	if ttl <= 0 {
+		return time.Time{}
 	}
-	return nil, fmt.Errorf("nil pointer was found in the cache")
+	return time.Now().Add(ttl)
 }
 
-func SetCacheValue[T any](cache ICache, key string, value T) error {
-	bytes, err := GenericEncoding.ToJson[T](value)
-	if err != nil {
-		return fmt.Errorf("CACHE: failed to cache value: %v", err)
-	} else {
-		err = cache.Set(key, bytes)
-		if err != nil {
-			return fmt.Errorf("CACHE: failed to cache value: %v", err)
+// Returning the ttl that is left until the expiry, NoExpiration for the zero
+// time. ok is false when the key already expired
+func TTLUntil(expiresAt time.Time) (ttl time.Duration, ok bool) {
+	if expiresAt.IsZero() {
+		return NoExpiration, true
+	}
+	ttl = time.Until(expiresAt)
+	return ttl, ttl > 0
+}
+
+// Caches that keep the expiry of their keys, the lower layers are expiring
+// the keys on their own so the upper layer must load a key with the expiry it
+// was saved with
+type ExpiryCache interface {
+	// Like Get with the time the key expires at, the zero time means the
+	// key never expires
+	GetWithExpiry(key string) (interface{}, time.Time, error)
+}
+
+// Getting the key with its expiry, the keys of a cache that doesn't keep
+// their expiry never expire
+func GetWithExpiry(cache ICache, key string) (interface{}, time.Time, error) {
+	if withExpiry, ok := cache.(ExpiryCache); ok {
+		return withExpiry.GetWithExpiry(key)
+	}
+	val, err := cache.Get(key)
+	return val, time.Time{}, err
+}
+
+// The prefixes of the cache keys, every key is starting with one of them
+var DefaultNamespaces = []string{"secret", "access", "acsync", "arnlst"}
+
+// Keys without a known prefix are in the default namespace
+const DefaultNamespace = "default"
+
+// Returning the namespace of the key
+func KeyNamespace(key string, namespaces []string) string {
+	for _, namespace := range namespaces {
+		if strings.HasPrefix(key, namespace) {
+			return namespace
 		}
 	}
+	return DefaultNamespace
+}
+
+// Returning the keys of the cache sorted, including the keys of its lower
+// layers when the cache can list them
+func AllKeys(cache ICache) []string {
+	if withLayer, ok := cache.(interface{ Keys() []string }); ok {
+		return withLayer.Keys()
+	}
+	keys := cache.GetAllKeys()
+	sort.Strings(keys)
+	return keys
+}
+
+// Removing the key from the cache and from its lower layers when the cache
+// supports it, otherwise only from the cache
+func PurgeKey(cache ICache, key string) error {
+	if purger, ok := cache.(interface{ Purge(key string) error }); ok {
+		return purger.Purge(key)
+	}
+	if err := cache.Delete(key); err != nil && !IsNotFound(err) {
+		return err
+	}
 	return nil
 }
+
+// Returning the codec the cache encodes its values with, JSON for the caches
+// that don't have one
+func CodecOf(cache ICache) Codec {
+	if withCodec, ok := cache.(interface{ Codec() Codec }); ok && withCodec.Codec() != nil {
+		return withCodec.Codec()
+	}
+	return JSONCodec
+}
+
+// GetCacheValue and SetCacheValue are kept for compatibility, they are
+// using a TypedCache with the codec of the cache on top of it
+func GetCacheValue[T any](cache ICache, key string) (*T, error) {
+	val, err := NewTypedCache[T](cache, CodecOf(cache)).Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return &val, nil
+}
+
+func SetCacheValue[T any](cache ICache, key string, value T, opts ...SetOption) error {
+	return NewTypedCache[T](cache, CodecOf(cache)).Set(key, value, opts...)
+}
@@ -0,0 +1,276 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Size of the AES-256 data key
+const dataKeySize = 32
+
+// Provides the data key that the entries are encrypted with
+type KeyProvider interface {
+	// Returning the data key and a short id of the key that is saved with
+	// each entry, so the entry can be opened with the right key later
+	DataKey() (key []byte, keyID string, err error)
+}
+
+// The KMS calls that are needed for envelope encryption, the AWS client
+// implements it and LocalKMS is a stand-in that never leaves the machine
+type KMSClient interface {
+	GenerateDataKey(masterKeyID string) (plaintext []byte, ciphertext []byte, err error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// AEAD cipher of the entries (AES-256-GCM), the cache key is used as the
+// additional data so an entry can't be copied to another key
+type entryCipher struct {
+	aead  cipher.AEAD
+	keyID string
+}
+
+func newEntryCipher(provider KeyProvider) (*entryCipher, error) {
+	key, keyID, err := provider.DataKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the data key: %v", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &entryCipher{aead: aead, keyID: keyID}, nil
+}
+
+func (c *entryCipher) seal(key string, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, []byte(key)), nil
+}
+
+func (c *entryCipher) open(key string, ciphertext []byte) ([]byte, error) {
+	size := c.aead.NonceSize()
+	if len(ciphertext) < size {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+	plaintext, err := c.aead.Open(nil, ciphertext[:size], ciphertext[size:], []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt with key id %s: %v", c.keyID, err)
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != dataKeySize {
+		return nil, fmt.Errorf("data key must be %d bytes, got %d", dataKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// The id of a key is the start of its sha256, it can't be used to recover it
+func keyIDOf(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:6])
+}
+
+// FileKeyProvider reads the data key from a local file (hex encoded), the file
+// is created with a random key when it doesn't exist
+type FileKeyProvider struct {
+	Path string
+}
+
+func NewFileKeyProvider(path string) *FileKeyProvider {
+	return &FileKeyProvider{Path: path}
+}
+
+func (p *FileKeyProvider) DataKey() ([]byte, string, error) {
+	key, err := readOrCreateKeyFile(p.Path, func() ([]byte, error) {
+		return randomBytes(dataKeySize)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if len(key) != dataKeySize {
+		return nil, "", fmt.Errorf("key file %s must hold %d bytes", p.Path, dataKeySize)
+	}
+	return key, keyIDOf(key), nil
+}
+
+// PassphraseKeyProvider derives the data key from a passphrase with scrypt, the
+// salt is saved in a local file so the same key is derived on every start
+type PassphraseKeyProvider struct {
+	Passphrase string
+	SaltPath   string
+}
+
+func NewPassphraseKeyProvider(passphrase string, saltPath string) *PassphraseKeyProvider {
+	return &PassphraseKeyProvider{Passphrase: passphrase, SaltPath: saltPath}
+}
+
+// Reading the passphrase from an environment variable
+func NewEnvPassphraseKeyProvider(envName string, saltPath string) (*PassphraseKeyProvider, error) {
+	passphrase := os.Getenv(envName)
+	if passphrase == "" {
+		return nil, fmt.Errorf("environment variable %s is empty", envName)
+	}
+	return NewPassphraseKeyProvider(passphrase, saltPath), nil
+}
+
+func (p *PassphraseKeyProvider) DataKey() ([]byte, string, error) {
+	salt, err := readOrCreateKeyFile(p.SaltPath, func() ([]byte, error) {
+		return randomBytes(16)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	key, err := deriveKey(p.Passphrase, salt)
+	if err != nil {
+		return nil, "", err
+	}
+	return key, keyIDOf(key), nil
+}
+
+// Deriving the data key from a passphrase with scrypt
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, dataKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %v", err)
+	}
+	return key, nil
+}
+
+// Provider of a key that is already known
+type staticKeyProvider struct {
+	key []byte
+}
+
+func (p staticKeyProvider) DataKey() ([]byte, string, error) {
+	return p.key, keyIDOf(p.key), nil
+}
+
+// KMSKeyProvider uses envelope encryption, a data key is generated by KMS once
+// and only its encrypted blob is kept on disk. On each start the blob is
+// decrypted by KMS to get the data key back
+type KMSKeyProvider struct {
+	Client      KMSClient
+	MasterKeyID string
+	BlobPath    string
+}
+
+func NewKMSKeyProvider(client KMSClient, masterKeyID string, blobPath string) *KMSKeyProvider {
+	return &KMSKeyProvider{Client: client, MasterKeyID: masterKeyID, BlobPath: blobPath}
+}
+
+func (p *KMSKeyProvider) DataKey() ([]byte, string, error) {
+	var plaintext []byte
+	blob, err := readOrCreateKeyFile(p.BlobPath, func() ([]byte, error) {
+		var ciphertext []byte
+		var err error
+		plaintext, ciphertext, err = p.Client.GenerateDataKey(p.MasterKeyID)
+		return ciphertext, err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if plaintext == nil {
+		// the blob was already on disk
+		if plaintext, err = p.Client.Decrypt(blob); err != nil {
+			return nil, "", fmt.Errorf("failed to decrypt the data key: %v", err)
+		}
+	}
+	return plaintext, keyIDOf(plaintext), nil
+}
+
+// LocalKMS acts like KMS with a master key that is saved in a local file, it is
+// used for development and tests instead of the real service
+type LocalKMS struct {
+	MasterKeyPath string
+}
+
+func NewLocalKMS(masterKeyPath string) *LocalKMS {
+	return &LocalKMS{MasterKeyPath: masterKeyPath}
+}
+
+func (k *LocalKMS) GenerateDataKey(masterKeyID string) ([]byte, []byte, error) {
+	plaintext, err := randomBytes(dataKeySize)
+	if err != nil {
+		return nil, nil, err
+	}
+	master, err := k.master()
+	if err != nil {
+		return nil, nil, err
+	}
+	ciphertext, err := master.seal(masterKeyID, plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+	// like KMS the blob carries the master key id
+	return plaintext, append([]byte(masterKeyID+"\n"), ciphertext...), nil
+}
+
+func (k *LocalKMS) Decrypt(blob []byte) ([]byte, error) {
+	masterKeyID, ciphertext, found := strings.Cut(string(blob), "\n")
+	if !found {
+		return nil, fmt.Errorf("LocalKMS: invalid blob")
+	}
+	master, err := k.master()
+	if err != nil {
+		return nil, err
+	}
+	return master.open(masterKeyID, []byte(ciphertext))
+}
+
+func (k *LocalKMS) master() (*entryCipher, error) {
+	return newEntryCipher(NewFileKeyProvider(k.MasterKeyPath))
+}
+
+// Reading a hex encoded key file, when the file doesn't exist a new key is
+// created and saved with permissions only to the owner
+func readOrCreateKeyFile(path string, create func() ([]byte, error)) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode key file %s: %v", path, err)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := create()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to save key file %s: %v", path, err)
+	}
+	return key, nil
+}
+
+func randomBytes(size int) ([]byte, error) {
+	b := make([]byte, size)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testMasterKeyID = "alias/secret-manager-cache"
+
+func newTestPersistCache(t *testing.T, dir string, provider KeyProvider, previous ...KeyProvider) *PersistCache {
+	t.Helper()
+	cache, err := CreatePersistCache(dir)
+	if err != nil {
+		t.Fatalf("CreatePersistCache: %v", err)
+	}
+	t.Cleanup(func() { cache.HomeDir.Close() })
+	if provider != nil {
+		if err := cache.SetEncryption(provider, previous...); err != nil {
+			t.Fatalf("SetEncryption: %v", err)
+		}
+	}
+	return cache
+}
+
+func expectValue(t *testing.T, cache ICache, key string, want string) {
+	t.Helper()
+	val, err := cache.Get(key)
+	if err != nil {
+		t.Fatalf("Get %s: %v", key, err)
+	}
+	if !bytes.Equal(val.([]byte), []byte(want)) {
+		t.Errorf("Get %s: got %q, want %q", key, val, want)
+	}
+}
+
+func TestLocalKMSDataKey(t *testing.T) {
+	kms := NewLocalKMS(filepath.Join(t.TempDir(), "master.key"))
+
+	plaintext, blob, err := kms.GenerateDataKey(testMasterKeyID)
+	if err != nil {
+		t.Fatalf("GenerateDataKey: %v", err)
+	}
+	if len(plaintext) != dataKeySize {
+		t.Errorf("data key of %d bytes, want %d", len(plaintext), dataKeySize)
+	}
+	if bytes.Contains(blob, plaintext) {
+		t.Error("the blob holds the plain data key")
+	}
+	decrypted, err := kms.Decrypt(blob)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Error("Decrypt didn't return the data key")
+	}
+
+	other := NewLocalKMS(filepath.Join(t.TempDir(), "master.key"))
+	if _, err := other.Decrypt(blob); err == nil {
+		t.Error("a blob was decrypted with another master key")
+	}
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	keysDir := t.TempDir()
+	cacheDir := t.TempDir()
+	kms := NewLocalKMS(filepath.Join(keysDir, "master.key"))
+	blobPath := filepath.Join(keysDir, "data-key.blob")
+
+	cache := newTestPersistCache(t, cacheDir, NewKMSKeyProvider(kms, testMasterKeyID, blobPath))
+	if err := cache.Set("secret_a", []byte("top secret value")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	expectValue(t, cache, "secret_a", "top secret value")
+
+	files, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(cacheDir, file.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte("top secret value")) {
+			t.Errorf("%s holds the plain value", file.Name())
+		}
+	}
+
+	// a restart decrypts the saved blob to get the same data key back
+	reopened := newTestPersistCache(t, cacheDir, NewKMSKeyProvider(kms, testMasterKeyID, blobPath))
+	expectValue(t, reopened, "secret_a", "top secret value")
+	report := reopened.Verify()
+	if !report.OK || report.Total != 1 || report.Encrypted != 1 || report.Plain != 0 {
+		t.Errorf("Verify: got %+v", report)
+	}
+
+	// without the key the entry can't be read
+	plain := newTestPersistCache(t, cacheDir, nil)
+	if report := plain.Verify(); report.OK || len(report.Failed) != 1 {
+		t.Errorf("Verify without the key: got %+v", report)
+	}
+}
+
+func TestRotateKeyThenDecrypt(t *testing.T) {
+	keysDir := t.TempDir()
+	cacheDir := t.TempDir()
+	kms := NewLocalKMS(filepath.Join(keysDir, "master.key"))
+	oldKey := func() KeyProvider {
+		return NewKMSKeyProvider(kms, testMasterKeyID, filepath.Join(keysDir, "old.blob"))
+	}
+	newKey := func() KeyProvider {
+		return NewKMSKeyProvider(kms, testMasterKeyID, filepath.Join(keysDir, "new.blob"))
+	}
+
+	cache := newTestPersistCache(t, cacheDir, oldKey())
+	values := map[string]string{"secret_a": "first", "access_a": "second"}
+	for key, value := range values {
+		if err := cache.Set(key, []byte(value)); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+	}
+	oldKeyID := cache.Verify().KeyID
+
+	if err := cache.RotateKey(newKey()); err != nil {
+		t.Fatalf("RotateKey: %v", err)
+	}
+	report := cache.Verify()
+	if !report.OK || report.Encrypted != len(values) {
+		t.Fatalf("Verify after the rotation: got %+v", report)
+	}
+	if report.KeyID == oldKeyID {
+		t.Fatalf("the key id didn't change with the rotation: %s", report.KeyID)
+	}
+	for key, value := range values {
+		expectValue(t, cache, key, value)
+	}
+
+	// the entries were re-encrypted, the new key alone opens them
+	rotated := newTestPersistCache(t, cacheDir, newKey())
+	if report := rotated.Verify(); !report.OK {
+		t.Errorf("Verify with the new key: got %+v", report)
+	}
+	for key, value := range values {
+		expectValue(t, rotated, key, value)
+	}
+
+	// and the old key can't
+	old := newTestPersistCache(t, cacheDir, nil)
+	if err := old.SetEncryption(oldKey()); !IsReencryptError(err) {
+		t.Errorf("SetEncryption with the old key: got %v, want a ReencryptError", err)
+	}
+	if report := old.Verify(); report.OK || len(report.Failed) != len(values) {
+		t.Errorf("Verify with the old key: got %+v", report)
+	}
+}
+
+func TestRotateKeyOnStart(t *testing.T) {
+	cacheDir := t.TempDir()
+	keysDir := t.TempDir()
+	oldKey := NewFileKeyProvider(filepath.Join(keysDir, "old.key"))
+	newKey := NewFileKeyProvider(filepath.Join(keysDir, "new.key"))
+
+	cache := newTestPersistCache(t, cacheDir, oldKey)
+	if err := cache.Set("secret_a", []byte("value")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// a restart with the new key and the old one as previous, like the
+	// CACHE_PREVIOUS_* settings of the server
+	restarted := newTestPersistCache(t, cacheDir, newKey, oldKey)
+	expectValue(t, restarted, "secret_a", "value")
+
+	fresh := newTestPersistCache(t, cacheDir, newKey)
+	if report := fresh.Verify(); !report.OK {
+		t.Errorf("Verify with the new key only: got %+v", report)
+	}
+	expectValue(t, fresh, "secret_a", "value")
+}
@@ -0,0 +1,68 @@
+package storage
+
+import "fmt"
+
+// Seals and opens the values of the persist layers, the value is encrypted
+// with the current key and every known key is kept by its id so entries
+// that were written with an older key can still be opened
+type entryCodec struct {
+	cipher  *entryCipher
+	ciphers map[string]*entryCipher
+}
+
+func newEntryCodec() entryCodec {
+	return entryCodec{ciphers: make(map[string]*entryCipher)}
+}
+
+// Loading the current key and the previous ones
+func (c *entryCodec) useKeys(provider KeyProvider, previous ...KeyProvider) error {
+	for _, p := range previous {
+		prev, err := newEntryCipher(p)
+		if err != nil {
+			return fmt.Errorf("failed to load previous key: %v", err)
+		}
+		c.ciphers[prev.keyID] = prev
+	}
+	current, err := newEntryCipher(provider)
+	if err != nil {
+		return fmt.Errorf("failed to load key: %v", err)
+	}
+	c.ciphers[current.keyID] = current
+	c.cipher = current
+	return nil
+}
+
+// Returning the id of the key that new entries are written with
+func (c *entryCodec) keyID() string {
+	if c.cipher == nil {
+		return plainKeyID
+	}
+	return c.cipher.keyID
+}
+
+func (c *entryCodec) seal(key string, value []byte) (fileEntry, error) {
+	entry := fileEntry{key: key, keyID: plainKeyID, value: value}
+	if c.cipher == nil {
+		return entry, nil
+	}
+	sealed, err := c.cipher.seal(key, value)
+	if err != nil {
+		return entry, fmt.Errorf("error encrypting value: %v", err)
+	}
+	entry.keyID = c.cipher.keyID
+	entry.value = sealed
+	return entry, nil
+}
+
+// Decrypting the value of the entry, a failure here is not a corruption since
+// the entry may be encrypted with a key that wasn't loaded
+func (c *entryCodec) open(entry *fileEntry) ([]byte, error) {
+	if !entry.encrypted() {
+		return entry.value, nil
+	}
+	found, ok := c.ciphers[entry.keyID]
+	if !ok {
+		return nil, fmt.Errorf("key: %s is encrypted with unknown key id: %s", entry.key, entry.keyID)
+	}
+	return found.open(entry.key, entry.value)
+}
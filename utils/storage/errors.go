@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Returned (wrapped) by every cache layer when the key is not there
+var ErrNotFound = errors.New("key not found")
+
+// The key is not in the cache, errors.Is(err, ErrNotFound) is true
+type NotFoundError struct {
+	Key string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("key: %s was not found in cache", e.Key)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// The key is in the cache but its value can't be decoded to the wanted type,
+// the entry is corrupted or was saved with another type or codec
+type DecodeError struct {
+	Key   string
+	Codec string
+	Type  string
+	Err   error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("couldn't decode key: %s to %s with %s codec: %v", e.Key, e.Type, e.Codec, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Some entries couldn't be re-encrypted with the current key when the
+// encryption was set, they are left as they are and stay readable with the
+// keys that were loaded
+type ReencryptError struct {
+	Layer  string
+	Failed int
+}
+
+func (e *ReencryptError) Error() string {
+	return fmt.Sprintf("%s: failed to re-encrypt %d entries", e.Layer, e.Failed)
+}
+
+// Returning true when the error means the key is missing
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// Returning true when the key was found but couldn't be decoded
+func IsDecodeError(err error) bool {
+	var decodeErr *DecodeError
+	return errors.As(err, &decodeErr)
+}
+
+// Returning true when only some entries couldn't be re-encrypted, the
+// encryption itself was set
+func IsReencryptError(err error) bool {
+	var reencryptErr *ReencryptError
+	return errors.As(err, &reencryptErr)
+}
@@ -1,16 +1,53 @@
 package storage
 
 import (
+	"container/list"
 	"fmt"
+	"golang-secret-manager/types"
 	"os"
 	"os/signal"
+	"reflect"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/patrickmn/go-cache"
 )
 
+// Budget of the fast cache, when one of the limits is passed the least
+// recently used keys are evicted. Zero value means no limit
+type FastCacheConfig struct {
+	Expiration time.Duration
+	MaxEntries int
+	MaxBytes   int64
+
+	// Values that are not []byte are kept as they are in memory and are
+	// encoded with the codec only when they are saved to the lower layer
+	Codec Codec
+
+	// The registered types are saved inside a versioned envelope and the
+	// entries of older versions are migrated when they are loaded
+	Schemas *SchemaRegistry
+}
+
+// Entry inside the lru list
+type lruEntry struct {
+	key       string
+	size      int64
+	setAt     time.Time
+	expiresAt time.Time
+}
+
+// A changed key that was evicted and is being saved to the lower layer, the
+// reads are served from it until it is saved
+type pendingFlush struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+	opts      []SetOption
+}
+
 type FastCache struct {
 	mutex        sync.Mutex
 	instance     *cache.Cache
@@ -18,25 +55,54 @@ type FastCache struct {
 	changed      map[string]bool
 	changedMutex sync.Mutex
 	ch           chan bool
+	pending      map[string]*pendingFlush
+
+	config    FastCacheConfig
+	lru       *list.List
+	entries   map[string]*list.Element
+	usedBytes int64
+
+	migrations types.CacheMigrationReport
+
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	evictions   atomic.Uint64
+	expirations atomic.Uint64
 }
 
 var once sync.Once
 var fastCache *FastCache = nil
 
-func NewFastCache(expirationTime time.Duration) *FastCache {
-	// creating the go-cache instance
-	if fastCache == nil {
-		once.Do(func() {
-			chanel := make(chan bool)
-			changed := make(map[string]bool)
-			instance := cache.New(expirationTime, expirationTime*2)
-			fastCache = &FastCache{
-				instance: instance,
-				changed:  changed,
-				ch:       chanel,
-			}
-		})
+// Creating a new fast cache, every call returns its own instance
+func CreateFastCache(config FastCacheConfig) *FastCache {
+	if config.Codec == nil {
+		config.Codec = JSONCodec
 	}
+	return &FastCache{
+		// the janitor of go-cache is off, the expired keys are removed by
+		// deleteExpired so the lru list and the used bytes are kept with
+		// them
+		instance: cache.New(config.Expiration, 0),
+		changed:  make(map[string]bool),
+		pending:  make(map[string]*pendingFlush),
+		ch:       make(chan bool),
+		config:   config,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// NewFastCache and NewFastCacheWithConfig are returning the process wide
+// instance, they are kept for compatibility, new code should create its own
+// cache with CreateFastCache and pass it along
+func NewFastCache(expirationTime time.Duration) *FastCache {
+	return NewFastCacheWithConfig(FastCacheConfig{Expiration: expirationTime})
+}
+
+func NewFastCacheWithConfig(config FastCacheConfig) *FastCache {
+	once.Do(func() {
+		fastCache = CreateFastCache(config)
+	})
 	return fastCache
 }
 
@@ -50,14 +116,16 @@ func GetCacheInstance() ICache {
 // The fast cache implements the following interface:
 // type ICache interface {
 // 	Get(key string) (interface{}, error)
-// 	Set(key string, value interface{}) error
+// 	Set(key string, value interface{}, opts ...SetOption) error
 // 	Delete(key string) error
 // 	SetCacheLayer(layer ICache)
 // 	ActivateLayerSavingRuntime(intervals time.Duration) error
-// 	LayerSet(key string, value interface{}) error
+// 	LayerSet(key string, value interface{}, opts ...SetOption) error
 // }
 
 func (f *FastCache) GetAllKeys() []string {
+	f.changedMutex.Lock()
+	defer f.changedMutex.Unlock()
 	var list []string
 	for key := range f.changed {
 		list = append(list, key)
@@ -68,30 +136,58 @@ func (f *FastCache) GetAllKeys() []string {
 func (f *FastCache) Get(key string) (interface{}, error) {
 	// getting the key from the current layer
 	f.mutex.Lock()
-	defer f.mutex.Unlock()
+	value, evicted, err := f.get(key)
+	f.mutex.Unlock()
+	f.flushEvicted(evicted)
+	return value, err
+}
+
+// Get without locking, returning the changed keys that were evicted to make
+// room for the key that was read from the lower layer
+func (f *FastCache) get(key string) (interface{}, []*pendingFlush, error) {
+
+	if f.isExpired(key) {
+		// the ttl of the copy in memory passed, the lower layer is expiring
+		// its own copy by the ttl it was saved with
+		f.expire(key)
+	}
+
 	result, found := f.instance.Get(key)
-	if !found && f.layer != nil {
-		// was not found in the cache searching in the other layer
-		r, err := f.layer.Get(key)
-		if err != nil {
-			// file was not found in the other layer
-			return nil, err
-		} else {
-			// found in the other layer, applying it to the fast layer
-			f.safeSet(key, result)
-			f.SetChangedValue(key, false)
-			return r, nil
-		}
+	if found {
+		f.touch(key)
+		f.hits.Add(1)
+		return result, nil, nil
 	}
+	f.misses.Add(1)
 
-	// searching if the key is in the fast cache keys
-	for _, keyVal := range f.GetAllKeys() {
-		if keyVal == key {
-			return result, nil
-		}
+	if p, ok := f.pending[key]; ok {
+		// evicted and not saved yet, the lower layer holds an older value
+		return p.value, nil, nil
+	}
+	if f.layer == nil {
+		return nil, nil, &NotFoundError{Key: key}
 	}
-	// in fast layer
-	return nil, fmt.Errorf("key found in cache but was deleted before so")
+
+	// was not found in the cache searching in the other layer
+	r, expiresAt, err := GetWithExpiry(f.layer, key)
+	if err != nil {
+		// file was not found in the other layer
+		return nil, nil, err
+	}
+	ttl, ok := TTLUntil(expiresAt)
+	if !ok {
+		return nil, nil, &NotFoundError{Key: key}
+	}
+	value, migrated, err := f.open(key, r)
+	if err != nil {
+		return nil, nil, &DecodeError{Key: key, Codec: f.config.Codec.Name(), Type: "envelope", Err: err}
+	}
+	// found in the other layer, applying it to the fast layer with the
+	// expiry it was saved with, a migrated entry is saved again with the
+	// current version
+	f.safeSet(key, value, ttl)
+	f.SetChangedValue(key, migrated)
+	return value, f.enforceBudget(), nil
 }
 
 func (f *FastCache) SetChangedValue(key string, val bool) {
@@ -107,12 +203,12 @@ func (f *FastCache) ActivateLayerSavingRuntime(intervals time.Duration) error {
 	}
 
 	ticker := time.NewTicker(intervals)
-	defer ticker.Stop()
 
 	osChanel := make(chan os.Signal, 1)
 	signal.Notify(osChanel, syscall.SIGINT)
 
 	go func() {
+		defer ticker.Stop()
 		for {
 			select {
 			case <-osChanel:
@@ -120,20 +216,25 @@ func (f *FastCache) ActivateLayerSavingRuntime(intervals time.Duration) error {
 				signal.Reset(syscall.SIGINT)
 				return
 			case <-ticker.C:
+				// removing the expired keys before saving
+				f.mutex.Lock()
+				f.deleteExpired()
+				f.mutex.Unlock()
+				if layer, ok := f.layer.(interface{ DeleteExpired() int }); ok {
+					if removed := layer.DeleteExpired(); removed > 0 {
+						fmt.Println("Cache Runtime: removed", removed, "expired keys from lower level")
+					}
+				}
+
 				// Saving all the changed to the files
-				tempChangedKeys := f.changed
-				for key, val := range tempChangedKeys {
-					if val {
-						// this key was changed
-						if realVal, err := f.Get(key); err != nil {
-							// failed to save realVal
-							fmt.Println("Cache Runtime: Failed While trying to get key:", key, "and save it to the lower cache level")
-						} else if err := f.layer.Set(key, realVal); err != nil {
-							fmt.Println("Cache Runtime: Failed While trying to save key:", key, "to the lower cache level")
-						} else {
-							fmt.Println("Saving key:", key, "to lower level")
-							f.SetChangedValue(key, false)
-						}
+				for _, key := range f.changedKeys() {
+					f.mutex.Lock()
+					err := f.flushKey(key)
+					f.mutex.Unlock()
+					if err != nil {
+						fmt.Println("Cache Runtime:", err)
+					} else {
+						fmt.Println("Saving key:", key, "to lower level")
 					}
 				}
 			}
@@ -142,41 +243,67 @@ func (f *FastCache) ActivateLayerSavingRuntime(intervals time.Duration) error {
 	return nil
 }
 
-func (f *FastCache) LayerSet(key string, value interface{}) error {
+func (f *FastCache) LayerSet(key string, value interface{}, opts ...SetOption) error {
 	// Setting the value first in the current cache
-	err := f.Set(key, value)
+	err := f.Set(key, value, opts...)
 	if err != nil {
 		// failed to save to the current level
 		return err
 	}
 	if f.layer != nil {
-		err := f.layer.LayerSet(key, value)
-		return err
+		encoded, err := f.encode(value)
+		if err != nil {
+			return err
+		}
+		f.mutex.Lock()
+		layerOpts, _ := f.layerOptions(key)
+		f.mutex.Unlock()
+		return f.layer.LayerSet(key, encoded, layerOpts...)
 	}
 	// layer is nil, dont have where to save it
 	return nil
 }
 
-func (f *FastCache) Set(key string, value interface{}) error {
+func (f *FastCache) Set(key string, value interface{}, opts ...SetOption) error {
+	// encoded values (like the entries of a snapshot) are opened first so
+	// they are kept decoded like the rest
+	value, _, err := f.open(key, value)
+	if err != nil {
+		return &DecodeError{Key: key, Codec: f.config.Codec.Name(), Type: "envelope", Err: err}
+	}
+
 	f.mutex.Lock()
-	defer f.mutex.Unlock()
 	// setting the value to the fast cache
-	f.instance.Set(key, value, 0)
+	options := ApplySetOptions(opts...)
+	f.safeSet(key, value, options.TTL)
 	f.SetChangedValue(key, true)
+	evicted := f.enforceBudget()
+	f.mutex.Unlock()
+	f.flushEvicted(evicted)
 	return nil
 }
 
-func (f *FastCache) safeSet(key string, value interface{}) error {
-	f.instance.Set(key, value, 0)
+// Setting the value without locking, updating the lru list with the new size
+func (f *FastCache) safeSet(key string, value interface{}, ttl time.Duration) error {
+	f.instance.Set(key, value, ttl)
+
+	entry := lruEntry{key: key, size: sizeOf(value), setAt: time.Now(), expiresAt: f.expiresAt(ttl)}
+	if elem, ok := f.entries[key]; ok {
+		old := elem.Value.(*lruEntry)
+		f.usedBytes -= old.size
+		elem.Value = &entry
+		f.lru.MoveToFront(elem)
+	} else {
+		f.entries[key] = f.lru.PushFront(&entry)
+	}
+	f.usedBytes += entry.size
 	return nil
 }
 
 func (f *FastCache) Delete(key string) error {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
-	// for some reason thie Delete won't really delete from the cache
-	f.instance.Delete(key)
-	delete(f.changed, key)
+	f.removeEntry(key)
 	return nil
 }
 
@@ -189,19 +316,396 @@ func (f *FastCache) SetCacheLayer(layer ICache, load bool) error {
 
 	// if load == true then loading the lower cache to the upper cache
 	if load {
+		report := types.CacheMigrationReport{Failed: make(map[string]string)}
 		lst := f.layer.GetAllKeys()
 		for _, key := range lst {
-			item, err := f.layer.Get(key)
+			item, expiresAt, err := GetWithExpiry(f.layer, key)
 			if err != nil {
-				// failed to get key..
-				fmt.Println("failed to Get key:", key)
+				if !IsNotFound(err) {
+					// failed to get key..
+					fmt.Println("failed to Get key:", key)
+				}
+				continue
+			}
+			ttl, ok := TTLUntil(expiresAt)
+			if !ok {
+				// expired while loading
 				continue
 			}
-			err = f.Set(key, item)
+			report.Total++
+			value, migrated, err := f.open(key, item)
 			if err != nil {
-				fmt.Println("failed to Set key:", key)
+				// the entry is left in the lower layer as it is, so it can
+				// still be migrated when the missing migration is added
+				report.Failed[key] = err.Error()
+				continue
+			}
+			if migrated {
+				report.Migrated++
+			} else {
+				report.Current++
 			}
+			// the value is already saved in the lower layer unless it was
+			// migrated to a newer version, it is kept until the expiry it
+			// was saved with
+			f.mutex.Lock()
+			f.safeSet(key, value, ttl)
+			f.SetChangedValue(key, migrated)
+			evicted := f.enforceBudget()
+			f.mutex.Unlock()
+			f.flushEvicted(evicted)
+		}
+		f.mutex.Lock()
+		f.migrations = report
+		f.mutex.Unlock()
+	}
+	return nil
+}
+
+// Replacing the value of a key that is already in memory without marking it
+// as changed, used to keep the decoded value instead of the bytes that were
+// loaded from the lower layer. The ttl of the key is kept
+func (f *FastCache) Replace(key string, value interface{}) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	elem, ok := f.entries[key]
+	if !ok || f.isExpired(key) {
+		return false
+	}
+	old := elem.Value.(*lruEntry)
+	ttl := NoExpiration
+	if !old.expiresAt.IsZero() {
+		ttl = time.Until(old.expiresAt)
+	}
+	setAt := old.setAt
+	f.safeSet(key, value, ttl)
+	// the value is the same, only its form was changed
+	f.entries[key].Value.(*lruEntry).setAt = setAt
+	return true
+}
+
+// Like Get with the time the key expires at, the zero time means the key
+// never expires
+func (f *FastCache) GetWithExpiry(key string) (interface{}, time.Time, error) {
+	val, err := f.Get(key)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var expiresAt time.Time
+	if elem, ok := f.entries[key]; ok {
+		expiresAt = elem.Value.(*lruEntry).expiresAt
+	} else if p, ok := f.pending[key]; ok {
+		expiresAt = p.expiresAt
+	}
+	return val, expiresAt, nil
+}
+
+// Returning the value of the key encoded to bytes, like it is saved in the
+// lower layer, with the time it expires at
+func (f *FastCache) GetEncoded(key string) ([]byte, time.Time, error) {
+	val, expiresAt, err := f.GetWithExpiry(key)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	data, err := f.encode(val)
+	return data, expiresAt, err
+}
+
+// Dropping the key only from the memory, used when another replica changed
+// the key in the shared layer. The next Get will read it from the layer
+func (f *FastCache) Invalidate(key string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.removeEntry(key)
+}
+
+// Returning the lower layer of the cache
+func (f *FastCache) Layer() ICache {
+	return f.layer
+}
+
+// Returning the result of migrating the entries that were loaded from the
+// lower layer when it was set
+func (f *FastCache) MigrationReport() types.CacheMigrationReport {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	report := f.migrations
+	report.Failed = make(map[string]string, len(f.migrations.Failed))
+	for key, reason := range f.migrations.Failed {
+		report.Failed[key] = reason
+	}
+	return report
+}
+
+// Returning the current counters of the cache
+func (f *FastCache) Stats() types.CacheStats {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return types.CacheStats{
+		Hits:        f.hits.Load(),
+		Misses:      f.misses.Load(),
+		Evictions:   f.evictions.Load(),
+		Expirations: f.expirations.Load(),
+		Entries:     f.lru.Len(),
+		UsedBytes:   f.usedBytes,
+		MaxEntries:  f.config.MaxEntries,
+		MaxBytes:    f.config.MaxBytes,
+	}
+}
+
+// Returning the keys that were changed and not saved yet to the lower layer
+func (f *FastCache) changedKeys() []string {
+	f.changedMutex.Lock()
+	defer f.changedMutex.Unlock()
+	var keys []string
+	for key, val := range f.changed {
+		if val {
+			keys = append(keys, key)
 		}
 	}
+	return keys
+}
+
+// Saving a changed key to the lower layer, the caller must hold the mutex
+func (f *FastCache) flushKey(key string) error {
+	val, found := f.instance.Get(key)
+	if !found {
+		return fmt.Errorf("failed while trying to get key: %s and save it to the lower cache level", key)
+	}
+	opts, ok := f.layerOptions(key)
+	if !ok {
+		// expired before it was saved
+		f.SetChangedValue(key, false)
+		return nil
+	}
+	encoded, err := f.encode(val)
+	if err != nil {
+		return fmt.Errorf("failed while trying to encode key: %s: %v", key, err)
+	}
+	if err := f.layer.Set(key, encoded, opts...); err != nil {
+		return fmt.Errorf("failed while trying to save key: %s to the lower cache level: %v", key, err)
+	}
+	f.SetChangedValue(key, false)
 	return nil
 }
+
+// The options of saving the key to the lower layer, the key is saved with the
+// time that is left of its ttl so the lower layer expires it at the same
+// time. false is returned when it already expired, the caller must hold the
+// mutex
+func (f *FastCache) layerOptions(key string) ([]SetOption, bool) {
+	elem, ok := f.entries[key]
+	if !ok {
+		return nil, true
+	}
+	ttl, ok := TTLUntil(elem.Value.(*lruEntry).expiresAt)
+	return []SetOption{WithTTL(ttl)}, ok
+}
+
+// Moving the key to the front of the lru list
+func (f *FastCache) touch(key string) {
+	if elem, ok := f.entries[key]; ok {
+		f.lru.MoveToFront(elem)
+	}
+}
+
+func (f *FastCache) expiresAt(ttl time.Duration) time.Time {
+	if ttl == DefaultTTL {
+		ttl = f.config.Expiration
+	}
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+func (f *FastCache) isExpired(key string) bool {
+	elem, ok := f.entries[key]
+	if !ok {
+		return false
+	}
+	expiresAt := elem.Value.(*lruEntry).expiresAt
+	return !expiresAt.IsZero() && time.Now().After(expiresAt)
+}
+
+// Removing an expired key from memory, the lower layer holds the key with its
+// own expiry and removes it by itself
+func (f *FastCache) expire(key string) {
+	f.removeEntry(key)
+	f.expirations.Add(1)
+}
+
+func (f *FastCache) deleteExpired() {
+	for elem := f.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		if key := elem.Value.(*lruEntry).key; f.isExpired(key) {
+			f.expire(key)
+		}
+		elem = prev
+	}
+}
+
+// Evicting the least recently used keys until the cache is inside the budget,
+// the caller must hold the mutex. The changed keys are returned so they are
+// saved to the lower layer by flushEvicted after the mutex is released, the
+// reads don't wait for the lower layer
+func (f *FastCache) enforceBudget() []*pendingFlush {
+	if !f.overBudget() {
+		return nil
+	}
+	f.deleteExpired()
+	var evicted []*pendingFlush
+	for f.overBudget() && f.lru.Len() > 1 {
+		key := f.lru.Back().Value.(*lruEntry).key
+		var p *pendingFlush
+		if f.layer != nil && f.isChanged(key) {
+			if val, found := f.instance.Get(key); found {
+				if opts, ok := f.layerOptions(key); ok {
+					p = &pendingFlush{key: key, value: val, opts: opts,
+						expiresAt: f.entries[key].Value.(*lruEntry).expiresAt}
+				}
+			}
+		}
+		f.removeEntry(key)
+		f.evictions.Add(1)
+		if p != nil {
+			f.pending[key] = p
+			evicted = append(evicted, p)
+		}
+	}
+	return evicted
+}
+
+// Saving the evicted keys to the lower layer, the caller must not hold the
+// mutex. A key that was set or removed again in the meantime is skipped
+func (f *FastCache) flushEvicted(evicted []*pendingFlush) {
+	for _, p := range evicted {
+		f.mutex.Lock()
+		current := f.pending[p.key] == p
+		f.mutex.Unlock()
+		if !current {
+			continue
+		}
+
+		encoded, err := f.encode(p.value)
+		if err == nil {
+			err = f.layer.Set(p.key, encoded, p.opts...)
+		}
+		if err != nil {
+			fmt.Println("Cache Runtime: evicting unsaved key:", p.key, err)
+		}
+
+		f.mutex.Lock()
+		if f.pending[p.key] == p {
+			delete(f.pending, p.key)
+		}
+		f.mutex.Unlock()
+	}
+}
+
+func (f *FastCache) overBudget() bool {
+	if f.config.MaxEntries > 0 && f.lru.Len() > f.config.MaxEntries {
+		return true
+	}
+	return f.config.MaxBytes > 0 && f.usedBytes > f.config.MaxBytes
+}
+
+func (f *FastCache) isChanged(key string) bool {
+	f.changedMutex.Lock()
+	defer f.changedMutex.Unlock()
+	return f.changed[key]
+}
+
+// Removing the key from go-cache, the lru list, the changed map and the
+// evicted keys that are waiting to be saved
+func (f *FastCache) removeEntry(key string) {
+	f.instance.Delete(key)
+	delete(f.pending, key)
+	if elem, ok := f.entries[key]; ok {
+		f.usedBytes -= elem.Value.(*lruEntry).size
+		f.lru.Remove(elem)
+		delete(f.entries, key)
+	}
+	f.changedMutex.Lock()
+	delete(f.changed, key)
+	f.changedMutex.Unlock()
+}
+
+// The codec the values are encoded with when they are saved to the lower
+// layer
+func (f *FastCache) Codec() Codec {
+	return f.config.Codec
+}
+
+func (f *FastCache) encode(value interface{}) ([]byte, error) {
+	if b, ok := value.([]byte); ok {
+		return b, nil
+	}
+	if f.config.Schemas != nil {
+		sealed, registered, err := f.config.Schemas.Seal(value, f.config.Codec)
+		if registered {
+			return sealed, err
+		}
+	}
+	return f.config.Codec.Marshal(value)
+}
+
+// Opening an encoded value with the schemas, the value is returned as it is
+// when it is not encoded or there are no schemas
+func (f *FastCache) open(key string, value interface{}) (interface{}, bool, error) {
+	raw, ok := value.([]byte)
+	if !ok || f.config.Schemas == nil {
+		return value, false, nil
+	}
+	return f.config.Schemas.Open(key, raw, f.config.Codec)
+}
+
+// Estimating the memory size of a cached value
+func sizeOf(value interface{}) int64 {
+	switch v := value.(type) {
+	case []byte:
+		return int64(len(v))
+	case string:
+		return int64(len(v))
+	default:
+		return estimateSize(reflect.ValueOf(value), 0)
+	}
+}
+
+// Walking the value and summing the size of its strings, slices and maps
+func estimateSize(v reflect.Value, depth int) int64 {
+	if !v.IsValid() || depth > 8 {
+		return 0
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return int64(16 + v.Len())
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return 8
+		}
+		return 8 + estimateSize(v.Elem(), depth+1)
+	case reflect.Slice, reflect.Array:
+		size := int64(24)
+		for i := 0; i < v.Len(); i++ {
+			size += estimateSize(v.Index(i), depth+1)
+		}
+		return size
+	case reflect.Map:
+		size := int64(48)
+		iter := v.MapRange()
+		for iter.Next() {
+			size += estimateSize(iter.Key(), depth+1) + estimateSize(iter.Value(), depth+1)
+		}
+		return size
+	case reflect.Struct:
+		var size int64
+		for i := 0; i < v.NumField(); i++ {
+			size += estimateSize(v.Field(i), depth+1)
+		}
+		return size
+	}
+	return int64(v.Type().Size())
+}
@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"golang-secret-manager/types"
+	"sort"
+	"time"
+)
+
+// Returning the keys of the memory and of the lower layer, sorted
+func (f *FastCache) Keys() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	set := make(map[string]bool)
+	for key := range f.entries {
+		if !f.isExpired(key) {
+			set[key] = true
+		}
+	}
+	if f.layer != nil {
+		for _, key := range f.layer.GetAllKeys() {
+			set[key] = true
+		}
+	}
+
+	list := make([]string, 0, len(set))
+	for key := range set {
+		list = append(list, key)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Returning the information of a single key without touching its place in
+// the lru list. Keys that are only in the lower layer have no age
+func (f *FastCache) Inspect(key string) (types.CacheEntryInfo, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	info := types.CacheEntryInfo{
+		Key:       key,
+		Namespace: KeyNamespace(key, DefaultNamespaces),
+	}
+
+	if elem, ok := f.entries[key]; ok && !f.isExpired(key) {
+		entry := elem.Value.(*lruEntry)
+		setAt := entry.setAt
+		info.InMemory = true
+		info.Dirty = f.isChanged(key)
+		info.SizeBytes = entry.size
+		info.SetAt = &setAt
+		info.Age = time.Since(setAt).Round(time.Second).String()
+		if !entry.expiresAt.IsZero() {
+			expiresAt := entry.expiresAt
+			info.ExpiresAt = &expiresAt
+		}
+		if val, found := f.instance.Get(key); found {
+			info.Value = f.inspectValue(key, val)
+		}
+		return info, nil
+	}
+
+	if f.layer == nil {
+		return info, &NotFoundError{Key: key}
+	}
+	val, err := f.layer.Get(key)
+	if err != nil {
+		return info, err
+	}
+	info.SizeBytes = sizeOf(val)
+	info.Value = f.inspectValue(key, val)
+	return info, nil
+}
+
+// Values that are still encoded are opened with the schemas or decoded to a
+// generic value so they can be shown, nil is returned when the codec can't
+// decode them without the type
+func (f *FastCache) inspectValue(key string, val interface{}) interface{} {
+	if opened, _, err := f.open(key, val); err == nil {
+		val = opened
+	}
+	b, ok := val.([]byte)
+	if !ok {
+		return val
+	}
+	var decoded interface{}
+	if err := f.config.Codec.Unmarshal(b, &decoded); err != nil {
+		return nil
+	}
+	return decoded
+}
+
+// Removing the key from the memory and from the lower layer, unlike Delete the
+// next Get won't load it again
+func (f *FastCache) Purge(key string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.removeEntry(key)
+	if f.layer == nil {
+		return nil
+	}
+	if err := f.layer.Delete(key); err != nil && !IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Saving all the changed keys to the lower layer now instead of waiting to
+// the saving runtime, returning how many keys were saved
+func (f *FastCache) Flush() (int, error) {
+	if f.layer == nil {
+		return 0, fmt.Errorf("there is no another layer to the cache")
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.deleteExpired()
+
+	flushed := 0
+	var errs []error
+	for _, key := range f.changedKeys() {
+		if err := f.flushKey(key); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		flushed++
+	}
+	return flushed, errors.Join(errs...)
+}
+
+// Returning the keys that would be removed if the budget was enforced now,
+// nothing is removed. Zero limits are using the limits of the config
+func (f *FastCache) PreviewEvictions(maxEntries int, maxBytes int64) []types.CacheEviction {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if maxEntries == 0 {
+		maxEntries = f.config.MaxEntries
+	}
+	if maxBytes == 0 {
+		maxBytes = f.config.MaxBytes
+	}
+
+	var evictions []types.CacheEviction
+	expired := make(map[string]bool)
+	entries := f.lru.Len()
+	usedBytes := f.usedBytes
+
+	// the expired keys are removed first
+	for elem := f.lru.Back(); elem != nil; elem = elem.Prev() {
+		entry := elem.Value.(*lruEntry)
+		if !f.isExpired(entry.key) {
+			continue
+		}
+		expired[entry.key] = true
+		entries--
+		usedBytes -= entry.size
+		evictions = append(evictions, f.eviction(entry, "expired"))
+	}
+
+	overBudget := func() bool {
+		if maxEntries > 0 && entries > maxEntries {
+			return true
+		}
+		return maxBytes > 0 && usedBytes > maxBytes
+	}
+
+	// then the least recently used ones, like enforceBudget the last key is kept
+	for elem := f.lru.Back(); elem != nil && overBudget() && entries > 1; elem = elem.Prev() {
+		entry := elem.Value.(*lruEntry)
+		if expired[entry.key] {
+			continue
+		}
+		entries--
+		usedBytes -= entry.size
+		evictions = append(evictions, f.eviction(entry, "lru"))
+	}
+	return evictions
+}
+
+func (f *FastCache) eviction(entry *lruEntry, reason string) types.CacheEviction {
+	return types.CacheEviction{
+		Key:       entry.key,
+		Namespace: KeyNamespace(entry.key, DefaultNamespaces),
+		Reason:    reason,
+		Dirty:     f.isChanged(entry.key),
+		SizeBytes: entry.size,
+	}
+}
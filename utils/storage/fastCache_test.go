@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func expectMissing(t *testing.T, cache ICache, key string) {
+	t.Helper()
+	if _, err := cache.Get(key); !IsNotFound(err) {
+		t.Errorf("Get %s: got %v, want a NotFoundError", key, err)
+	}
+}
+
+func TestFastCacheLRUOrder(t *testing.T) {
+	cache := CreateFastCache(FastCacheConfig{Expiration: time.Minute, MaxEntries: 2})
+	for _, key := range []string{"secret_a", "secret_b"} {
+		if err := cache.Set(key, []byte(key)); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+	}
+	// reading secret_a makes secret_b the least recently used key
+	expectValue(t, cache, "secret_a", "secret_a")
+	if err := cache.Set("secret_c", []byte("secret_c")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	expectMissing(t, cache, "secret_b")
+	expectValue(t, cache, "secret_a", "secret_a")
+	expectValue(t, cache, "secret_c", "secret_c")
+	if stats := cache.Stats(); stats.Entries != 2 || stats.Evictions != 1 {
+		t.Errorf("Stats: got %+v", stats)
+	}
+}
+
+func TestFastCacheByteBudget(t *testing.T) {
+	cache := CreateFastCache(FastCacheConfig{Expiration: time.Minute, MaxBytes: 10})
+	for _, key := range []string{"a", "b", "c"} {
+		if err := cache.Set(key, []byte("1234")); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+	}
+
+	expectMissing(t, cache, "a")
+	if stats := cache.Stats(); stats.UsedBytes != 8 || stats.Entries != 2 || stats.Evictions != 1 {
+		t.Errorf("Stats: got %+v", stats)
+	}
+
+	// a bigger value of a key that is kept replaces its size
+	if err := cache.Set("c", []byte("123456")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if stats := cache.Stats(); stats.UsedBytes != 10 {
+		t.Errorf("UsedBytes: got %d, want 10", stats.UsedBytes)
+	}
+}
+
+func TestFastCacheExpiryReleasesBudget(t *testing.T) {
+	cache := CreateFastCache(FastCacheConfig{Expiration: time.Minute, MaxBytes: 100})
+	if err := cache.Set("secret_a", []byte("1234"), WithTTL(10*time.Millisecond)); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	expectMissing(t, cache, "secret_a")
+	if stats := cache.Stats(); stats.Entries != 0 || stats.UsedBytes != 0 || stats.Expirations != 1 {
+		t.Errorf("Stats: got %+v", stats)
+	}
+}
+
+func TestFastCacheReadThrough(t *testing.T) {
+	layer := CreateFastCache(FastCacheConfig{Expiration: time.Minute})
+	for _, key := range []string{"secret_a", "secret_b", "secret_c"} {
+		if err := layer.Set(key, []byte(key), WithTTL(time.Minute)); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+	}
+	cache := CreateFastCache(FastCacheConfig{Expiration: time.Hour, MaxEntries: 2})
+	if err := cache.SetCacheLayer(layer, false); err != nil {
+		t.Fatalf("SetCacheLayer: %v", err)
+	}
+
+	for _, key := range []string{"secret_a", "secret_b", "secret_c"} {
+		expectValue(t, cache, key, key)
+	}
+	// the keys read from the layer are kept inside the budget
+	stats := cache.Stats()
+	if stats.Entries != 2 || stats.Misses != 3 || stats.Evictions != 1 {
+		t.Errorf("Stats: got %+v", stats)
+	}
+
+	// with the expiry they were saved with, not the one of the cache
+	_, expiresAt, err := cache.GetWithExpiry("secret_c")
+	if err != nil {
+		t.Fatalf("GetWithExpiry: %v", err)
+	}
+	if left := time.Until(expiresAt); left <= 0 || left > time.Minute {
+		t.Errorf("the key expires in %v, want at most a minute", left)
+	}
+	if hits := cache.Stats().Hits; hits != 1 {
+		t.Errorf("Hits: got %d, want 1", hits)
+	}
+}
+
+func TestFastCacheEvictionSavesChangedKeys(t *testing.T) {
+	layer := CreateFastCache(FastCacheConfig{Expiration: time.Minute})
+	cache := CreateFastCache(FastCacheConfig{Expiration: time.Minute, MaxEntries: 1})
+	if err := cache.SetCacheLayer(layer, false); err != nil {
+		t.Fatalf("SetCacheLayer: %v", err)
+	}
+
+	if err := cache.Set("secret_a", []byte("first")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := cache.Set("secret_b", []byte("second")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// secret_a was not saved yet, the eviction saved it to the layer
+	expectValue(t, layer, "secret_a", "first")
+	if len(cache.pending) != 0 {
+		t.Errorf("%d evicted keys are still pending", len(cache.pending))
+	}
+	expectValue(t, cache, "secret_a", "first")
+}
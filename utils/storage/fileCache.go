@@ -2,50 +2,68 @@ package storage
 
 import (
 	"fmt"
-	"io"
+	"golang-secret-manager/types"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
 type PersistCache struct {
-	mutex        sync.Mutex
-	HomeDir      *os.File
-	dirPath      string
-	fileNameList []string
-	layer        ICache
+	mutex   sync.Mutex
+	HomeDir *os.File
+	dirPath string
+	keys    map[string]string
+	layer   ICache
+
+	// the keys that expire, by the time they expire at
+	expires map[string]time.Time
+
+	codec entryCodec
 }
 
 var onceC sync.Once
 var fileCache *PersistCache
 
-// GetCacheInstance returns the singleton instance of the cache
-func NewPersistCache(storagePath string) *PersistCache {
-	onceC.Do(func() {
-		fileCache = new(PersistCache)
-		fileCache.dirPath = storagePath
+// Creating a new persist cache on the directory, every call returns its own
+// instance so two instances must not share the same directory
+func CreatePersistCache(storagePath string) (*PersistCache, error) {
+	f := &PersistCache{
+		dirPath: storagePath,
+		keys:    make(map[string]string),
+		expires: make(map[string]time.Time),
+		codec:   newEntryCodec(),
+	}
 
-		// creating the directory if it doesn't exist
-		if _, err := os.Stat(fileCache.dirPath); os.IsNotExist(err) {
-			os.Mkdir(fileCache.dirPath, 0755)
-		}
+	// creating the directory if it doesn't exist
+	if err := os.MkdirAll(f.dirPath, 0755); err != nil {
+		return nil, fmt.Errorf("FileCache: Failed to create the dir: %v", err)
+	}
 
-		// loading the file name mapping
-		file, err := os.Open(fileCache.dirPath)
-		if err != nil {
-			// FileCache: Failed to create the dir
-			panic(err)
-		}
-		fileCache.HomeDir = file
+	file, err := os.Open(f.dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("FileCache: Failed to open the dir: %v", err)
+	}
+	f.HomeDir = file
 
-		// getting all the file names
-		files, err := file.Readdir(-1)
-		if err != nil {
+	// loading the key to file name index
+	if err := f.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// NewPersistCache returns the process wide instance, it is kept for
+// compatibility and panics when the directory can't be loaded
+func NewPersistCache(storagePath string) *PersistCache {
+	onceC.Do(func() {
+		var err error
+		if fileCache, err = CreatePersistCache(storagePath); err != nil {
 			panic(err)
 		}
-		for _, file := range files {
-			fileCache.fileNameList = append(fileCache.fileNameList, file.Name())
-		}
 	})
 	return fileCache
 }
@@ -58,113 +76,115 @@ func GetFileCache() *PersistCache {
 	return fileCache
 }
 
-func (f *PersistCache) createFile(fileName string) (*os.File, error) {
-
-	// creating file inside the Working dir
-	err := f.HomeDir.Chdir()
-	if err != nil {
-		return nil, err
-	}
-
-	file, err := os.Create(fileName)
-	if err != nil {
-		return nil, err
-	}
-	return file, nil
-}
-
-// The fast cache implements the following interface:
+// The persist cache implements the following interface:
 // type ICache interface {
 // 	Get(key string) (interface{}, error)
-// 	Set(key string, value interface{}) error
+// 	Set(key string, value interface{}, opts ...SetOption) error
 // 	Delete(key string) error
 // 	SetCacheLayer(layer ICache) error
 // 	ActivateLayerSavingRuntime(intervals time.Duration) error
-// 	LayerSet(key string, value interface{}) error
+// 	LayerSet(key string, value interface{}, opts ...SetOption) error
 // }
 
 func (f *PersistCache) Get(key string) (interface{}, error) {
+	val, _, err := f.GetWithExpiry(key)
+	return val, err
+}
+
+// Getting the key with the time it expires at, an expired key is removed
+func (f *PersistCache) GetWithExpiry(key string) (interface{}, time.Time, error) {
 	// getting the key from the current layer
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
-	result, err := f.readFile(key)
-	if err != nil && f.layer != nil {
-		// failed to read file, maybe doesn't exists searching in layer
-		val, err := f.layer.Get(key)
-		if err != nil {
-			return nil, err
-		}
 
-		err = f.Set(key, val)
-		if err != nil {
-			// failed to set to the current cache
-			return nil, err
+	fileName, found := f.keys[key]
+	if found {
+		entry, err := f.readRawEntry(fileName, key)
+		if err == nil && entry.expired(time.Now()) {
+			f.removeFile(key)
+			err = &NotFoundError{Key: key}
 		}
-
-		return val, nil
-	}
-
-	// searching if the key is in the fast cache keys
-	for _, keyVal := range f.GetAllKeys() {
-		if keyVal == key {
-			return result, nil
+		var data []byte
+		if err == nil {
+			data, err = f.codec.open(entry)
 		}
-	}
-
-	// in fast layer
-	return nil, fmt.Errorf("key found in cache but was deleted before so")
-}
-func (f *PersistCache) Set(key string, value interface{}) error {
-	f.mutex.Lock()
-	defer f.mutex.Unlock()
-	// First deleting the file if exsits
-	for _, name := range f.fileNameList {
-		if name == key {
-			err := f.deleteFile(key)
-			if err != nil {
-				// maybe file was not there
-				fmt.Printf("FileCache: Failed to delete file name: %s", key)
+		if err == nil {
+			return data, entry.expiresAt, nil
+		}
+		if f.layer == nil {
+			if IsNotFound(err) {
+				return nil, time.Time{}, err
 			}
-			f.fileNameList = removeStringFromList(f.fileNameList, key)
-			break
+			return nil, time.Time{}, fmt.Errorf("FileCache: %v", err)
+		}
+		if !IsNotFound(err) {
+			fmt.Println("FileCache:", err)
 		}
 	}
 
-	// Setting new value
-	file, err := f.createFile(key)
-	if err != nil {
-		return fmt.Errorf("FileCache: Error creating file: %v", err)
-	}
-	defer file.Close()
-
-	val, ok := value.([]byte)
-	if !ok {
-		return fmt.Errorf("FileCache: Error converting value to []byte")
+	if f.layer == nil {
+		return nil, time.Time{}, &NotFoundError{Key: key}
 	}
 
-	_, err = file.Write(val)
+	// failed to read file, maybe doesn't exists searching in layer
+	val, expiresAt, err := GetWithExpiry(f.layer, key)
 	if err != nil {
-		return fmt.Errorf("fileCache: Error writing to file: %v", err)
+		return nil, time.Time{}, err
+	}
+	if err := f.writeEntry(key, val, expiresAt); err != nil {
+		// failed to set to the current cache
+		return nil, time.Time{}, err
 	}
+	return val, expiresAt, nil
+}
 
-	// adding to the file names
-	f.fileNameList = append(f.fileNameList, key)
-	return nil
+func (f *PersistCache) Set(key string, value interface{}, opts ...SetOption) error {
+	// the expiry is saved with the entry, the keys that were set without a
+	// ttl are kept until they are deleted
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.writeEntry(key, value, ExpiryOf(opts, NoExpiration))
 }
 
 func (f *PersistCache) Delete(key string) error {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
 
-	err := f.deleteFile(key)
-	if err != nil {
+	if _, found := f.keys[key]; !found {
+		return &NotFoundError{Key: key}
+	}
+	return f.removeFile(key)
+}
+
+// Removing the expired entries, returning how many were removed
+func (f *PersistCache) DeleteExpired() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	now := time.Now()
+	removed := 0
+	for key, expiresAt := range f.expires {
+		if !now.After(expiresAt) {
+			continue
+		}
+		if err := f.removeFile(key); err != nil {
+			fmt.Println("FileCache: failed to remove expired key:", key, err)
+			continue
+		}
+		removed++
+	}
+	return removed
+}
+
+// Removing the file of the key and dropping it from the index
+func (f *PersistCache) removeFile(key string) error {
+	if err := os.Remove(filepath.Join(f.dirPath, f.keys[key])); err != nil && !os.IsNotExist(err) {
 		// failed to remove
 		return err
 	}
-
-	// removing from the file
-	f.fileNameList = removeStringFromList(f.fileNameList, key)
-	return nil
+	delete(f.keys, key)
+	delete(f.expires, key)
+	return syncDir(f.dirPath)
 }
 
 func (f *PersistCache) SetCacheLayer(layer ICache, load bool) error {
@@ -179,57 +199,251 @@ func (f *PersistCache) ActivateLayerSavingRuntime(intervals time.Duration) error
 	// NONE only to satisfiy the interface
 	return nil
 }
-func (f *PersistCache) LayerSet(key string, value interface{}) error {
+
+func (f *PersistCache) LayerSet(key string, value interface{}, opts ...SetOption) error {
 	// Like normal Set
-	return f.Set(key, value)
+	return f.Set(key, value, opts...)
 }
 
 func (f *PersistCache) GetAllKeys() []string {
-	return f.fileNameList
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var list []string
+	for key := range f.keys {
+		list = append(list, key)
+	}
+	sort.Strings(list)
+	return list
 }
 
-func (f *PersistCache) readFile(fileName string) ([]byte, error) {
-	err := f.HomeDir.Chdir()
-	if err != nil {
-		return nil, err
+// Encrypting the entries with the key of the provider, the previous providers
+// are only used to open entries that were written before. All the entries are
+// re-encrypted with the new key, so rotating the key is done by passing the
+// old provider as previous
+func (f *PersistCache) SetEncryption(provider KeyProvider, previous ...KeyProvider) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := f.codec.useKeys(provider, previous...); err != nil {
+		return fmt.Errorf("FileCache: %v", err)
+	}
+	return f.reencrypt()
+}
+
+// Replacing the current key with a new one and re-encrypting all the entries
+func (f *PersistCache) RotateKey(provider KeyProvider) error {
+	return f.SetEncryption(provider)
+}
+
+// Checking that every entry can be read and decrypted
+func (f *PersistCache) Verify() types.CacheVerifyReport {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	report := types.CacheVerifyReport{Failed: make(map[string]string)}
+	report.KeyID = f.codec.keyID()
+	for key, fileName := range f.keys {
+		report.Total++
+		entry, err := f.readRawEntry(fileName, key)
+		if err != nil {
+			report.Failed[key] = err.Error()
+			continue
+		}
+		if _, err := f.codec.open(entry); err != nil {
+			report.Failed[key] = err.Error()
+			continue
+		}
+		if entry.encrypted() {
+			report.Encrypted++
+		} else {
+			report.Plain++
+		}
+	}
+	report.OK = len(report.Failed) == 0
+	return report
+}
+
+// Writing again every entry that is not encrypted with the current key
+func (f *PersistCache) reencrypt() error {
+	failed := 0
+	for key, fileName := range f.keys {
+		entry, err := f.readRawEntry(fileName, key)
+		if err != nil {
+			fmt.Println("FileCache:", err)
+			failed++
+			continue
+		}
+		if entry.keyID == f.codec.keyID() {
+			continue
+		}
+		value, err := f.codec.open(entry)
+		if err == nil {
+			err = f.writeEntry(key, value, entry.expiresAt)
+		}
+		if err != nil {
+			fmt.Println("FileCache: failed to re-encrypt key:", key, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return &ReencryptError{Layer: "FileCache", Failed: failed}
 	}
-	file, err := os.Open(fileName)
+	return nil
+}
+
+// Writing the entry to a temp file, syncing it and then renaming it over the
+// old file so a crash in the middle of the write won't leave a broken entry
+func (f *PersistCache) writeEntry(key string, value interface{}, expiresAt time.Time) error {
+	val, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("FileCache: Error converting value to []byte")
+	}
+
+	entry, err := f.codec.seal(key, val)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("FileCache: %v", err)
 	}
-	defer file.Close()
+	entry.expiresAt = expiresAt
 
-	data, err := io.ReadAll(file)
+	fileName := encodeFileName(key)
+	temp, err := os.CreateTemp(f.dirPath, tempFilePrefix+"*")
 	if err != nil {
-		// failed to read
-		return nil, err
+		return fmt.Errorf("FileCache: Error creating file: %v", err)
 	}
+	// removing the temp file if we fail before the rename
+	defer os.Remove(temp.Name())
 
-	return data, nil
+	if _, err := temp.Write(encodeEntry(entry)); err != nil {
+		temp.Close()
+		return fmt.Errorf("FileCache: Error writing to file: %v", err)
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return fmt.Errorf("FileCache: Error syncing file: %v", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("FileCache: Error closing file: %v", err)
+	}
+	if err := os.Rename(temp.Name(), filepath.Join(f.dirPath, fileName)); err != nil {
+		return fmt.Errorf("FileCache: Error renaming file: %v", err)
+	}
+	if err := syncDir(f.dirPath); err != nil {
+		return fmt.Errorf("FileCache: Error syncing dir: %v", err)
+	}
+
+	f.keys[key] = fileName
+	f.setExpiry(key, expiresAt)
+	return nil
 }
 
-func (f *PersistCache) deleteFile(fileName string) error {
-	err := f.HomeDir.Chdir()
+func (f *PersistCache) setExpiry(key string, expiresAt time.Time) {
+	if expiresAt.IsZero() {
+		delete(f.expires, key)
+	} else {
+		f.expires[key] = expiresAt
+	}
+}
+
+func (f *PersistCache) readRawEntry(fileName string, key string) (*fileEntry, error) {
+	raw, err := os.ReadFile(filepath.Join(f.dirPath, fileName))
 	if err != nil {
-		return err
+		return nil, err
+	}
+	entry, err := decodeEntry(raw)
+	if err == nil && entry.key != key {
+		// the file is fine but belongs to another key, it is left in place
+		fmt.Println("FileCache: file:", fileName, "of key:", key, "holds the key:", entry.key)
+		delete(f.keys, key)
+		delete(f.expires, key)
+		return nil, &NotFoundError{Key: key}
 	}
-	err = os.Remove(fileName)
+	if err != nil {
+		f.quarantine(fileName, err)
+		delete(f.keys, key)
+		delete(f.expires, key)
+		return nil, fmt.Errorf("key: %s is corrupted: %v", key, err)
+	}
+	return entry, nil
+}
+
+// Building the key index from the directory, removing leftovers of writes that
+// never finished and the expired entries, quarantining corrupted entries,
+// converting entries that were saved with the key as the file name and
+// renaming entries that were saved with an older file name
+func (f *PersistCache) load() error {
+	files, err := os.ReadDir(f.dirPath)
 	if err != nil {
 		return err
 	}
+	now := time.Now()
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() {
+			continue
+		}
+		if strings.HasPrefix(name, tempFilePrefix) {
+			// the process crashed in the middle of a write
+			os.Remove(filepath.Join(f.dirPath, name))
+			continue
+		}
+
+		raw, err := os.ReadFile(filepath.Join(f.dirPath, name))
+		if err != nil {
+			fmt.Println("FileCache: failed to read file:", name, err)
+			continue
+		}
+
+		if !strings.HasSuffix(name, entrySuffix) {
+			// old entry, the file name is the key and the content is the value
+			if err := f.writeEntry(name, raw, time.Time{}); err != nil {
+				fmt.Println("FileCache: failed to convert old entry:", name, err)
+				continue
+			}
+			os.Remove(filepath.Join(f.dirPath, name))
+			continue
+		}
+
+		entry, err := decodeEntry(raw)
+		if err != nil {
+			f.quarantine(name, err)
+			continue
+		}
+		if entry.expired(now) {
+			os.Remove(filepath.Join(f.dirPath, name))
+			continue
+		}
+		if current := encodeFileName(entry.key); name != current {
+			if err := os.Rename(filepath.Join(f.dirPath, name), filepath.Join(f.dirPath, current)); err != nil {
+				fmt.Println("FileCache: failed to rename file:", name, err)
+			} else {
+				name = current
+			}
+		}
+		f.keys[entry.key] = name
+		f.setExpiry(entry.key, entry.expiresAt)
+	}
 	return nil
 }
 
-func removeStringFromList(slice []string, item string) []string {
-	index := -1
-	for i, v := range slice {
-		if v == item {
-			index = i
-			break
-		}
+// Moving a corrupted file to the quarantine dir so it won't be loaded again
+func (f *PersistCache) quarantine(fileName string, reason error) {
+	fmt.Println("FileCache: quarantining corrupted file:", fileName, "reason:", reason)
+	dir := filepath.Join(f.dirPath, quarantineDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Println("FileCache: failed to create quarantine dir:", err)
+		return
+	}
+	target := filepath.Join(dir, fmt.Sprintf("%s.%d", fileName, time.Now().UnixNano()))
+	if err := os.Rename(filepath.Join(f.dirPath, fileName), target); err != nil {
+		fmt.Println("FileCache: failed to quarantine file:", fileName, err)
 	}
-	if index == -1 {
-		return slice
+}
+
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
 	}
-	return append(slice[:index], slice[index+1:]...)
+	defer dir.Close()
+	return dir.Sync()
 }
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestPersistCacheFileFormat(t *testing.T) {
+	dir := t.TempDir()
+	cache := newTestPersistCache(t, dir, nil)
+
+	// the keys differ only by case
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := cache.Set("Secret_A", []byte("upper"), WithTTL(time.Until(expiresAt))); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := cache.Set("secret_a", []byte("lower")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2", len(files))
+	}
+	name := regexp.MustCompile(`^[0-9a-f]{64}\.entry$`)
+	for _, file := range files {
+		if !name.MatchString(file.Name()) {
+			t.Errorf("file name %s is not the lowercase hex of a hash", file.Name())
+		}
+	}
+
+	raw, err := os.ReadFile(filepath.Join(dir, encodeFileName("secret_a")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := entryMagic + "\nkey:" + base64.StdEncoding.EncodeToString([]byte("secret_a")) + "\nenc:none\nexp:none\n"
+	if !bytes.HasPrefix(raw, []byte(header)) || !bytes.HasSuffix(raw, []byte("\nlower")) {
+		t.Errorf("unexpected entry:\n%s", raw)
+	}
+
+	// a restart reads both keys back with their expiry
+	reopened := newTestPersistCache(t, dir, nil)
+	expectValue(t, reopened, "secret_a", "lower")
+	_, got, err := reopened.GetWithExpiry("Secret_A")
+	if err != nil {
+		t.Fatalf("GetWithExpiry: %v", err)
+	}
+	if got.Sub(expiresAt).Abs() > time.Second {
+		t.Errorf("expires at %v, want %v", got, expiresAt)
+	}
+}
+
+func TestPersistCacheRenamesOldFileNames(t *testing.T) {
+	dir := t.TempDir()
+	old := base64.RawURLEncoding.EncodeToString([]byte("secret_a")) + entrySuffix
+	entry := encodeEntry(fileEntry{key: "secret_a", keyID: plainKeyID, value: []byte("value")})
+	if err := os.WriteFile(filepath.Join(dir, old), entry, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := newTestPersistCache(t, dir, nil)
+	expectValue(t, cache, "secret_a", "value")
+	if _, err := os.Stat(filepath.Join(dir, old)); !os.IsNotExist(err) {
+		t.Errorf("the old file is still there: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, encodeFileName("secret_a"))); err != nil {
+		t.Errorf("the file was not renamed: %v", err)
+	}
+}
+
+func TestPersistCacheKeyMismatchIsNotFound(t *testing.T) {
+	dir := t.TempDir()
+	cache := newTestPersistCache(t, dir, nil)
+	if err := cache.Set("secret_a", []byte("value")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// the file of secret_a now holds another key
+	path := filepath.Join(dir, encodeFileName("secret_a"))
+	other := encodeEntry(fileEntry{key: "secret_b", keyID: plainKeyID, value: []byte("other")})
+	if err := os.WriteFile(path, other, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cache.Get("secret_a"); !IsNotFound(err) {
+		t.Errorf("Get: got %v, want a NotFoundError", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("the file was moved: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, quarantineDir)); !os.IsNotExist(err) {
+		t.Errorf("the file was quarantined: %v", err)
+	}
+}
+
+func TestPersistCacheQuarantinesCorruptedFiles(t *testing.T) {
+	dir := t.TempDir()
+	cache := newTestPersistCache(t, dir, nil)
+	for _, key := range []string{"secret_a", "secret_b"} {
+		if err := cache.Set(key, []byte("value")); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+	}
+	corrupt := func(key string) {
+		path := filepath.Join(dir, encodeFileName(key))
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, append(raw, "tampered"...), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// found by a read
+	corrupt("secret_a")
+	if _, err := cache.Get("secret_a"); err == nil || IsNotFound(err) {
+		t.Errorf("Get of a corrupted file: got %v", err)
+	}
+	// and by a restart
+	corrupt("secret_b")
+	reopened := newTestPersistCache(t, dir, nil)
+	if keys := reopened.GetAllKeys(); len(keys) != 0 {
+		t.Errorf("the corrupted keys were loaded: %v", keys)
+	}
+
+	quarantined, err := os.ReadDir(filepath.Join(dir, quarantineDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(quarantined) != 2 {
+		t.Errorf("got %d quarantined files, want 2", len(quarantined))
+	}
+	for _, key := range []string{"secret_a", "secret_b"} {
+		if _, err := os.Stat(filepath.Join(dir, encodeFileName(key))); !os.IsNotExist(err) {
+			t.Errorf("the file of %s is still in the cache dir", key)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Every entry of the persist cache is saved in its own file:
+//
+//	SMCACHE3\n
+//	key:<base64 of the key>\n
+//	enc:<id of the data key, or none>\n
+//	exp:<RFC3339 time the key expires at, or none>\n
+//	sha256:<hex checksum of the stored value>\n
+//	<value, encrypted when enc is not none>
+//
+// SMCACHE2 entries are the same without the exp line and never expire,
+// SMCACHE1 entries are also without the enc line, they are always plain.
+// The file name is the lowercase hex of the sha256 of the key, so keys that
+// differ only by case don't share a file on case insensitive file systems.
+// The key is recovered from the header
+
+const (
+	entryMagicV1   = "SMCACHE1"
+	entryMagicV2   = "SMCACHE2"
+	entryMagic     = "SMCACHE3"
+	entrySuffix    = ".entry"
+	tempFilePrefix = ".tmp-"
+	quarantineDir  = "quarantine"
+	plainKeyID     = "none"
+	noExpiry       = "none"
+)
+
+// Decoded file of the persist cache
+type fileEntry struct {
+	key   string
+	keyID string
+	value []byte
+
+	// the zero time means the key never expires
+	expiresAt time.Time
+}
+
+func (e *fileEntry) encrypted() bool {
+	return e.keyID != plainKeyID
+}
+
+func (e *fileEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+func encodeFileName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:]) + entrySuffix
+}
+
+func encodeEntry(entry fileEntry) []byte {
+	sum := sha256.Sum256(entry.value)
+	var buf bytes.Buffer
+	buf.WriteString(entryMagic + "\n")
+	buf.WriteString("key:" + base64.StdEncoding.EncodeToString([]byte(entry.key)) + "\n")
+	buf.WriteString("enc:" + entry.keyID + "\n")
+	expiry := noExpiry
+	if !entry.expiresAt.IsZero() {
+		expiry = entry.expiresAt.UTC().Format(time.RFC3339Nano)
+	}
+	buf.WriteString("exp:" + expiry + "\n")
+	buf.WriteString("sha256:" + hex.EncodeToString(sum[:]) + "\n")
+	buf.Write(entry.value)
+	return buf.Bytes()
+}
+
+// Decoding the entry and verifying the checksum of the stored value
+func decodeEntry(raw []byte) (*fileEntry, error) {
+	return parseEntry(raw, true)
+}
+
+// Decoding only the header of the entry, the stored value is not verified
+func decodeEntryHeader(raw []byte) (*fileEntry, error) {
+	return parseEntry(raw, false)
+}
+
+func parseEntry(raw []byte, verify bool) (*fileEntry, error) {
+	magic, rest, found := bytes.Cut(raw, []byte("\n"))
+	if !found {
+		return nil, fmt.Errorf("entry header is truncated")
+	}
+
+	var fields []string
+	switch string(magic) {
+	case entryMagicV1:
+		fields = []string{"key:", "sha256:"}
+	case entryMagicV2:
+		fields = []string{"key:", "enc:", "sha256:"}
+	case entryMagic:
+		fields = []string{"key:", "enc:", "exp:", "sha256:"}
+	default:
+		return nil, fmt.Errorf("unknown entry format: %q", magic)
+	}
+
+	header := make(map[string]string)
+	for _, field := range fields {
+		line, after, found := bytes.Cut(rest, []byte("\n"))
+		if !found {
+			return nil, fmt.Errorf("entry header is truncated")
+		}
+		value, found := strings.CutPrefix(string(line), field)
+		if !found {
+			return nil, fmt.Errorf("entry is missing the %s field", strings.TrimSuffix(field, ":"))
+		}
+		header[field] = value
+		rest = after
+	}
+
+	key, err := base64.StdEncoding.DecodeString(header["key:"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the entry key: %v", err)
+	}
+	if verify {
+		sum := sha256.Sum256(rest)
+		if hex.EncodeToString(sum[:]) != header["sha256:"] {
+			return nil, fmt.Errorf("checksum mismatch")
+		}
+	}
+
+	keyID := plainKeyID
+	if id, ok := header["enc:"]; ok && id != "" {
+		keyID = id
+	}
+	var expiresAt time.Time
+	if expiry, ok := header["exp:"]; ok && expiry != noExpiry {
+		if expiresAt, err = time.Parse(time.RFC3339Nano, expiry); err != nil {
+			return nil, fmt.Errorf("failed to decode the entry expiry: %v", err)
+		}
+	}
+	return &fileEntry{key: string(key), keyID: keyID, value: rest, expiresAt: expiresAt}, nil
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Result of importing a persist cache directory
+type ImportReport struct {
+	Imported int
+	Failed   map[string]string
+}
+
+// Importing all the entries of a persist cache directory to another cache,
+// the directory is only read. The key providers are needed when the entries
+// are encrypted
+func ImportPersistCache(dirPath string, dst ICache, providers ...KeyProvider) (*ImportReport, error) {
+	codec := newEntryCodec()
+	for _, provider := range providers {
+		if err := codec.useKeys(provider); err != nil {
+			return nil, err
+		}
+	}
+
+	files, err := os.ReadDir(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dir %s: %v", dirPath, err)
+	}
+
+	report := &ImportReport{Failed: make(map[string]string)}
+	values := make(map[string]interface{})
+	expires := make(map[string]time.Time)
+	now := time.Now()
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || strings.HasPrefix(name, tempFilePrefix) {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(dirPath, name))
+		if err != nil {
+			report.Failed[name] = err.Error()
+			continue
+		}
+		if !strings.HasSuffix(name, entrySuffix) {
+			// old entry, the file name is the key
+			values[name] = raw
+			continue
+		}
+		entry, err := decodeEntry(raw)
+		if err != nil {
+			report.Failed[name] = err.Error()
+			continue
+		}
+		if entry.expired(now) {
+			continue
+		}
+		value, err := codec.open(entry)
+		if err != nil {
+			report.Failed[entry.key] = err.Error()
+			continue
+		}
+		values[entry.key] = value
+		expires[entry.key] = entry.expiresAt
+	}
+
+	// saving everything in one transaction when the cache supports it
+	if batch, ok := dst.(interface {
+		SetManyWithExpiry(values map[string]interface{}, expires map[string]time.Time) error
+	}); ok {
+		if err := batch.SetManyWithExpiry(values, expires); err != nil {
+			return report, err
+		}
+		report.Imported = len(values)
+		return report, nil
+	}
+	for key, value := range values {
+		ttl, ok := TTLUntil(expires[key])
+		if !ok {
+			continue
+		}
+		if err := dst.Set(key, value, WithTTL(ttl)); err != nil {
+			report.Failed[key] = err.Error()
+			continue
+		}
+		report.Imported++
+	}
+	return report, nil
+}
@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"golang-secret-manager/utils/resp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config of the shared cache
+type RedisCacheConfig struct {
+	Addr string
+
+	// Every key is saved with the prefix, so a few deployments can share
+	// the same server
+	Prefix string
+
+	// Time to live of keys that were set with the default ttl
+	DefaultTTL time.Duration
+
+	// The channel that the replicas are publishing the changed keys on
+	Channel string
+
+	Timeout time.Duration
+}
+
+// RedisCache keeps the entries in a server that speaks the Redis protocol so
+// all the server replicas are sharing the same cache. Every change is
+// published to the other replicas, so they can drop their own copy
+type RedisCache struct {
+	client    *resp.Client
+	config    RedisCacheConfig
+	replicaID string
+	layer     ICache
+
+	subMutex     sync.Mutex
+	subscription *resp.Subscription
+	closed       bool
+}
+
+func NewRedisCache(config RedisCacheConfig) (*RedisCache, error) {
+	if config.Prefix == "" {
+		config.Prefix = "secret-manager:"
+	}
+	if config.Channel == "" {
+		config.Channel = config.Prefix + "invalidate"
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	r := &RedisCache{
+		client:    resp.NewClient(config.Addr, config.Timeout),
+		config:    config,
+		replicaID: hex.EncodeToString(id),
+	}
+	if _, err := r.client.Do("PING"); err != nil {
+		return nil, fmt.Errorf("RedisCache: %v", err)
+	}
+	return r, nil
+}
+
+// The redis cache implements the following interface:
+// type ICache interface {
+// 	Get(key string) (interface{}, error)
+// 	Set(key string, value interface{}, opts ...SetOption) error
+// 	Delete(key string) error
+// 	SetCacheLayer(layer ICache) error
+// 	ActivateLayerSavingRuntime(intervals time.Duration) error
+// 	LayerSet(key string, value interface{}, opts ...SetOption) error
+// }
+
+func (r *RedisCache) Get(key string) (interface{}, error) {
+	val, _, err := r.GetWithExpiry(key)
+	return val, err
+}
+
+// Getting the key with the time it expires at, the server is expiring the
+// keys by itself
+func (r *RedisCache) GetWithExpiry(key string) (interface{}, time.Time, error) {
+	reply, err := r.client.Do("GET", r.config.Prefix+key)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("RedisCache: failed to get key: %s: %v", key, err)
+	}
+	if val, ok := reply.([]byte); ok {
+		reply, err := r.client.Do("PTTL", r.config.Prefix+key)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("RedisCache: failed to get the ttl of key: %s: %v", key, err)
+		}
+		// -1 when the key has no ttl, -2 when it expired since the GET
+		ttl, _ := reply.(int64)
+		switch {
+		case ttl == -2:
+			return nil, time.Time{}, &NotFoundError{Key: key}
+		case ttl < 0:
+			return val, time.Time{}, nil
+		}
+		return val, time.Now().Add(time.Duration(ttl) * time.Millisecond), nil
+	}
+
+	if r.layer == nil {
+		return nil, time.Time{}, &NotFoundError{Key: key}
+	}
+	val, expiresAt, err := GetWithExpiry(r.layer, key)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	ttl, ok := TTLUntil(expiresAt)
+	if !ok {
+		return nil, time.Time{}, &NotFoundError{Key: key}
+	}
+	if err := r.Set(key, val, WithTTL(ttl)); err != nil {
+		return nil, time.Time{}, err
+	}
+	return val, expiresAt, nil
+}
+
+func (r *RedisCache) Set(key string, value interface{}, opts ...SetOption) error {
+	val, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("RedisCache: Error converting value to []byte")
+	}
+
+	args := []interface{}{"SET", r.config.Prefix + key, val}
+	ttl := ApplySetOptions(opts...).TTL
+	if ttl == DefaultTTL {
+		ttl = r.config.DefaultTTL
+	}
+	if ttl > 0 {
+		// redis won't accept a ttl that is less than a millisecond
+		args = append(args, "PX", max(int64(ttl/time.Millisecond), 1))
+	}
+	if _, err := r.client.Do(args...); err != nil {
+		return fmt.Errorf("RedisCache: failed to set key: %s: %v", key, err)
+	}
+	r.publish(key)
+	return nil
+}
+
+func (r *RedisCache) Delete(key string) error {
+	if _, err := r.client.Do("DEL", r.config.Prefix+key); err != nil {
+		return fmt.Errorf("RedisCache: failed to delete key: %s: %v", key, err)
+	}
+	r.publish(key)
+	return nil
+}
+
+func (r *RedisCache) SetCacheLayer(layer ICache, load bool) error {
+	if r.layer != nil {
+		return fmt.Errorf("cannot changed layer")
+	}
+	r.layer = layer
+	return nil
+}
+
+func (r *RedisCache) ActivateLayerSavingRuntime(intervals time.Duration) error {
+	// NONE every Set is already sent to the server
+	return nil
+}
+
+func (r *RedisCache) LayerSet(key string, value interface{}, opts ...SetOption) error {
+	return r.Set(key, value, opts...)
+}
+
+func (r *RedisCache) GetAllKeys() []string {
+	var list []string
+	cursor := "0"
+	pattern := escapeGlob(r.config.Prefix) + "*"
+	for {
+		reply, err := r.client.Do("SCAN", cursor, "MATCH", pattern, "COUNT", 500)
+		if err != nil {
+			fmt.Println("RedisCache: failed to list keys:", err)
+			break
+		}
+		page, ok := reply.([]interface{})
+		if !ok || len(page) != 2 {
+			break
+		}
+		next, _ := page[0].([]byte)
+		keys, _ := page[1].([]interface{})
+		for _, k := range keys {
+			if b, ok := k.([]byte); ok {
+				list = append(list, strings.TrimPrefix(string(b), r.config.Prefix))
+			}
+		}
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			break
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Listening to the changes of the other replicas, the handler gets every key
+// that another replica set or deleted. The subscription is opened again when
+// the connection is lost
+func (r *RedisCache) SubscribeInvalidations(handler func(key string)) error {
+	onMessage := func(_ string, message []byte) {
+		replica, key, found := strings.Cut(string(message), " ")
+		if !found || replica == r.replicaID {
+			// our own change
+			return
+		}
+		handler(key)
+	}
+
+	sub, err := r.client.Subscribe(r.config.Channel, onMessage)
+	if err != nil {
+		return fmt.Errorf("RedisCache: failed to subscribe: %v", err)
+	}
+	r.subMutex.Lock()
+	r.subscription = sub
+	r.subMutex.Unlock()
+
+	go func() {
+		for {
+			<-sub.Closed()
+			if r.isClosed() {
+				return
+			}
+			fmt.Println("RedisCache: invalidation subscription lost, reconnecting")
+			for {
+				time.Sleep(time.Second)
+				if r.isClosed() {
+					return
+				}
+				if sub, err = r.client.Subscribe(r.config.Channel, onMessage); err == nil {
+					break
+				}
+			}
+			r.subMutex.Lock()
+			if r.closed {
+				r.subMutex.Unlock()
+				sub.Close()
+				return
+			}
+			r.subscription = sub
+			r.subMutex.Unlock()
+		}
+	}()
+	return nil
+}
+
+func (r *RedisCache) Close() error {
+	r.subMutex.Lock()
+	sub := r.subscription
+	r.subscription = nil
+	r.closed = true
+	r.subMutex.Unlock()
+	if sub != nil {
+		sub.Close()
+	}
+	return r.client.Close()
+}
+
+func (r *RedisCache) isClosed() bool {
+	r.subMutex.Lock()
+	defer r.subMutex.Unlock()
+	return r.closed
+}
+
+// Telling the other replicas that the key was changed
+func (r *RedisCache) publish(key string) {
+	if _, err := r.client.Do("PUBLISH", r.config.Channel, r.replicaID+" "+key); err != nil {
+		fmt.Println("RedisCache: failed to publish invalidation of key:", key, err)
+	}
+}
+
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]\`, c) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"bytes"
+	"golang-secret-manager/utils/resp"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestRedisServer(t *testing.T) *resp.Server {
+	t.Helper()
+	server, err := resp.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("starting the RESP server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func newTestRedisCache(t *testing.T, config RedisCacheConfig) *RedisCache {
+	t.Helper()
+	if config.Timeout == 0 {
+		config.Timeout = time.Second
+	}
+	cache, err := NewRedisCache(config)
+	if err != nil {
+		t.Fatalf("NewRedisCache: %v", err)
+	}
+	t.Cleanup(func() { cache.Close() })
+	return cache
+}
+
+func TestRedisCacheSetGet(t *testing.T) {
+	server := newTestRedisServer(t)
+	cache := newTestRedisCache(t, RedisCacheConfig{Addr: server.Addr()})
+
+	if err := cache.Set("secret", []byte("value")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	val, expiresAt, err := cache.GetWithExpiry("secret")
+	if err != nil {
+		t.Fatalf("GetWithExpiry: %v", err)
+	}
+	if !bytes.Equal(val.([]byte), []byte("value")) {
+		t.Errorf("got %q, want %q", val, "value")
+	}
+	if !expiresAt.IsZero() {
+		t.Errorf("key without a ttl expires at %v", expiresAt)
+	}
+
+	if err := cache.Set("secret", "not bytes"); err == nil {
+		t.Error("Set accepted a value that is not []byte")
+	}
+
+	if err := cache.Delete("secret"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := cache.Get("secret"); !IsNotFound(err) {
+		t.Errorf("Get after Delete: got %v, want NotFoundError", err)
+	}
+}
+
+func TestRedisCacheTTL(t *testing.T) {
+	server := newTestRedisServer(t)
+	cache := newTestRedisCache(t, RedisCacheConfig{Addr: server.Addr(), DefaultTTL: 50 * time.Millisecond})
+
+	if err := cache.Set("default", []byte("a")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := cache.Set("long", []byte("b"), WithTTL(time.Hour)); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := cache.Set("forever", []byte("c"), WithTTL(NoExpiration)); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	_, expiresAt, err := cache.GetWithExpiry("long")
+	if err != nil {
+		t.Fatalf("GetWithExpiry: %v", err)
+	}
+	if ttl := time.Until(expiresAt); ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("ttl of long is %v, want about an hour", ttl)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, err := cache.Get("default"); !IsNotFound(err) {
+		t.Errorf("Get of an expired key: got %v, want NotFoundError", err)
+	}
+	for _, key := range []string{"long", "forever"} {
+		if _, err := cache.Get(key); err != nil {
+			t.Errorf("Get %s: %v", key, err)
+		}
+	}
+}
+
+func TestRedisCachePrefix(t *testing.T) {
+	server := newTestRedisServer(t)
+	first := newTestRedisCache(t, RedisCacheConfig{Addr: server.Addr(), Prefix: "first:"})
+	second := newTestRedisCache(t, RedisCacheConfig{Addr: server.Addr(), Prefix: "second:"})
+
+	if err := first.Set("shared", []byte("one")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := first.Set("only-first", []byte("one")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := second.Set("shared", []byte("two")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	val, err := first.Get("shared")
+	if err != nil || !bytes.Equal(val.([]byte), []byte("one")) {
+		t.Errorf("first Get: got %q %v, want %q", val, err, "one")
+	}
+	val, err = second.Get("shared")
+	if err != nil || !bytes.Equal(val.([]byte), []byte("two")) {
+		t.Errorf("second Get: got %q %v, want %q", val, err, "two")
+	}
+	if _, err := second.Get("only-first"); !IsNotFound(err) {
+		t.Errorf("Get of a key of another prefix: got %v, want NotFoundError", err)
+	}
+
+	if keys, want := first.GetAllKeys(), []string{"only-first", "shared"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("first keys: got %v, want %v", keys, want)
+	}
+	if keys, want := second.GetAllKeys(), []string{"shared"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("second keys: got %v, want %v", keys, want)
+	}
+
+	client := resp.NewClient(server.Addr(), time.Second)
+	defer client.Close()
+	reply, err := client.Do("EXISTS", "first:shared")
+	if err != nil || reply != int64(1) {
+		t.Errorf("first:shared is not on the server: %v %v", reply, err)
+	}
+}
+
+func TestRedisCacheInvalidation(t *testing.T) {
+	server := newTestRedisServer(t)
+	first := newTestRedisCache(t, RedisCacheConfig{Addr: server.Addr()})
+	second := newTestRedisCache(t, RedisCacheConfig{Addr: server.Addr()})
+
+	firstKeys := make(chan string, 10)
+	secondKeys := make(chan string, 10)
+	if err := first.SubscribeInvalidations(func(key string) { firstKeys <- key }); err != nil {
+		t.Fatalf("SubscribeInvalidations: %v", err)
+	}
+	if err := second.SubscribeInvalidations(func(key string) { secondKeys <- key }); err != nil {
+		t.Fatalf("SubscribeInvalidations: %v", err)
+	}
+
+	expectKey := func(keys chan string, want string) {
+		t.Helper()
+		select {
+		case key := <-keys:
+			if key != want {
+				t.Errorf("invalidated %q, want %q", key, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Errorf("no invalidation of %q", want)
+		}
+	}
+
+	if err := first.Set("secret", []byte("value")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	expectKey(secondKeys, "secret")
+
+	if err := second.Delete("secret"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	expectKey(firstKeys, "secret")
+
+	// a replica isn't told about its own changes
+	select {
+	case key := <-firstKeys:
+		t.Errorf("first replica got its own change of %q", key)
+	case key := <-secondKeys:
+		t.Errorf("second replica got its own change of %q", key)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Every registered value is saved to the lower layers inside an envelope that
+// holds its type name and schema version, the data is the value encoded with
+// the codec of the cache. The field names are not used by the stored types so
+// an entry that was saved before the envelope is never mistaken for one
+type Envelope struct {
+	Type    string          `json:"_type" msgpack:"_type"`
+	Version int             `json:"_version" msgpack:"_version"`
+	Data    json.RawMessage `json:"_data" msgpack:"_data"`
+}
+
+// Migration upgrades the data of an entry by one version, from the version it
+// is registered with to the next one
+type Migration func(data []byte, codec Codec) ([]byte, error)
+
+// KeepData is the migration of a version that only changed the meaning of the
+// data and not its layout, like the entries that were saved before the
+// envelope (version 0)
+func KeepData(data []byte, codec Codec) ([]byte, error) {
+	return data, nil
+}
+
+type schemaType struct {
+	name       string
+	version    int
+	namespace  string
+	goType     reflect.Type
+	migrations map[int]Migration
+}
+
+// SchemaRegistry knows the current version of every stored type and how to
+// migrate the older versions of it
+type SchemaRegistry struct {
+	mutex       sync.RWMutex
+	byType      map[reflect.Type]*schemaType
+	byName      map[string]*schemaType
+	byNamespace map[string]*schemaType
+}
+
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{
+		byType:      make(map[reflect.Type]*schemaType),
+		byName:      make(map[string]*schemaType),
+		byNamespace: make(map[string]*schemaType),
+	}
+}
+
+// Registering T with its current version, the entries of the key namespace
+// that have no envelope are treated as version 0 of T
+func RegisterSchema[T any](r *SchemaRegistry, name string, version int, namespace string) error {
+	if version < 1 {
+		return fmt.Errorf("schema %s: version must be at least 1", name)
+	}
+	goType := reflect.TypeOf((*T)(nil)).Elem()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, found := r.byName[name]; found {
+		return fmt.Errorf("schema %s is already registered", name)
+	}
+	if existing, found := r.byType[goType]; found {
+		return fmt.Errorf("type %s is already registered as %s", goType, existing.name)
+	}
+	if existing, found := r.byNamespace[namespace]; found && namespace != "" {
+		return fmt.Errorf("namespace %s is already registered to %s", namespace, existing.name)
+	}
+
+	t := &schemaType{
+		name:       name,
+		version:    version,
+		namespace:  namespace,
+		goType:     goType,
+		migrations: make(map[int]Migration),
+	}
+	r.byType[goType] = t
+	r.byName[name] = t
+	if namespace != "" {
+		r.byNamespace[namespace] = t
+	}
+	return nil
+}
+
+// Registering the migration of the type from the version to the next one
+func (r *SchemaRegistry) RegisterMigration(name string, from int, migration Migration) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	t, found := r.byName[name]
+	if !found {
+		return fmt.Errorf("schema %s is not registered", name)
+	}
+	if from < 0 || from >= t.version {
+		return fmt.Errorf("schema %s: can't migrate from version %d, the current version is %d", name, from, t.version)
+	}
+	t.migrations[from] = migration
+	return nil
+}
+
+// Encoding the value inside an envelope, false is returned when the type of
+// the value is not registered
+func (r *SchemaRegistry) Seal(value interface{}, codec Codec) ([]byte, bool, error) {
+	r.mutex.RLock()
+	t, found := r.byType[reflect.TypeOf(value)]
+	r.mutex.RUnlock()
+	if !found {
+		return nil, false, nil
+	}
+
+	data, err := codec.Marshal(value)
+	if err != nil {
+		return nil, true, err
+	}
+	sealed, err := codec.Marshal(Envelope{Type: t.name, Version: t.version, Data: data})
+	return sealed, true, err
+}
+
+// Opening an entry that was loaded from a lower layer, the data is migrated
+// to the current version and decoded to the registered type. Entries of an
+// unknown namespace without an envelope are returned as they are. migrated
+// is true when the entry should be saved again with the current version
+func (r *SchemaRegistry) Open(key string, raw []byte, codec Codec) (value interface{}, migrated bool, err error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var t *schemaType
+	var version int
+	var data []byte
+
+	var envelope Envelope
+	if err := codec.Unmarshal(raw, &envelope); err == nil && envelope.Type != "" && envelope.Version > 0 {
+		found := false
+		if t, found = r.byName[envelope.Type]; !found {
+			return nil, false, fmt.Errorf("unknown schema type: %s", envelope.Type)
+		}
+		version = envelope.Version
+		data = envelope.Data
+	} else {
+		// saved before the envelope
+		found := false
+		if t, found = r.byNamespace[KeyNamespace(key, r.namespaces())]; !found {
+			return raw, false, nil
+		}
+		data = raw
+	}
+
+	if version > t.version {
+		return nil, false, fmt.Errorf("%s version %d is newer than the known version %d", t.name, version, t.version)
+	}
+	for ; version < t.version; version++ {
+		migration, found := t.migrations[version]
+		if !found {
+			return nil, false, fmt.Errorf("no migration of %s from version %d", t.name, version)
+		}
+		if data, err = migration(data, codec); err != nil {
+			return nil, false, fmt.Errorf("migration of %s from version %d failed: %v", t.name, version, err)
+		}
+		migrated = true
+	}
+
+	decoded := reflect.New(t.goType)
+	if err := codec.Unmarshal(data, decoded.Interface()); err != nil {
+		return nil, false, fmt.Errorf("failed to decode %s version %d: %v", t.name, version, err)
+	}
+	return decoded.Elem().Interface(), migrated, nil
+}
+
+// The registered namespaces, the longest first so a namespace that is a
+// prefix of another one doesn't take its keys
+func (r *SchemaRegistry) namespaces() []string {
+	list := make([]string, 0, len(r.byNamespace))
+	for namespace := range r.byNamespace {
+		list = append(list, namespace)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if len(list[i]) != len(list[j]) {
+			return len(list[i]) > len(list[j])
+		}
+		return list[i] < list[j]
+	})
+	return list
+}
@@ -0,0 +1,375 @@
+package storage
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"golang-secret-manager/types"
+	"io"
+	"strings"
+	"time"
+)
+
+// A snapshot is a gzip compressed tar of the whole cache:
+//
+//	manifest.json              -- schema version, codec and a checksum per entry
+//	entries/<file name>        -- the encoded value of the key, encrypted
+//	                              when the snapshot has a passphrase
+//
+// The values are saved encoded with the codec of the cache, so a snapshot can
+// only be imported to a cache that is using the same codec
+
+// Version of the snapshot layout, snapshots of a newer version can't be read.
+// Version 2 added the expiry of the entries, the entries of a version 1
+// snapshot are imported with the default ttl of the cache
+const SnapshotSchemaVersion = 2
+
+const (
+	snapshotManifestName = "manifest.json"
+	snapshotEntriesDir   = "entries/"
+)
+
+type SnapshotManifest struct {
+	SchemaVersion int                 `json:"schema_version"`
+	CreatedAt     time.Time           `json:"created_at"`
+	Codec         string              `json:"codec"`
+	Encryption    *SnapshotEncryption `json:"encryption,omitempty"`
+	Entries       []SnapshotEntry     `json:"entries"`
+}
+
+// The entries are sealed with AES-256-GCM and a key that is derived with
+// scrypt from the passphrase and the salt
+type SnapshotEncryption struct {
+	Cipher string `json:"cipher"`
+	KDF    string `json:"kdf"`
+	Salt   string `json:"salt"`
+	KeyID  string `json:"key_id"`
+}
+
+type SnapshotEntry struct {
+	Key  string `json:"key"`
+	File string `json:"file"`
+	Size int64  `json:"size"`
+
+	// checksum of the file as it is stored in the archive
+	SHA256 string `json:"sha256"`
+
+	// The time the key expires at, nil when it never expires
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type SnapshotOptions struct {
+	// Codec that the values are encoded with, JSON when it is nil
+	Codec Codec
+
+	// Encrypting the entries when it is not empty
+	Passphrase string
+}
+
+type SnapshotImportMode string
+
+const (
+	// Keeping the keys that are not in the snapshot, the keys of the
+	// snapshot are replacing the existing ones
+	SnapshotMerge SnapshotImportMode = "merge"
+
+	// Removing every key that is not in the snapshot
+	SnapshotReplace SnapshotImportMode = "replace"
+)
+
+type SnapshotImportOptions struct {
+	Mode       SnapshotImportMode
+	Codec      Codec
+	Passphrase string
+}
+
+// Writing all the keys of the cache to w, values that are not encoded yet are
+// encoded with the codec of the options
+func ExportSnapshot(cache ICache, w io.Writer, opts SnapshotOptions) (*SnapshotManifest, error) {
+	if opts.Codec == nil {
+		opts.Codec = JSONCodec
+	}
+	manifest := &SnapshotManifest{
+		SchemaVersion: SnapshotSchemaVersion,
+		CreatedAt:     time.Now().UTC(),
+		Codec:         opts.Codec.Name(),
+	}
+
+	var cipher *entryCipher
+	if opts.Passphrase != "" {
+		salt, err := randomBytes(16)
+		if err != nil {
+			return nil, err
+		}
+		key, err := deriveKey(opts.Passphrase, salt)
+		if err != nil {
+			return nil, err
+		}
+		if cipher, err = newEntryCipher(staticKeyProvider{key: key}); err != nil {
+			return nil, err
+		}
+		manifest.Encryption = &SnapshotEncryption{
+			Cipher: "aes-256-gcm",
+			KDF:    "scrypt",
+			Salt:   hex.EncodeToString(salt),
+			KeyID:  cipher.keyID,
+		}
+	}
+
+	// the manifest is first in the archive, so all the entries are read
+	// before anything is written
+	keys := AllKeys(cache)
+	files := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		data, expiresAt, err := snapshotValue(cache, key, opts.Codec)
+		if err != nil {
+			if IsNotFound(err) {
+				// expired between listing and reading
+				continue
+			}
+			return nil, fmt.Errorf("failed to read key: %s: %v", key, err)
+		}
+		if cipher != nil {
+			if data, err = cipher.seal(key, data); err != nil {
+				return nil, fmt.Errorf("failed to encrypt key: %s: %v", key, err)
+			}
+		}
+
+		file := snapshotEntriesDir + encodeFileName(key)
+		sum := sha256.Sum256(data)
+		entry := SnapshotEntry{
+			Key:    key,
+			File:   file,
+			Size:   int64(len(data)),
+			SHA256: hex.EncodeToString(sum[:]),
+		}
+		if !expiresAt.IsZero() {
+			expiresAt = expiresAt.UTC()
+			entry.ExpiresAt = &expiresAt
+		}
+		manifest.Entries = append(manifest.Entries, entry)
+		files[file] = data
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeTarFile(tw, snapshotManifestName, raw, manifest.CreatedAt); err != nil {
+		return nil, err
+	}
+	for _, entry := range manifest.Entries {
+		if err := writeTarFile(tw, entry.File, files[entry.File], manifest.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Reading a snapshot from r and saving its keys to the cache. The whole
+// snapshot is verified before the cache is changed, so a broken snapshot
+// doesn't leave the cache half imported
+func ImportSnapshot(cache ICache, r io.Reader, opts SnapshotImportOptions) (types.CacheImportReport, error) {
+	if opts.Codec == nil {
+		opts.Codec = JSONCodec
+	}
+	if opts.Mode == "" {
+		opts.Mode = SnapshotMerge
+	}
+	report := types.CacheImportReport{Mode: string(opts.Mode)}
+	if opts.Mode != SnapshotMerge && opts.Mode != SnapshotReplace {
+		return report, fmt.Errorf("unknown import mode: %s", opts.Mode)
+	}
+
+	manifest, values, err := readSnapshot(r, opts)
+	if err != nil {
+		return report, err
+	}
+	report.SchemaVersion = manifest.SchemaVersion
+	report.CreatedAt = manifest.CreatedAt
+
+	if opts.Mode == SnapshotReplace {
+		for _, key := range AllKeys(cache) {
+			if _, found := values[key]; found {
+				continue
+			}
+			if err := PurgeKey(cache, key); err != nil {
+				return report, fmt.Errorf("failed to remove key: %s: %v", key, err)
+			}
+			report.Removed++
+		}
+	}
+
+	for _, entry := range manifest.Entries {
+		ttl, ok := entry.ttl(manifest.SchemaVersion)
+		if !ok {
+			// expired since the export
+			report.Expired++
+			continue
+		}
+		if err := cache.Set(entry.Key, values[entry.Key], WithTTL(ttl)); err != nil {
+			return report, fmt.Errorf("failed to save key: %s: %v", entry.Key, err)
+		}
+		report.Imported++
+	}
+	return report, nil
+}
+
+// Reading the manifest and all the entries, verifying their checksum and
+// decrypting them
+func readSnapshot(r io.Reader, opts SnapshotImportOptions) (*SnapshotManifest, map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("snapshot is not a gzip archive: %v", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	header, err := tr.Next()
+	if err != nil || header.Name != snapshotManifestName {
+		return nil, nil, fmt.Errorf("snapshot must start with %s", snapshotManifestName)
+	}
+	var manifest SnapshotManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode the manifest: %v", err)
+	}
+	if manifest.SchemaVersion < 1 || manifest.SchemaVersion > SnapshotSchemaVersion {
+		return nil, nil, fmt.Errorf("unsupported snapshot schema version: %d", manifest.SchemaVersion)
+	}
+	if manifest.Codec != opts.Codec.Name() {
+		return nil, nil, fmt.Errorf("snapshot is encoded with %s and the cache with %s", manifest.Codec, opts.Codec.Name())
+	}
+
+	cipher, err := snapshotCipher(&manifest, opts.Passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byFile := make(map[string]SnapshotEntry, len(manifest.Entries))
+	for _, entry := range manifest.Entries {
+		byFile[entry.File] = entry
+	}
+
+	values := make(map[string][]byte, len(manifest.Entries))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read snapshot: %v", err)
+		}
+		entry, found := byFile[header.Name]
+		if !found {
+			return nil, nil, fmt.Errorf("file %s is not in the manifest", header.Name)
+		}
+		if header.Size != entry.Size {
+			return nil, nil, fmt.Errorf("key: %s has size %d, the manifest has %d", entry.Key, header.Size, entry.Size)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read key: %s: %v", entry.Key, err)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != entry.SHA256 {
+			return nil, nil, fmt.Errorf("key: %s checksum mismatch", entry.Key)
+		}
+		if cipher != nil {
+			if data, err = cipher.open(entry.Key, data); err != nil {
+				return nil, nil, fmt.Errorf("key: %s: %v", entry.Key, err)
+			}
+		}
+		values[entry.Key] = data
+	}
+
+	var missing []string
+	for _, entry := range manifest.Entries {
+		if _, found := values[entry.Key]; !found {
+			missing = append(missing, entry.Key)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("snapshot is missing %d entries: %s", len(missing), strings.Join(missing, ", "))
+	}
+	return &manifest, values, nil
+}
+
+// Reading the value of the key encoded like it is saved in the lower layers
+func snapshotValue(cache ICache, key string, codec Codec) ([]byte, time.Time, error) {
+	if encoder, ok := cache.(interface {
+		GetEncoded(key string) ([]byte, time.Time, error)
+	}); ok {
+		return encoder.GetEncoded(key)
+	}
+	val, expiresAt, err := GetWithExpiry(cache, key)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if data, ok := val.([]byte); ok {
+		return data, expiresAt, nil
+	}
+	data, err := codec.Marshal(val)
+	return data, expiresAt, err
+}
+
+// The ttl that the entry is imported with, false when it already expired
+func (e SnapshotEntry) ttl(schemaVersion int) (time.Duration, bool) {
+	if schemaVersion < 2 {
+		// the expiry wasn't saved
+		return DefaultTTL, true
+	}
+	if e.ExpiresAt == nil {
+		return NoExpiration, true
+	}
+	return TTLUntil(*e.ExpiresAt)
+}
+
+func snapshotCipher(manifest *SnapshotManifest, passphrase string) (*entryCipher, error) {
+	if manifest.Encryption == nil {
+		return nil, nil
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("snapshot is encrypted, a passphrase is needed")
+	}
+	salt, err := hex.DecodeString(manifest.Encryption.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot salt: %v", err)
+	}
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := newEntryCipher(staticKeyProvider{key: key})
+	if err != nil {
+		return nil, err
+	}
+	if cipher.keyID != manifest.Encryption.KeyID {
+		return nil, fmt.Errorf("wrong snapshot passphrase")
+	}
+	return cipher, nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
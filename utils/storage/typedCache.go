@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Caches that can keep values as they are in memory (instead of bytes), they
+// encode them only when the values are saved to their lower layer
+type valueCache interface {
+	ICache
+	Replace(key string, value interface{}) bool
+}
+
+// TypedCache is a type safe facade on top of an ICache. On a memory cache
+// (FastCache) the decoded values are kept as they are, so the hot path never
+// serializes, and bytes that were loaded from a lower layer are decoded once
+// and replaced with the decoded value. On other caches the values are
+// encoded with the codec. The values that are kept in memory are copied on
+// Get and Set, so the callers can change them without changing the cache
+type TypedCache[T any] struct {
+	cache ICache
+	codec Codec
+}
+
+// The codec must be the one the cache encodes with, without one the codec of
+// the cache is used (see CodecOf)
+func NewTypedCache[T any](cache ICache, codec Codec) *TypedCache[T] {
+	if codec == nil {
+		codec = CodecOf(cache)
+	}
+	return &TypedCache[T]{cache: cache, codec: codec}
+}
+
+// Returning the value of the key, the error is a *NotFoundError when the key
+// is missing and a *DecodeError when the value can't be decoded
+func (c *TypedCache[T]) Get(key string) (T, error) {
+	var zero T
+	val, err := c.cache.Get(key)
+	if err != nil {
+		return zero, err
+	}
+
+	switch v := val.(type) {
+	case T:
+		return cloneValue(v), nil
+	case *T:
+		if v == nil {
+			return zero, &NotFoundError{Key: key}
+		}
+		return cloneValue(*v), nil
+	case []byte:
+		var decoded T
+		if err := c.codec.Unmarshal(v, &decoded); err != nil {
+			return zero, &DecodeError{Key: key, Codec: c.codec.Name(), Type: typeName[T](), Err: err}
+		}
+		if memory, ok := c.cache.(valueCache); ok {
+			// next time a copy of the decoded value is returned
+			memory.Replace(key, cloneValue(decoded))
+		}
+		return decoded, nil
+	}
+	return zero, &DecodeError{
+		Key:   key,
+		Codec: c.codec.Name(),
+		Type:  typeName[T](),
+		Err:   fmt.Errorf("cache holds a value of type %T", val),
+	}
+}
+
+func (c *TypedCache[T]) Set(key string, value T, opts ...SetOption) error {
+	if _, ok := c.cache.(valueCache); ok {
+		return c.cache.Set(key, cloneValue(value), opts...)
+	}
+	encoded, err := c.codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("CACHE: failed to encode key: %s: %v", key, err)
+	}
+	return c.cache.Set(key, encoded, opts...)
+}
+
+func (c *TypedCache[T]) Delete(key string) error {
+	return c.cache.Delete(key)
+}
+
+func typeName[T any]() string {
+	return reflect.TypeOf((*T)(nil)).Elem().String()
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type typedTestValue struct {
+	Name    string
+	Tags    map[string]string
+	Events  []string
+	Created time.Time
+	Parent  *typedTestValue
+}
+
+func TestTypedCacheReturnsCopies(t *testing.T) {
+	cache := NewTypedCache[[]typedTestValue](CreateFastCache(FastCacheConfig{Expiration: time.Minute}), JSONCodec)
+
+	value := []typedTestValue{{
+		Name:    "db-password",
+		Tags:    map[string]string{"env": "prod"},
+		Events:  []string{"GetSecretValue"},
+		Created: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Parent:  &typedTestValue{Name: "parent"},
+	}}
+	want := cloneValue(value)
+	if err := cache.Set("secret_a", value); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// changing the value that was set
+	value[0].Name = "changed"
+	value[0].Tags["env"] = "dev"
+
+	got, err := cache.Get("secret_a")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Set kept the value of the caller: got %+v, want %+v", got, want)
+	}
+
+	// changing the value that was returned
+	got[0].Tags["env"] = "dev"
+	got[0].Events[0] = "DeleteSecret"
+	got[0].Parent.Name = "changed"
+
+	again, err := cache.Get("secret_a")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !reflect.DeepEqual(again, want) {
+		t.Errorf("Get returned the cached value: got %+v, want %+v", again, want)
+	}
+}
+
+func TestTypedCacheCopiesDecodedValues(t *testing.T) {
+	memory := CreateFastCache(FastCacheConfig{Expiration: time.Minute})
+	if err := memory.Set("arnlst_a", []byte(`["a","b"]`)); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	cache := NewTypedCache[[]string](memory, JSONCodec)
+
+	got, err := cache.Get("arnlst_a")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got[0] = "changed"
+
+	again, err := cache.Get("arnlst_a")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(again, want) {
+		t.Errorf("got %v, want %v", again, want)
+	}
+}
+
+func TestCacheValueUsesTheCodecOfTheCache(t *testing.T) {
+	memory := CreateFastCache(FastCacheConfig{Expiration: time.Minute, Codec: MsgpackCodec})
+	want := typedTestValue{Name: "db-password", Events: []string{"GetSecretValue"}}
+
+	// bytes like the ones loaded from the lower layer, encoded by the cache
+	encoded, err := memory.Codec().Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := memory.Set("secret_a", encoded); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	got, err := GetCacheValue[typedTestValue](memory, "secret_a")
+	if err != nil {
+		t.Fatalf("GetCacheValue: %v", err)
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("got %+v, want %+v", *got, want)
+	}
+
+	if CodecOf(memory) != MsgpackCodec {
+		t.Errorf("CodecOf: got %s, want msgpack", CodecOf(memory).Name())
+	}
+	if CodecOf(CreateFastCache(FastCacheConfig{})) != JSONCodec {
+		t.Error("CodecOf: the default codec is not json")
+	}
+}